@@ -0,0 +1,45 @@
+package backend
+
+import (
+	blblcdmodel "bilibili-comments-viewer-go/crawler/blblcd/model"
+	"bilibili-comments-viewer-go/database"
+)
+
+// dbCheckpointStore 将爬取断点保存到数据库 crawl_checkpoints 表，服务重启后仍可继续爬取
+type dbCheckpointStore struct{}
+
+func (dbCheckpointStore) LoadCheckpoint(bvid string) (*blblcdmodel.Checkpoint, error) {
+	cp, err := database.GetCrawlCheckpoint(bvid)
+	if err != nil || cp == nil {
+		return nil, err
+	}
+	result := &blblcdmodel.Checkpoint{
+		Bvid:            cp.Bvid,
+		Page:            cp.Page,
+		Offset:          cp.Offset,
+		DownloadedCount: cp.DownloadedCount,
+		CompletedRoots:  cp.CompletedRoots,
+	}
+	for _, t := range cp.PendingThreads {
+		result.PendingThreads = append(result.PendingThreads, blblcdmodel.CheckpointThread{Rpid: t.Rpid, Oid: t.Oid, Rcount: t.Rcount})
+	}
+	return result, nil
+}
+
+func (dbCheckpointStore) SaveCheckpoint(cp *blblcdmodel.Checkpoint) error {
+	record := &database.CrawlCheckpoint{
+		Bvid:            cp.Bvid,
+		Page:            cp.Page,
+		Offset:          cp.Offset,
+		DownloadedCount: cp.DownloadedCount,
+		CompletedRoots:  cp.CompletedRoots,
+	}
+	for _, t := range cp.PendingThreads {
+		record.PendingThreads = append(record.PendingThreads, database.CheckpointThread{Rpid: t.Rpid, Oid: t.Oid, Rcount: t.Rcount})
+	}
+	return database.SaveCrawlCheckpoint(record)
+}
+
+func (dbCheckpointStore) DeleteCheckpoint(bvid string) error {
+	return database.DeleteCrawlCheckpoint(bvid)
+}
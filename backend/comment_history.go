@@ -0,0 +1,108 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"bilibili-comments-viewer-go/database"
+)
+
+var (
+	// ErrCrawlNotFound 指定的爬取记录不存在
+	ErrCrawlNotFound = errors.New("爬取记录不存在")
+	// ErrNoCompletedCrawl 视频没有成功结束的爬取，无法确定比较的终点
+	ErrNoCompletedCrawl = errors.New("视频没有已完成的爬取记录")
+	// ErrInvalidCrawlRange 起点爬取不早于终点爬取
+	ErrInvalidCrawlRange = errors.New("起点爬取必须早于终点爬取")
+)
+
+// VideoChanges 视频在两次爬取之间被删除或修改的评论
+type VideoChanges struct {
+	From *database.VideoCrawl `json:"from"` // 起点爬取，为空时从最早的观察开始
+	To   *database.VideoCrawl `json:"to"`
+	database.CommentChanges
+}
+
+// CommentHistory 评论及其历史状态
+type CommentHistory struct {
+	Comment   *database.Comment          `json:"comment"`
+	Snapshots []database.CommentSnapshot `json:"snapshots"` // 每次变化前的状态，按时间升序
+}
+
+// GetVideoChanges 获取两次爬取之间观察到的评论删除与内容修改，即起点爬取结束之后、终点爬取结束之前的变化。
+// toID 为 0 时取最近一次已完成的爬取，fromID 为 0 时取终点之前最近一次已完成的爬取（没有时从最早的观察开始）
+func GetVideoChanges(bvid string, fromID, toID int64) (*VideoChanges, error) {
+	crawls, err := database.GetVideoCrawls(bvid)
+	if err != nil {
+		return nil, err
+	}
+	find := func(id int64) *database.VideoCrawl {
+		for i := range crawls {
+			if crawls[i].ID == id {
+				return &crawls[i]
+			}
+		}
+		return nil
+	}
+	// previousCompleted 早于 id 的最近一次已完成的爬取，id 为 0 时不限
+	previousCompleted := func(id int64) *database.VideoCrawl {
+		for i := range crawls {
+			if crawls[i].Status == database.VideoCrawlCompleted && (id == 0 || crawls[i].ID < id) {
+				return &crawls[i]
+			}
+		}
+		return nil
+	}
+
+	changes := &VideoChanges{}
+	if toID != 0 {
+		if changes.To = find(toID); changes.To == nil {
+			return nil, fmt.Errorf("%w: %d", ErrCrawlNotFound, toID)
+		}
+	} else if changes.To = previousCompleted(0); changes.To == nil {
+		return nil, ErrNoCompletedCrawl
+	}
+	if fromID != 0 {
+		if changes.From = find(fromID); changes.From == nil {
+			return nil, fmt.Errorf("%w: %d", ErrCrawlNotFound, fromID)
+		}
+		if changes.From.ID >= changes.To.ID {
+			return nil, fmt.Errorf("%w: %d >= %d", ErrInvalidCrawlRange, changes.From.ID, changes.To.ID)
+		}
+	} else {
+		changes.From = previousCompleted(changes.To.ID)
+	}
+
+	// 运行中的爬取以当前时间为终点
+	var from time.Time
+	to := time.Now()
+	if changes.To.FinishedAt != nil {
+		to = *changes.To.FinishedAt
+	}
+	if changes.From != nil {
+		from = changes.From.StartedAt
+		if changes.From.FinishedAt != nil {
+			from = *changes.From.FinishedAt
+		}
+	}
+	result, err := database.GetCommentChanges(bvid, from, to)
+	if err != nil {
+		return nil, err
+	}
+	changes.CommentChanges = *result
+	return changes, nil
+}
+
+// GetCommentHistory 获取评论（unique_id 或 rpid）及其历史状态
+func GetCommentHistory(id string) (*CommentHistory, error) {
+	c, err := ResolveComment(id)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := database.GetCommentSnapshots(c.UniqueID)
+	if err != nil {
+		return nil, err
+	}
+	return &CommentHistory{Comment: c, Snapshots: snapshots}, nil
+}
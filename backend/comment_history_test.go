@@ -0,0 +1,88 @@
+package backend
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"bilibili-comments-viewer-go/database"
+	"bilibili-comments-viewer-go/internal/fakebili"
+)
+
+// 两次完整爬取之间修改一条主评论、删除一个楼层，第二次爬取标记删除并记录修改前的内容
+func TestCommentHistory(t *testing.T) {
+	e := newFakeEnv(t)
+	small := &e.fx.Videos[1]
+	saved := append([]fakebili.Comment(nil), small.Comments...)
+	e.crawl(t, small.Bvid)
+	time.Sleep(1100 * time.Millisecond) // 观察时间精确到秒
+
+	edited, removed := small.Comments[0], small.Comments[2]
+	small.Comments = append([]fakebili.Comment(nil), saved...)
+	small.Comments[0].Message += "（已编辑）"
+	small.Comments[0].Like += 10
+	small.Comments = append(small.Comments[:2], small.Comments[3:]...)
+	e.crawl(t, small.Bvid)
+
+	changes, err := GetVideoChanges(small.Bvid, 0, 0)
+	if err != nil {
+		t.Fatalf("GetVideoChanges: %v", err)
+	}
+	if changes.From == nil || len(changes.Deleted) != 1+len(removed.Replies) || changes.To.Deleted != len(changes.Deleted) {
+		t.Errorf("两次爬取之间删除 %d 条评论，期望 %d", len(changes.Deleted), 1+len(removed.Replies))
+	}
+	for _, c := range changes.Deleted {
+		if c.DeletedAt == nil || (c.Rpid != removed.Rpid && c.Root != removed.Rpid) {
+			t.Errorf("评论 %d 不在被删除的楼层 %d 中", c.Rpid, removed.Rpid)
+		}
+	}
+	if len(changes.Edited) != 1 || changes.Edited[0].Rpid != edited.Rpid ||
+		changes.Edited[0].Before != edited.Message || changes.Edited[0].After != edited.Message+"（已编辑）" {
+		t.Errorf("修改了 %d 条评论: %+v", len(changes.Edited), changes.Edited)
+	}
+
+	history, err := GetCommentHistory(fmt.Sprint(edited.Rpid))
+	if err != nil {
+		t.Fatalf("GetCommentHistory: %v", err)
+	}
+	s := history.Snapshots
+	if len(s) != 1 || s[0].Content == nil || *s[0].Content != edited.Message || s[0].LikeCount != edited.Like ||
+		history.Comment.LikeCount != edited.Like+10 {
+		t.Errorf("评论 %d 的历史快照 %d 条: %+v", edited.Rpid, len(s), s)
+	}
+
+	// 评论恢复后再次爬取清除删除标记
+	small.Comments = saved
+	e.crawl(t, small.Bvid)
+	if got, want := commentCount(t, small.Bvid), small.CommentCount(); got != want {
+		t.Errorf("评论数 %d，期望 %d", got, want)
+	}
+	if n := countRows(t, "SELECT COUNT(*) FROM bilibili_comments WHERE bvid = ? AND deleted_at IS NOT NULL", small.Bvid); n != 0 {
+		t.Errorf("恢复后仍有 %d 条评论标记为已删除", n)
+	}
+}
+
+// 无法确认爬到末尾时照常保存评论，但不把未出现的评论标记为已删除
+func TestIncompleteCrawlSkipsDeletion(t *testing.T) {
+	e := newFakeEnv(t)
+	small := &e.fx.Videos[1]
+	e.crawl(t, small.Bvid)
+	time.Sleep(1100 * time.Millisecond) // 观察时间精确到秒
+
+	// 第一页返回空页：爬虫没有到达末尾，未爬取的评论不能视为已删除
+	small.Comments = small.Comments[1:]
+	e.server.FailNext(fakebili.PathReplyMain, fakebili.EmptyPage, 1)
+	e.crawl(t, small.Bvid)
+
+	crawls, err := database.GetVideoCrawls(small.Bvid)
+	if err != nil || len(crawls) != 2 {
+		t.Fatalf("爬取记录 %d 条 err=%v", len(crawls), err)
+	}
+	if latest := crawls[0]; latest.Status != database.VideoCrawlCompleted || !latest.Incomplete || latest.Deleted != 0 {
+		t.Errorf("最近一次爬取 status=%s incomplete=%v deleted=%d，期望 completed、incomplete 且未标记删除",
+			latest.Status, latest.Incomplete, latest.Deleted)
+	}
+	if n := countRows(t, "SELECT COUNT(*) FROM bilibili_comments WHERE bvid = ? AND deleted_at IS NOT NULL", small.Bvid); n != 0 {
+		t.Errorf("不完整的爬取标记了 %d 条已删除的评论", n)
+	}
+}
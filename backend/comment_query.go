@@ -0,0 +1,195 @@
+package backend
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"bilibili-comments-viewer-go/database"
+)
+
+// 评论列表分页大小
+const (
+	DefaultCommentPageSize = 20
+	MaxCommentPageSize     = 100
+)
+
+// CommentQuery 评论列表接口的查询参数
+//
+//	page, pageSize          分页，pageSize 不超过 100
+//	cursor                  上一页返回的 next_cursor，给出时忽略 page
+//	keyword                 关键词，语法同全文检索
+//	mid                     评论者 mid
+//	location                IP 属地，包含匹配
+//	min_level, max_level    用户等级范围（含）
+//	sex                     男 / 女 / 保密
+//	from, to                发布时间范围（含两端），支持 2006-01-02、2006-01-02 15:04:05、RFC3339 与 Unix 秒；
+//	                        只写日期的 to 包含当天
+//	min_likes               最少点赞数
+//	has_pictures            是否带图片
+//	pinned                  是否被 UP 主置顶
+//	up_replied              UP 主是否回复过
+//	sort, order             排序字段 time / likes / replies，方向 asc / desc，默认按点赞数降序
+type CommentQuery struct {
+	Filter database.CommentFilter
+	database.PageRequest
+}
+
+// ParseCommentQuery 解析并校验评论列表的查询参数
+func ParseCommentQuery(q url.Values) (*CommentQuery, error) {
+	cq := &CommentQuery{PageRequest: database.PageRequest{Page: 1, PageSize: DefaultCommentPageSize}}
+	f := &cq.Filter
+	var err error
+
+	if cq.Page, err = intParam(q, "page", 1); err != nil {
+		return nil, err
+	}
+	if cq.Page < 1 {
+		return nil, fmt.Errorf("page 必须大于 0")
+	}
+	if cq.PageSize, err = intParam(q, "pageSize", DefaultCommentPageSize); err != nil {
+		return nil, err
+	}
+	if cq.PageSize < 1 || cq.PageSize > MaxCommentPageSize {
+		return nil, fmt.Errorf("pageSize 必须在 1 到 %d 之间", MaxCommentPageSize)
+	}
+
+	cq.Cursor = q.Get("cursor")
+
+	f.Keyword = strings.TrimSpace(q.Get("keyword"))
+	f.Location = strings.TrimSpace(q.Get("location"))
+	if v := q.Get("mid"); v != "" {
+		if f.Mid, err = strconv.ParseInt(v, 10, 64); err != nil || f.Mid <= 0 {
+			return nil, fmt.Errorf("无效的 mid: %s", v)
+		}
+	}
+
+	if f.MinLevel, err = optionalIntParam(q, "min_level"); err != nil {
+		return nil, err
+	}
+	if f.MaxLevel, err = optionalIntParam(q, "max_level"); err != nil {
+		return nil, err
+	}
+	if f.MinLevel != nil && f.MaxLevel != nil && *f.MinLevel > *f.MaxLevel {
+		return nil, fmt.Errorf("min_level 不能大于 max_level")
+	}
+
+	switch sex := q.Get("sex"); sex {
+	case "", "男", "女", "保密":
+		f.Sex = sex
+	default:
+		return nil, fmt.Errorf("无效的 sex: %s", sex)
+	}
+
+	if v := q.Get("from"); v != "" {
+		if f.From, _, err = parseTimeParam(v); err != nil {
+			return nil, fmt.Errorf("无效的 from: %w", err)
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		var dateOnly bool
+		if f.To, dateOnly, err = parseTimeParam(v); err != nil {
+			return nil, fmt.Errorf("无效的 to: %w", err)
+		}
+		if dateOnly {
+			f.To = f.To.AddDate(0, 0, 1)
+		} else {
+			f.To = f.To.Add(time.Second)
+		}
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return nil, fmt.Errorf("from 不能晚于 to")
+	}
+
+	if f.MinLikes, err = intParam(q, "min_likes", 0); err != nil {
+		return nil, err
+	}
+	if f.MinLikes < 0 {
+		return nil, fmt.Errorf("min_likes 不能为负数")
+	}
+
+	if f.HasPictures, err = boolParam(q, "has_pictures"); err != nil {
+		return nil, err
+	}
+	if f.Pinned, err = boolParam(q, "pinned"); err != nil {
+		return nil, err
+	}
+	if f.UpReplied, err = boolParam(q, "up_replied"); err != nil {
+		return nil, err
+	}
+
+	switch sort := q.Get("sort"); sort {
+	case "", database.CommentSortLikes:
+		f.Sort = database.CommentSortLikes
+	case database.CommentSortTime, database.CommentSortReplies:
+		f.Sort = sort
+	default:
+		return nil, fmt.Errorf("无效的 sort: %s，可选 time、likes、replies", sort)
+	}
+	switch order := strings.ToLower(q.Get("order")); order {
+	case "", "desc":
+	case "asc":
+		f.Asc = true
+	default:
+		return nil, fmt.Errorf("无效的 order: %s，可选 asc、desc", order)
+	}
+	return cq, nil
+}
+
+// Run 查询指定视频满足条件的主评论，游标无效时返回 database.ErrInvalidCursor
+func (cq *CommentQuery) Run(bvid string) (*database.CommentPage, error) {
+	return database.QueryComments(bvid, cq.Filter, cq.PageRequest)
+}
+
+func intParam(q url.Values, name string, def int) (int, error) {
+	v := q.Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("无效的 %s: %s", name, v)
+	}
+	return n, nil
+}
+
+func optionalIntParam(q url.Values, name string) (*int, error) {
+	if q.Get(name) == "" {
+		return nil, nil
+	}
+	n, err := intParam(q, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func boolParam(q url.Values, name string) (*bool, error) {
+	v := q.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, fmt.Errorf("无效的 %s: %s", name, v)
+	}
+	return &b, nil
+}
+
+// parseTimeParam 解析时间参数，按本地时区理解不带时区的时间；dateOnly 表示只给出了日期
+func parseTimeParam(v string) (t time.Time, dateOnly bool, err error) {
+	if t, err = time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+		return t, true, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05", time.RFC3339} {
+		if t, err = time.ParseInLocation(layout, v, time.Local); err == nil {
+			return t, false, nil
+		}
+	}
+	if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(sec, 0), false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("%s 不是日期、时间或 Unix 秒", v)
+}
@@ -0,0 +1,132 @@
+package backend
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"bilibili-comments-viewer-go/database"
+)
+
+func TestCommentQueryFilters(t *testing.T) {
+	_, video := newCrawledEnv(t)
+
+	// 置顶、UP主回复过、IP属地与点赞数，total 与筛选后的条数一致
+	for _, tc := range []struct {
+		query string
+		want  func(database.Comment) bool
+	}{
+		{"pinned=true", func(c database.Comment) bool { return c.Rpid == 1000074 && c.Pinned }},
+		{"up_replied=true", func(c database.Comment) bool { return c.Rpid == 1000049 && c.UpReplied }},
+		{"location=四川&min_likes=100", func(c database.Comment) bool { return strings.Contains(c.Location, "四川") && c.LikeCount >= 100 }},
+	} {
+		values, _ := url.ParseQuery(tc.query)
+		cq, err := ParseCommentQuery(values)
+		if err != nil {
+			t.Errorf("解析评论筛选 %q 失败: %v", tc.query, err)
+			continue
+		}
+		result, err := cq.Run(video.Bvid)
+		if err != nil {
+			t.Errorf("评论筛选 %q 查询失败: %v", tc.query, err)
+			continue
+		}
+		if result.Total == 0 || result.Total != len(result.Comments) {
+			t.Errorf("评论筛选 %q 返回 %d/%d 条", tc.query, len(result.Comments), result.Total)
+		}
+		for _, c := range result.Comments {
+			if !tc.want(c) {
+				t.Errorf("评论筛选 %q 返回了不符合条件的评论 %d", tc.query, c.Rpid)
+			}
+		}
+	}
+	if _, err := ParseCommentQuery(url.Values{"sort": {"hot"}}); err == nil {
+		t.Error("无效的排序字段未被拒绝")
+	}
+}
+
+// 按时间升序逐页取完全部主评论，不重复、不遗漏且与一次取出的顺序一致
+func TestCommentQueryCursor(t *testing.T) {
+	_, video := newCrawledEnv(t)
+
+	cq, err := ParseCommentQuery(url.Values{"sort": {"time"}, "order": {"asc"}, "pageSize": {"100"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := cq.Run(video.Bvid)
+	if err != nil || len(all.Comments) < 2 || all.NextCursor != "" {
+		t.Fatalf("一次取出全部主评论: %d 条 err=%v", len(all.Comments), err)
+	}
+	var walked []database.Comment
+	cq.PageSize = 7
+	for pages := 0; pages < 100; pages++ {
+		page, err := cq.Run(video.Bvid)
+		if err != nil {
+			t.Fatalf("游标翻页第 %d 页: %v", pages+1, err)
+		}
+		walked = append(walked, page.Comments...)
+		if page.NextCursor == "" {
+			break
+		}
+		cq.Cursor = page.NextCursor
+	}
+	if len(walked) != len(all.Comments) {
+		t.Fatalf("游标翻页取到 %d 条主评论，期望 %d", len(walked), len(all.Comments))
+	}
+	for i := range walked {
+		if walked[i].UniqueID != all.Comments[i].UniqueID || (i > 0 && walked[i].Ctime.Before(walked[i-1].Ctime)) {
+			t.Fatalf("游标翻页第 %d 条为 %s，期望 %s", i+1, walked[i].UniqueID, all.Comments[i].UniqueID)
+		}
+	}
+}
+
+func TestGetCommentRepliesCursor(t *testing.T) {
+	_, video := newCrawledEnv(t)
+
+	pr := database.PageRequest{PageSize: 3}
+	var replies []database.Comment
+	for pages := 0; ; pages++ {
+		if pages >= 100 {
+			t.Fatal("游标翻页未结束")
+		}
+		page, err := database.GetCommentReplies(video.Bvid+"_1000062", pr)
+		if err != nil {
+			t.Fatalf("游标翻页查询回复失败: %v", err)
+		}
+		replies = append(replies, page.Comments...)
+		if page.NextCursor == "" {
+			if len(replies) != page.Total || page.Total <= pr.PageSize {
+				t.Errorf("游标翻页取到 %d 条回复，共 %d 条", len(replies), page.Total)
+			}
+			break
+		}
+		pr.Cursor = page.NextCursor
+	}
+	if _, err := database.GetCommentReplies(video.Bvid+"_1000062", database.PageRequest{PageSize: 3, Cursor: "bad"}); !errors.Is(err, database.ErrInvalidCursor) {
+		t.Errorf("无效的游标未被拒绝: %v", err)
+	}
+}
+
+// 主评论列表的 reply_count 与回复接口的总数一致
+func TestCommentQueryReplyCount(t *testing.T) {
+	_, video := newCrawledEnv(t)
+
+	cq, err := ParseCommentQuery(url.Values{"sort": {"replies"}, "pageSize": {"100"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := cq.Run(video.Bvid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range result.Comments {
+		replies, err := database.GetCommentReplies(c.UniqueID, database.PageRequest{Page: 1, PageSize: 1})
+		if err != nil {
+			t.Fatalf("查询 %s 的回复: %v", c.UniqueID, err)
+		}
+		if c.ReplyCount != replies.Total || (c.ReplyCount == 0) != (c.LatestReplyAt == nil) {
+			t.Errorf("%s 的 reply_count=%d，回复接口共 %d 条", c.UniqueID, c.ReplyCount, replies.Total)
+		}
+	}
+}
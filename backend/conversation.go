@@ -0,0 +1,113 @@
+package backend
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+
+	"bilibili-comments-viewer-go/database"
+)
+
+// maxAncestors 沿 parent 向上查找祖先的最大层数，防止异常数据形成环
+const maxAncestors = 256
+
+// ErrNotReply 主评论不属于任何对话
+var ErrNotReply = errors.New("主评论没有所属对话，请使用回复的 id")
+
+// ConversationEntry 对话中的一条回复
+type ConversationEntry struct {
+	database.Comment
+	ReplyTo     string `json:"reply_to,omitempty"`      // 被回复评论的 unique_id，直接回复主评论时为空
+	ReplyToName string `json:"reply_to_name,omitempty"` // 被回复者昵称
+	Ancestor    bool   `json:"ancestor"`                // 是否在目标回复沿 parent 向上的回复链上
+	Target      bool   `json:"target"`                  // 是否为请求的回复
+}
+
+// Conversation 一条回复所在的对话（B站楼中楼里以同一 dialog 串起的来回回复）
+type Conversation struct {
+	Root     *database.Comment   `json:"root"`     // 所属主评论，未入库时为 nil
+	Dialog   int64               `json:"dialog"`   // 对话首条回复的 rpid
+	Comments []ConversationEntry `json:"comments"` // 按发布时间升序
+}
+
+// BuildConversation 重建回复所在的对话：同一楼层中 dialog 相同的全部回复，
+// 再补上目标回复沿 parent 向上的祖先（跨对话回复或缺少 dialog 的旧数据时不在同一对话中）
+func BuildConversation(uniqueID string) (*Conversation, error) {
+	target, err := database.GetCommentByID(uniqueID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, ErrCommentNotFound
+	}
+	if target.Parent == "0" {
+		return nil, ErrNotReply
+	}
+
+	conv := &Conversation{Dialog: target.Dialog}
+	comments := map[string]database.Comment{target.UniqueID: *target}
+	if target.Root != 0 && target.Dialog != 0 {
+		dialog, err := database.GetDialogComments(target.BVid, target.Root, target.Dialog)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range dialog {
+			comments[c.UniqueID] = c
+		}
+	}
+
+	// 祖先链，到主评论为止
+	ancestors := map[string]bool{}
+	rootID := ""
+	if target.Root != 0 {
+		rootID = target.BVid + "_" + strconv.FormatInt(target.Root, 10)
+	}
+	for id, steps := target.Parent, 0; id != "0" && steps < maxAncestors; steps++ {
+		c, ok := comments[id]
+		if !ok {
+			parent, err := database.GetCommentByID(id)
+			if err != nil {
+				return nil, err
+			}
+			if parent == nil {
+				break
+			}
+			c = *parent
+		}
+		if c.Parent == "0" {
+			rootID = c.UniqueID
+			break
+		}
+		if ancestors[id] {
+			break
+		}
+		ancestors[id] = true
+		comments[id] = c
+		id = c.Parent
+	}
+
+	if rootID != "" {
+		if conv.Root, err = database.GetCommentByID(rootID); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, c := range comments {
+		entry := ConversationEntry{Comment: c, Ancestor: ancestors[c.UniqueID], Target: c.UniqueID == target.UniqueID}
+		if c.Parent != rootID {
+			entry.ReplyTo = c.Parent
+			if p, ok := comments[c.Parent]; ok {
+				entry.ReplyToName = p.Upname
+			}
+		}
+		conv.Comments = append(conv.Comments, entry)
+	}
+	sort.Slice(conv.Comments, func(i, j int) bool {
+		a, b := conv.Comments[i], conv.Comments[j]
+		if !a.Ctime.Equal(b.Ctime) {
+			return a.Ctime.Before(b.Ctime)
+		}
+		return a.UniqueID < b.UniqueID
+	})
+	return conv, nil
+}
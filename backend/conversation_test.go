@@ -0,0 +1,34 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+)
+
+// 1000003 回复 1000002，所在对话的全部回复按时间排列，1000002 标为祖先
+func TestBuildConversation(t *testing.T) {
+	_, video := newCrawledEnv(t)
+
+	conv, err := BuildConversation(video.Bvid + "_1000003")
+	if err != nil {
+		t.Fatalf("BuildConversation: %v", err)
+	}
+	dialogSize := countRows(t, "SELECT COUNT(*) FROM bilibili_comments WHERE bvid = ? AND dialog = 1000002", video.Bvid)
+	var rpids []int64
+	ancestor := false
+	for i, c := range conv.Comments {
+		rpids = append(rpids, c.Rpid)
+		if i > 0 && c.Ctime.Before(conv.Comments[i-1].Ctime) {
+			t.Errorf("对话未按时间排列: %v", rpids)
+		}
+		ancestor = ancestor || (c.Ancestor && c.Rpid == 1000002)
+	}
+	if conv.Root == nil || conv.Root.Rpid != 1000001 || conv.Dialog != 1000002 || !ancestor ||
+		len(rpids) != dialogSize || rpids[0] != 1000002 {
+		t.Errorf("对话 dialog=%d 依次为 %v，期望 %d 条", conv.Dialog, rpids, dialogSize)
+	}
+
+	if _, err := BuildConversation(video.Bvid + "_1000001"); !errors.Is(err, ErrNotReply) {
+		t.Errorf("主评论的对话未返回 ErrNotReply: %v", err)
+	}
+}
@@ -1,7 +1,6 @@
 package backend
 
 import (
-	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -11,56 +10,6 @@ import (
 	"bilibili-comments-viewer-go/logger"
 )
 
-func importCommentsToDB(bvid string, comments []blblcdmodel.Comment) error {
-	log := logger.GetLogger()
-	log.Infof("正在导入 %d 条评论到数据库 (bvid: %s)", len(comments), bvid)
-
-	// +++ 添加空评论检查 +++
-	if len(comments) == 0 {
-		log.Warn("警告: 尝试导入空评论列表")
-		return nil
-	}
-
-	var dbComments []*database.Comment
-	for i, comment := range comments {
-		dbComment := convertToDBComment(&comment)
-		dbComments = append(dbComments, dbComment)
-
-		if i%100 == 0 {
-			logger.GetLogger().Infof("已转换 %d/%d 条评论", i+1, len(comments))
-		}
-	}
-
-	logger.GetLogger().Infof("开始批量保存 %d 条评论...", len(dbComments))
-
-	if err := database.BatchSaveComments(dbComments); err != nil {
-		return fmt.Errorf("批量保存评论失败: %w", err)
-	}
-
-	logger.GetLogger().Infof("评论数据保存完成，开始处理回复关系...")
-
-	for _, dbComment := range dbComments {
-		if len(dbComment.Replies) > 0 {
-			logger.GetLogger().Infof("为评论 %s 保存 %d 条回复关系", dbComment.UniqueID, len(dbComment.Replies))
-			if err := database.SaveCommentRelations(dbComment.UniqueID, dbComment.Replies); err != nil {
-				logger.GetLogger().Errorf("保存评论关系失败: %v", err)
-			}
-		}
-	}
-
-	logger.GetLogger().Infof("开始更新评论统计...")
-	if err := database.UpdateCommentStats(bvid); err != nil {
-		logger.GetLogger().Errorf("更新评论统计失败: %v", err)
-	}
-
-	logger.GetLogger().Infof("重建所有评论关系...")
-	if err := database.RebuildAllCommentRelations(bvid); err != nil {
-		logger.GetLogger().Errorf("重建评论关系失败: %v", err)
-	}
-
-	return nil
-}
-
 func convertToDBComment(comment *blblcdmodel.Comment) *database.Comment {
 	// 校验 Bvid 和 Rpid
 	if strings.HasPrefix(comment.Bvid, "http") || strings.Contains(comment.Bvid, "/") {
@@ -96,10 +45,16 @@ func convertToDBComment(comment *blblcdmodel.Comment) *database.Comment {
 	// 处理父评论ID
 	parentID := "0"
 	if comment.Parent != 0 {
-		parentID = comment.Bvid + "_" + strconv.FormatInt(int64(comment.Parent), 10)
+		parentID = comment.Bvid + "_" + strconv.FormatInt(comment.Parent, 10)
 	}
 
-	return &database.Comment{
+	// 处理@提及
+	var members []database.Mention
+	for _, m := range comment.Members {
+		members = append(members, database.Mention{Mid: m.Mid, Uname: m.Uname})
+	}
+
+	dbComment := &database.Comment{
 		UniqueID:  uniqueID,
 		BVid:      comment.Bvid,
 		Rpid:      comment.Rpid,
@@ -116,6 +71,24 @@ func convertToDBComment(comment *blblcdmodel.Comment) *database.Comment {
 		Following: comment.Following,
 		Level:     comment.Current_level,
 		Location:  comment.Location,
+		Rcount:    comment.Rcount,
+		Root:      comment.Root,
+		Dialog:    comment.Dialog,
+		Invisible: comment.Invisible,
+		Pinned:    comment.Pinned,
+		UpReplied: comment.UpReplied,
+		TimeDesc:  comment.TimeDesc,
+		Avatar:    comment.Avatar,
+		VipType:   comment.Vip.Type,
+		VipStatus: comment.Vip.Status,
+		VipLabel:  comment.Vip.Label,
+		Emotes:    comment.Emotes,
+		Members:   members,
 		Replies:   replies, // 修复：添加回复关系
 	}
+	if comment.FansMedal != nil {
+		dbComment.FansMedal = comment.FansMedal.Name
+		dbComment.FansMedalLevel = comment.FansMedal.Level
+	}
+	return dbComment
 }
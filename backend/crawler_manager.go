@@ -2,6 +2,7 @@ package backend
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"reflect"
@@ -12,36 +13,43 @@ import (
 	"bilibili-comments-viewer-go/config"
 	"bilibili-comments-viewer-go/crawler/blblcd"
 	blblcdmodel "bilibili-comments-viewer-go/crawler/blblcd/model"
-	blblcdstore "bilibili-comments-viewer-go/crawler/blblcd/store"
+	"bilibili-comments-viewer-go/crawler/blblcd/progress"
 	"bilibili-comments-viewer-go/database"
 	"bilibili-comments-viewer-go/logger"
 	"bilibili-comments-viewer-go/utils"
 )
 
 func CrawlAndImport(ctx context.Context, bvid string) error {
-	funcName := runtime.FuncForPC(reflect.ValueOf(CrawlAndImport).Pointer()).Name()
+	_, err := crawlAndImport(ctx, bvid, false, nil)
+	return err
+}
+
+// crawlAndImport 爬取并导入视频评论，返回爬取到的评论数
+// incremental: 是否只爬取上次入库之后的新增评论，数据库中没有该视频评论时自动全量爬取
+// reporter: 进度事件发布者，可为 nil
+func crawlAndImport(ctx context.Context, bvid string, incremental bool, reporter *progress.Reporter) (int, error) {
+	funcName := runtime.FuncForPC(reflect.ValueOf(crawlAndImport).Pointer()).Name()
 	log := logger.GetLogger()
 
 	// 添加上下文超时控制
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Minute)
 	defer cancel()
 
-	// +++ 添加关键日志 +++
 	log.Infof("START %s: bvid=%s", funcName, bvid)
 
 	defer func() {
 		if r := recover(); r != nil {
-			log.Errorf("CrawlAndImport PANIC: %v\n%s", r, string(debug.Stack()))
+			log.Errorf("crawlAndImport PANIC: %v\n%s", r, string(debug.Stack()))
 		}
 		log.Infof("END %s: bvid=%s", funcName, bvid)
 	}()
 
 	cfg := config.Get()
-	log.Infof("开始处理视频: %s (保存模式: %s)", bvid, cfg.Crawler.SaveMode)
+	log.Infof("开始处理视频: %s (输出目标: %v)", bvid, cfg.Crawler.Sinks)
 
 	// 获取并保存视频元数据
 	log.Infof("获取视频元数据: %s", bvid)
-	if videoInfo, err := FetchVideoMetadata(bvid); err == nil {
+	if videoInfo, err := FetchVideoMetadata(ctx, bvid); err == nil {
 		dbVideo := &database.Video{
 			BVid:  videoInfo.BVID,
 			Title: videoInfo.Title,
@@ -59,51 +67,130 @@ func CrawlAndImport(ctx context.Context, bvid string) error {
 		database.SaveVideo(dbVideo)
 	}
 
-	// 爬取评论
-	log.Infof("开始爬取评论: %s", bvid)
-	comments, err := crawlVideoComments(ctx, bvid)
+	// 上次全量爬取被取消或中断时留有断点，优先从断点继续
+	checkpoint, err := database.GetCrawlCheckpoint(bvid)
 	if err != nil {
-		log.Errorf("评论爬取失败: %v", err)
-		return CrawlerError{Message: "评论爬取失败: " + err.Error()}
+		log.Warnf("查询爬取断点失败，忽略断点: %v", err)
+		checkpoint = nil
 	}
 
-	// +++ 添加关键日志 +++
-	log.Infof("爬取到 %d 条评论 (bvid: %s)", len(comments), bvid)
-
-	// +++ 处理空评论情况 +++
-	if len(comments) == 0 {
-		log.Warnf("未爬取到评论，跳过处理 (bvid: %s)", bvid)
-		return nil
+	var state *blblcdmodel.IncrementalState
+	if incremental {
+		if checkpoint != nil {
+			log.Infof("视频 %s 有未完成的全量爬取（第%d页），从断点继续而不是增量爬取", bvid, checkpoint.Page)
+		} else {
+			state = loadIncrementalState(bvid)
+		}
 	}
 
-	// 根据保存模式处理评论
-	switch cfg.Crawler.SaveMode {
-	case SaveModeCSVOnly:
-		log.Infof("CSV_ONLY模式处理评论: %s", bvid)
-		processCSVOnly(bvid, comments)
-	case SaveModeDBOnly:
-		log.Infof("DB_ONLY模式导入评论: %s", bvid)
-		if err := importCommentsToDB(bvid, comments); err != nil {
-			log.Errorf("导入数据库失败: %v", err)
-			return err
+	// 写入数据库时记录本次爬取，完整爬取成功结束后据此标记已删除的评论
+	seenAt := time.Now()
+	var crawl *database.VideoCrawl
+	if hasSink(SinkSQLite) {
+		mode := database.VideoCrawlFull
+		if checkpoint != nil {
+			mode = database.VideoCrawlResume
+		} else if state != nil {
+			mode = database.VideoCrawlIncremental
+		}
+		if crawl, err = database.StartVideoCrawl(bvid, mode); err != nil {
+			log.Warnf("创建爬取记录失败，本次爬取不标记已删除的评论: %v", err)
+			crawl = nil
 		} else {
-			log.Infof("成功导入 %d 条评论到数据库 (bvid: %s)", len(comments), bvid)
+			seenAt = crawl.StartedAt
 		}
-	default: // SaveModeCSVAndDB
-		log.Infof("CSV_AND_DB模式处理评论: %s", bvid)
-		processCSVAndDB(bvid, comments)
 	}
 
-	// +++ 新增：根据配置自动下载评论图片 +++
-	if cfg.Crawler.ImgDownload {
-		DownloadAllCommentImages(comments)
+	// 增量结果只包含新增评论、断点续爬只包含断点之后的评论，文件输出追加到已有文件
+	sinks, err := newVideoSinks(bvid, state != nil || checkpoint != nil, seenAt)
+	if err != nil {
+		finishVideoCrawl(crawl, database.VideoCrawlFailed, 0, true)
+		return 0, err
+	}
+
+	// 爬取评论：爬虫边爬取边输出，评论按批分发给各输出目标
+	log.Infof("开始爬取评论: %s (增量: %v)", bvid, state != nil)
+	// 断点在评论写入各输出目标后才提交，写入失败时保留上次确认的断点
+	out := make(chan blblcdmodel.Comment, blblcd.DefaultSinkBatchSize)
+	commits := blblcd.NewCheckpointCommitter(dbCheckpointStore{}, nil)
+	crawlErr := make(chan error, 1)
+	go func() {
+		crawlErr <- crawlVideoComments(ctx, bvid, state, reporter, commits, out)
+	}()
+
+	count, importErr := blblcd.WriteSinksAck(out, sinks, blblcd.DefaultSinkBatchSize, blblcd.DefaultSinkFlushInterval,
+		func(written int) { commits.Ack(bvid, written) })
+	err = <-crawlErr
+	commits.Finish(bvid, importErr)
+
+	log.Infof("爬取到 %d 条评论 (bvid: %s)", count, bvid)
+
+	// 爬取正常结束但无法确认已到达末尾：评论照常保存，本次不标记已删除的评论
+	incomplete := errors.Is(err, blblcdmodel.ErrIncomplete)
+	if incomplete {
+		log.Warnf("视频 %s 的评论爬取结束，但%v，本次不标记已删除的评论", bvid, err)
+		err = nil
+	}
+	if err != nil {
+		// 被取消或超时：已获取的评论已写入，断点保存在数据库中以便下次继续，原样返回 ctx 错误
+		log.Warnf("评论爬取被中断 (bvid: %s): %v，已保存获取的 %d 条评论", bvid, err, count)
+		finishVideoCrawl(crawl, database.VideoCrawlInterrupted, count, true)
+		return count, err
+	}
+	if importErr != nil {
+		log.Errorf("保存评论失败: %v", importErr)
+		finishVideoCrawl(crawl, database.VideoCrawlFailed, count, true)
+		return count, importErr
 	}
+	finishVideoCrawl(crawl, database.VideoCrawlCompleted, count, incomplete)
 
 	log.Infof("视频 %s 的评论处理完成", bvid)
-	return nil
+	return count, nil
+}
+
+// finishVideoCrawl 记录爬取的最终状态，crawl 为 nil（未写入数据库）时忽略
+// incomplete: 是否未能确认爬完全部评论，此时不标记已删除的评论
+func finishVideoCrawl(crawl *database.VideoCrawl, status string, count int, incomplete bool) {
+	if crawl == nil {
+		return
+	}
+	log := logger.GetLogger()
+	if err := database.FinishVideoCrawl(crawl, status, count, incomplete); err != nil {
+		log.Errorf("更新爬取记录失败: %v", err)
+		return
+	}
+	if crawl.Deleted > 0 {
+		log.Infof("视频 %s 有 %d 条评论在本次完整爬取中未再出现，已标记为已删除", crawl.Bvid, crawl.Deleted)
+	}
+}
+
+// loadIncrementalState 从数据库加载增量爬取状态，无法增量时返回 nil 以全量爬取
+func loadIncrementalState(bvid string) *blblcdmodel.IncrementalState {
+	log := logger.GetLogger()
+	if !hasSink(SinkSQLite) {
+		log.Infof("未启用数据库输出，数据库中没有评论记录，视频 %s 将全量爬取", bvid)
+		return nil
+	}
+
+	roots, latest, err := database.GetRootCommentIndex(bvid)
+	if err != nil {
+		log.Errorf("加载增量爬取状态失败，将全量爬取: %v", err)
+		return nil
+	}
+	if len(roots) == 0 {
+		log.Infof("数据库中没有视频 %s 的评论，将全量爬取", bvid)
+		return nil
+	}
+	return &blblcdmodel.IncrementalState{KnownRoots: roots, LatestCtime: int(latest), ExtraPages: config.Get().Crawler.IncrementalExtraPages}
+}
+
+func CrawlUpVideos(ctx context.Context, mid int64, fetchAll bool) error {
+	return crawlUpVideos(ctx, mid, fetchAll, nil)
 }
 
-func CrawlUpVideos(mid int, fetchAll bool) error {
+// crawlUpVideos 爬取UP主视频评论，reporter 为进度事件发布者，可为 nil
+// 每个视频的评论边爬取边写入输出目标，ctx 被取消时已获取的评论均已写入，原样返回 ctx 错误
+func crawlUpVideos(ctx context.Context, mid int64, fetchAll bool, reporter *progress.Reporter) error {
 	cfg := config.Get()
 	opt := blblcdmodel.NewDefaultOption()
 
@@ -112,6 +199,8 @@ func CrawlUpVideos(mid int, fetchAll bool) error {
 	opt.Output = cfg.Crawler.OutputDir
 	opt.Mid = mid
 	opt.FetchAll = fetchAll
+	opt.Progress = reporter
+	opt.RawPages = rawPageStore()
 	if cfg.Crawler.UpPages > 0 {
 		opt.Pages = cfg.Crawler.UpPages
 	}
@@ -134,28 +223,16 @@ func CrawlUpVideos(mid int, fetchAll bool) error {
 	log.Printf("开始爬取UP主 %d 的视频 (页数: %d, 排序: %s, 协程: %d, 爬取所有: %v)",
 		mid, opt.Pages, opt.Vorder, opt.Workers, opt.FetchAll)
 
-	if err := blblcd.CrawlUp(mid, opt); err != nil {
+	// 每个视频的评论直接写入配置的输出目标
+	err := blblcd.CrawlUp(ctx, mid, opt, func(bvid string) ([]blblcd.Sink, error) {
+		return newVideoSinks(bvid, false, time.Now())
+	})
+	if err != nil && ctx.Err() == nil {
 		return CrawlerError{Message: fmt.Sprintf("UP主视频爬取失败: %s", err.Error())}
 	}
 
-	// 根据保存模式决定是否导入CSV
-	if cfg.Crawler.SaveMode != SaveModeDBOnly {
-		processCSVFiles()
-	}
-
-	return nil
-}
-
-// DownloadAllCommentImages 批量下载所有评论的图片（配置化：是否下载、保存路径，按BV号分目录）
-func DownloadAllCommentImages(comments []blblcdmodel.Comment) {
-	cfg := config.Get()
-	if !cfg.Crawler.ImgDownload {
-		return // 配置未开启图片下载
-	}
-	outputDir := cfg.ImageStorageDir
-	for _, cmt := range comments {
-		if len(cmt.Pictures) > 0 && cmt.Bvid != "" {
-			blblcdstore.WriteImage(cmt.Bvid, cmt.Pictures, outputDir)
-		}
+	if err != nil {
+		log.Printf("UP主 %d 的视频爬取已中断: %v", mid, err)
 	}
+	return err
 }
@@ -0,0 +1,262 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"bilibili-comments-viewer-go/config"
+	"bilibili-comments-viewer-go/crawler/biliapi"
+	"bilibili-comments-viewer-go/database"
+	"bilibili-comments-viewer-go/internal/fakebili"
+)
+
+// 主评论内嵌的回复数，超过时需要翻页爬取子评论
+const previewReplies = 3
+
+func TestCrawlAndImport(t *testing.T) {
+	e := newFakeEnv(t)
+	video := e.fx.Videos[0]
+
+	// 第一次子评论请求返回 502，重试后继续
+	e.server.FailNext(fakebili.PathReplyReply, fakebili.ServerError, 1)
+	e.crawl(t, video.Bvid)
+
+	if got, want := commentCount(t, video.Bvid), video.CommentCount(); got != want {
+		t.Errorf("入库评论数 %d，期望 %d", got, want)
+	}
+	v, err := database.GetVideoByBVid(video.Bvid)
+	if err != nil || v == nil {
+		t.Fatalf("视频信息未入库: %v", err)
+	}
+	if v.Title != video.Title || v.Cover == "" {
+		t.Errorf("视频信息 title=%q cover=%q，期望标题 %q 且封面已下载", v.Title, v.Cover, video.Title)
+	}
+	if n := e.server.Hits(fakebili.PathNav); n != 1 {
+		t.Errorf("WBI 密钥获取了 %d 次，期望 1 次", n)
+	}
+	// 子评论按回复总数翻页，不额外请求空页
+	wantReplyHits := 1 // 注入的 502
+	for _, c := range video.Comments {
+		if n := len(c.Replies); n > previewReplies {
+			wantReplyHits += (n + 19) / 20
+		}
+	}
+	if n := e.server.Hits(fakebili.PathReplyReply); n != wantReplyHits {
+		t.Errorf("子评论请求 %d 次，期望 %d 次", n, wantReplyHits)
+	}
+	csv := filepath.Join(config.Get().Crawler.OutputDir, video.Bvid, video.Bvid+".csv")
+	if _, err := os.Stat(csv); err != nil {
+		t.Errorf("CSV 输出不存在: %v", err)
+	}
+	if cp, err := database.GetCrawlCheckpoint(video.Bvid); err != nil || cp != nil {
+		t.Errorf("爬完后断点未删除: %+v, %v", cp, err)
+	}
+
+}
+
+func TestCrawlUpVideos(t *testing.T) {
+	e := newFakeEnv(t)
+	owner := e.fx.Users[0]
+
+	// 重复爬取的视频不产生重复评论
+	e.crawl(t, e.fx.Videos[1].Bvid)
+	if err := crawlUpVideos(e.ctx, owner.Mid, true, nil); err != nil {
+		t.Fatalf("crawlUpVideos(%d): %v", owner.Mid, err)
+	}
+	videos := e.fx.VideosOf(owner.Mid)
+	if len(videos) == 0 {
+		t.Fatal("模拟数据中UP主没有视频")
+	}
+	for _, v := range videos {
+		if got, want := commentCount(t, v.Bvid), v.CommentCount(); got != want {
+			t.Errorf("%s 入库评论数 %d，期望 %d", v.Bvid, got, want)
+		}
+		if cp, err := database.GetCrawlCheckpoint(v.Bvid); err != nil || cp != nil {
+			t.Errorf("%s 爬完后断点未删除: %+v, %v", v.Bvid, cp, err)
+		}
+	}
+}
+
+// 输出目标写入失败时保留断点，修复后从断点继续并在写入成功后删除
+func TestCrawlAndImportKeepsCheckpointWhenSinkFails(t *testing.T) {
+	e := newFakeEnv(t)
+	video := e.fx.Videos[1]
+	// 上次爬取在第一页之前中断
+	if err := database.SaveCrawlCheckpoint(&database.CrawlCheckpoint{Bvid: video.Bvid, Page: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// CSV 文件路径被目录占用，打开失败
+	csv := filepath.Join(config.Get().Crawler.OutputDir, video.Bvid, video.Bvid+".csv")
+	if err := os.MkdirAll(csv, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := crawlAndImport(e.ctx, video.Bvid, false, nil); err == nil {
+		t.Fatal("CSV 写入失败时 crawlAndImport 未返回错误")
+	}
+	if cp, err := database.GetCrawlCheckpoint(video.Bvid); err != nil || cp == nil {
+		t.Fatalf("写入失败后断点被删除: %v", err)
+	}
+	crawls, err := database.GetVideoCrawls(video.Bvid)
+	if err != nil || len(crawls) != 1 || crawls[0].Status != database.VideoCrawlFailed || crawls[0].Mode != database.VideoCrawlResume {
+		t.Fatalf("爬取记录 %+v err=%v，期望一次失败的断点续爬", crawls, err)
+	}
+
+	if err := os.Remove(csv); err != nil {
+		t.Fatal(err)
+	}
+	e.crawl(t, video.Bvid)
+	if cp, err := database.GetCrawlCheckpoint(video.Bvid); err != nil || cp != nil {
+		t.Errorf("写入成功后断点未删除: %+v, %v", cp, err)
+	}
+	if got, want := commentCount(t, video.Bvid), video.CommentCount(); got != want {
+		t.Errorf("入库评论数 %d，期望 %d", got, want)
+	}
+}
+
+func TestCrawlAndImportReplay(t *testing.T) {
+	e := newFakeEnv(t)
+	video := e.fx.Videos[0]
+
+	archive := filepath.Join(t.TempDir(), "fake.tar.zst")
+	rec, err := biliapi.NewRecorder(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := crawlAndImport(biliapi.WithRecorder(e.ctx, rec), video.Bvid, false, nil); err != nil {
+		t.Fatalf("录制爬取: %v", err)
+	}
+	if err := rec.Close(); err != nil || rec.Count() == 0 {
+		t.Fatalf("录制了 %d 次接口请求: %v", rec.Count(), err)
+	}
+
+	// 回放：从存档重新爬取，不访问模拟服务器
+	apiHits := func() (n int) {
+		for _, p := range []string{fakebili.PathReplyMain, fakebili.PathReplyReply, fakebili.PathReplyCount, fakebili.PathView, fakebili.PathNav} {
+			n += e.server.Hits(p)
+		}
+		return n
+	}
+	before := apiHits()
+	rp, err := biliapi.OpenReplay(archive)
+	if err != nil {
+		t.Fatalf("打开回放存档: %v", err)
+	}
+	if _, err := crawlAndImport(biliapi.WithReplay(e.ctx, rp), video.Bvid, false, nil); err != nil {
+		t.Fatalf("回放爬取: %v", err)
+	}
+	if n := apiHits() - before; n != 0 {
+		t.Errorf("回放时访问了服务器 %d 次", n)
+	}
+	if got, want := commentCount(t, video.Bvid), video.CommentCount(); got != want {
+		t.Errorf("回放后评论数 %d，期望 %d", got, want)
+	}
+}
+
+// 完整评论字段：楼中楼的 root/dialog、头像、大会员、粉丝勋章与表情
+func TestCrawlAndImportCommentFields(t *testing.T) {
+	_, video := newCrawledEnv(t)
+
+	var root, dialog int64
+	var avatar string
+	err := database.GetDB().QueryRow("SELECT root, dialog, avatar FROM bilibili_comments WHERE unique_id = ?", video.Bvid+"_1000003").
+		Scan(&root, &dialog, &avatar)
+	if err != nil || root != 1000001 || dialog != 1000002 || avatar == "" {
+		t.Errorf("楼中楼回复 root=%d dialog=%d avatar=%q err=%v", root, dialog, avatar, err)
+	}
+	var vipLabel, medal, emotes string
+	err = database.GetDB().QueryRow("SELECT vip_label, fans_medal, emotes FROM bilibili_comments WHERE unique_id = ?", video.Bvid+"_1000098").
+		Scan(&vipLabel, &medal, &emotes)
+	if err != nil || vipLabel == "" || medal == "" || !strings.Contains(emotes, "[吃瓜]") {
+		t.Errorf("大会员 %q、粉丝勋章 %q、表情 %s err=%v", vipLabel, medal, emotes, err)
+	}
+}
+
+// 增量爬取在到达已入库评论的那一页停止，只写入新增评论
+func TestIncrementalCrawlStopsAtStoredComments(t *testing.T) {
+	e := newFakeEnv(t)
+	video := &e.fx.Videos[0]
+	e.crawl(t, video.Bvid)
+
+	video.Comments = append(video.Comments, fakebili.Comment{Rpid: 1999002, Mid: 43, Uname: "新评论", Message: "新的主评论", Ctime: time.Now().Unix(), Level: 2})
+	before := e.server.Hits(fakebili.PathReplyMain)
+	if _, err := crawlAndImport(e.ctx, video.Bvid, true, nil); err != nil {
+		t.Fatalf("增量爬取: %v", err)
+	}
+	if n := e.server.Hits(fakebili.PathReplyMain) - before; n != 1 {
+		t.Errorf("增量爬取请求了 %d 页主评论，期望只请求第 1 页", n)
+	}
+	if got, want := commentCount(t, video.Bvid), video.CommentCount(); got != want {
+		t.Errorf("增量爬取后评论数 %d，期望 %d", got, want)
+	}
+	crawls, err := database.GetVideoCrawls(video.Bvid)
+	if err != nil || len(crawls) != 2 || crawls[0].Mode != database.VideoCrawlIncremental || crawls[0].Incomplete {
+		t.Errorf("爬取记录 %+v err=%v，期望一次完整结束的增量爬取", crawls, err)
+	}
+}
+
+// 未到达已入库评论就返回空页时记为未完成
+func TestIncrementalCrawlEmptyPageIsIncomplete(t *testing.T) {
+	e := newFakeEnv(t)
+	video := e.fx.Videos[0]
+	e.crawl(t, video.Bvid)
+
+	e.server.FailNext(fakebili.PathReplyMain, fakebili.EmptyPage, 1)
+	if _, err := crawlAndImport(e.ctx, video.Bvid, true, nil); err != nil {
+		t.Fatalf("增量爬取: %v", err)
+	}
+	crawls, err := database.GetVideoCrawls(video.Bvid)
+	if err != nil || len(crawls) != 2 || crawls[0].Status != database.VideoCrawlCompleted || !crawls[0].Incomplete {
+		t.Errorf("爬取记录 %+v err=%v，期望增量爬取记为未完成", crawls, err)
+	}
+}
+
+// 增量爬取刷新到达已入库评论之后再翻的页面上的旧楼层；子评论未爬完时保留入库时的回复数，下次继续刷新
+func TestIncrementalCrawlRefreshesOldThreads(t *testing.T) {
+	e := newFakeEnv(t)
+	video := &e.fx.Videos[0]
+	e.crawl(t, video.Bvid)
+	config.Get().Crawler.IncrementalExtraPages = 2 // 新评论在第 1 页，最早的主评论在第 3 页
+
+	// 最早的主评论（最后一页）有多页回复，新增一条回复；同时新增一条主评论
+	oldest := &video.Comments[0]
+	for i := range video.Comments {
+		if video.Comments[i].Ctime < oldest.Ctime {
+			oldest = &video.Comments[i]
+		}
+	}
+	if len(oldest.Replies) <= previewReplies {
+		t.Fatalf("最早的主评论 %d 只有 %d 条回复", oldest.Rpid, len(oldest.Replies))
+	}
+	now := time.Now().Unix()
+	oldest.Replies = append(oldest.Replies, fakebili.Comment{Rpid: 1999001, Mid: 42, Uname: "新回复", Message: "迟到的回复", Ctime: now, Level: 3})
+	video.Comments = append(video.Comments, fakebili.Comment{Rpid: 1999002, Mid: 43, Uname: "新评论", Message: "新的主评论", Ctime: now, Level: 2})
+	rcount := func() int {
+		return countRows(t, "SELECT rcount FROM bilibili_comments WHERE unique_id = ?", fmt.Sprintf("%s_%d", video.Bvid, oldest.Rpid))
+	}
+	stored := rcount()
+
+	// 子评论请求失败且不重试：楼层未刷新完，回复数保持不变
+	config.Get().Crawler.MaxTryCount = 1
+	e.server.FailNext(fakebili.PathReplyReply, fakebili.CodeError, 1)
+	if _, err := crawlAndImport(e.ctx, video.Bvid, true, nil); err == nil {
+		t.Fatal("子评论请求失败时增量爬取未返回错误")
+	}
+	if got := rcount(); got != stored {
+		t.Errorf("子评论未爬完时回复数被更新为 %d，期望保持 %d", got, stored)
+	}
+
+	if _, err := crawlAndImport(e.ctx, video.Bvid, true, nil); err != nil {
+		t.Fatalf("增量爬取: %v", err)
+	}
+	if got := rcount(); got != len(oldest.Replies) {
+		t.Errorf("刷新后回复数 %d，期望 %d", got, len(oldest.Replies))
+	}
+	if got, want := commentCount(t, video.Bvid), video.CommentCount(); got != want {
+		t.Errorf("增量爬取后评论数 %d，期望 %d", got, want)
+	}
+}
@@ -2,66 +2,16 @@ package backend
 
 import (
 	"encoding/csv"
-	"fmt"
+	"encoding/json"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
-	"bilibili-comments-viewer-go/logger"
-
-	blblcdmodel "bilibili-comments-viewer-go/crawler/blblcd/model"
 	"bilibili-comments-viewer-go/database"
+	"bilibili-comments-viewer-go/logger"
 )
 
-func saveCommentsToCSV(comments []blblcdmodel.Comment, csvPath string) error {
-	if err := os.MkdirAll(filepath.Dir(csvPath), 0755); err != nil {
-		return fmt.Errorf("创建CSV目录失败: %w", err)
-	}
-
-	file, err := os.Create(csvPath)
-	if err != nil {
-		return fmt.Errorf("创建CSV文件失败: %w", err)
-	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	headers := []string{"bvid", "upname", "sex", "content", "pictures", "rpid", "oid", "mid",
-		"parent", "fans_grade", "ctime", "like_count", "following", "level", "location"}
-	if err := writer.Write(headers); err != nil {
-		return fmt.Errorf("写入CSV标题失败: %w", err)
-	}
-
-	for _, comment := range comments {
-		record := []string{
-			comment.Bvid,
-			comment.Uname,
-			comment.Sex,
-			comment.Content,
-			"", // 图片留空
-			strconv.FormatInt(comment.Rpid, 10),
-			strconv.Itoa(comment.Oid),
-			strconv.Itoa(comment.Mid),
-			strconv.Itoa(comment.Parent),
-			strconv.Itoa(comment.Fansgrade),
-			strconv.Itoa(comment.Ctime),
-			strconv.Itoa(comment.Like),
-			strconv.FormatBool(comment.Following),
-			strconv.Itoa(comment.Current_level),
-			comment.Location,
-		}
-
-		if err := writer.Write(record); err != nil {
-			logger.GetLogger().Errorf("写入评论失败: %v", err)
-		}
-	}
-
-	return nil
-}
-
 func ImportCommentsFromCSV(bvid, filePath string) error {
 	// 打开CSV文件
 	file, err := os.Open(filePath)
@@ -87,7 +37,7 @@ func ImportCommentsFromCSV(bvid, filePath string) error {
 	}
 
 	// 第一遍：构建映射关系
-	rpidToUniqueID := make(map[int]string)
+	rpidToUniqueID := make(map[int64]string)
 	allComments := make([]map[string]string, 0)
 
 	// 读取所有行
@@ -115,7 +65,7 @@ func ImportCommentsFromCSV(bvid, filePath string) error {
 		uniqueID := bvidVal + "_" + rpidVal
 
 		// 存储映射关系
-		if rpid, err := strconv.Atoi(rpidVal); err == nil {
+		if rpid, err := strconv.ParseInt(rpidVal, 10, 64); err == nil {
 			rpidToUniqueID[rpid] = uniqueID
 			allComments = append(allComments, comment)
 		}
@@ -124,7 +74,7 @@ func ImportCommentsFromCSV(bvid, filePath string) error {
 	// 第二遍：构建父子关系
 	parentChildMap := make(map[string][]string)
 	for _, comment := range allComments {
-		childRpid, err := strconv.Atoi(comment["rpid"])
+		childRpid, err := strconv.ParseInt(comment["rpid"], 10, 64)
 		if err != nil {
 			continue
 		}
@@ -135,7 +85,7 @@ func ImportCommentsFromCSV(bvid, filePath string) error {
 			parentVal = "0"
 		}
 
-		parentRpid, err := strconv.Atoi(parentVal)
+		parentRpid, err := strconv.ParseInt(parentVal, 10, 64)
 		if err != nil {
 			continue
 		}
@@ -170,7 +120,7 @@ func ImportCommentsFromCSV(bvid, filePath string) error {
 		parentVal := comment["parent"]
 		parentID := "0"
 		if parentVal != "" && parentVal != "0" {
-			if parentRpid, err := strconv.Atoi(parentVal); err == nil {
+			if parentRpid, err := strconv.ParseInt(parentVal, 10, 64); err == nil {
 				if parentUniqueID, exists := rpidToUniqueID[parentRpid]; exists {
 					parentID = parentUniqueID
 				}
@@ -196,12 +146,12 @@ func ImportCommentsFromCSV(bvid, filePath string) error {
 		}
 
 		// 解析oid
-		if oid, err := strconv.Atoi(comment["oid"]); err == nil {
+		if oid, err := strconv.ParseInt(comment["oid"], 10, 64); err == nil {
 			dbComment.Oid = oid
 		}
 
 		// 解析mid
-		if mid, err := strconv.Atoi(comment["mid"]); err == nil {
+		if mid, err := strconv.ParseInt(comment["mid"], 10, 64); err == nil {
 			dbComment.Mid = mid
 		}
 
@@ -217,8 +167,12 @@ func ImportCommentsFromCSV(bvid, filePath string) error {
 			}
 		}
 
-		// 解析点赞数
-		if likeCount, err := strconv.Atoi(comment["like_count"]); err == nil {
+		// 解析点赞数，兼容旧版本写出的 like_count 列
+		likeStr, ok := comment["like"]
+		if !ok {
+			likeStr = comment["like_count"]
+		}
+		if likeCount, err := strconv.Atoi(likeStr); err == nil {
 			dbComment.LikeCount = likeCount
 		}
 
@@ -236,6 +190,31 @@ func ImportCommentsFromCSV(bvid, filePath string) error {
 		dbComment.Sex = comment["sex"]
 		dbComment.Location = comment["location"]
 
+		// 新版本写出的扩展列，旧文件中不存在时保持零值
+		dbComment.Rcount, _ = strconv.Atoi(comment["rcount"])
+		dbComment.Root, _ = strconv.ParseInt(comment["root"], 10, 64)
+		dbComment.Dialog, _ = strconv.ParseInt(comment["dialog"], 10, 64)
+		dbComment.Invisible = comment["invisible"] == "true"
+		dbComment.Pinned = comment["pinned"] == "true"
+		dbComment.UpReplied = comment["up_replied"] == "true"
+		dbComment.TimeDesc = comment["time_desc"]
+		dbComment.Avatar = comment["avatar"]
+		dbComment.VipType, _ = strconv.Atoi(comment["vip_type"])
+		dbComment.VipStatus, _ = strconv.Atoi(comment["vip_status"])
+		dbComment.VipLabel = comment["vip_label"]
+		dbComment.FansMedal = comment["fans_medal"]
+		dbComment.FansMedalLevel, _ = strconv.Atoi(comment["fans_medal_level"])
+		if emotes := comment["emotes"]; emotes != "" {
+			if err := json.Unmarshal([]byte(emotes), &dbComment.Emotes); err != nil {
+				logger.GetLogger().Warnf("解析评论 %s 的表情失败: %v", uniqueID, err)
+			}
+		}
+		if members := comment["members"]; members != "" {
+			if err := json.Unmarshal([]byte(members), &dbComment.Members); err != nil {
+				logger.GetLogger().Warnf("解析评论 %s 的@提及失败: %v", uniqueID, err)
+			}
+		}
+
 		// 处理图片
 		if pics, ok := comment["pictures"]; ok && pics != "" {
 			picList := strings.Split(pics, ";")
@@ -0,0 +1,118 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bilibili-comments-viewer-go/config"
+	biliutil "bilibili-comments-viewer-go/crawler/bili_info/util"
+	"bilibili-comments-viewer-go/database"
+	"bilibili-comments-viewer-go/internal/fakebili"
+	"bilibili-comments-viewer-go/logger"
+)
+
+// 离线测试：每个测试启动一个 fakebili 模拟服务器，配置、数据库与输出目录指向测试的临时目录
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "bcvg-test-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "创建临时目录失败: %v\n", err)
+		os.Exit(1)
+	}
+	// 默认路径都位于 user_data_dir 下，避免加载配置时在主目录中创建目录
+	os.Setenv("USER_DATA_DIR", dir)
+	if _, err := config.LoadConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
+		os.Exit(1)
+	}
+	logger.InitLogger("", "error", 1, 1, 1)
+	for _, ep := range []biliutil.Endpoint{biliutil.EndpointReplyMain, biliutil.EndpointReplyReply,
+		biliutil.EndpointArcSearch, biliutil.EndpointView, biliutil.EndpointNav} {
+		biliutil.SetRateLimit(ep, 0, 0)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// fakeEnv 一个测试的离线环境
+type fakeEnv struct {
+	server *fakebili.Server
+	fx     *fakebili.Fixture
+	ctx    context.Context
+}
+
+// newFakeEnv 启动模拟服务器并初始化临时目录中的数据库，测试结束时关闭
+func newFakeEnv(t *testing.T) *fakeEnv {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := config.Get()
+	cfg.UserDataDir = dir
+	cfg.DatabasePath = filepath.Join(dir, "bilibili.db")
+	cfg.ImageStorageDir = filepath.Join(dir, "images")
+	cfg.Crawler.OutputDir = filepath.Join(dir, "crawler_output")
+	cfg.Crawler.CookieFile = filepath.Join(dir, "cookie.txt")
+	cfg.Crawler.Sinks = []string{SinkSQLite, SinkCSV}
+	cfg.Crawler.ImgDownload = true
+	cfg.Crawler.NoCover = false
+	cfg.Crawler.MaxTryCount = 3
+	cfg.Crawler.IncrementalExtraPages = 0
+	// 0 表示使用爬虫的默认间隔（数秒），设为最小值
+	cfg.Crawler.DelayBaseMs = 1
+	cfg.Crawler.DelayJitterMs = 1
+	if err := os.WriteFile(cfg.Crawler.CookieFile, []byte("SESSDATA=fake"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := fakebili.New(nil)
+	restore := server.Install()
+	if err := database.InitDB(cfg.DatabasePath); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	t.Cleanup(func() {
+		cancel()
+		database.CloseDB()
+		restore()
+		server.Close()
+	})
+	return &fakeEnv{server: server, fx: server.Fixture, ctx: ctx}
+}
+
+// crawl 完整爬取并导入视频评论，失败时终止测试
+func (e *fakeEnv) crawl(t *testing.T, bvid string) {
+	t.Helper()
+	if _, err := crawlAndImport(e.ctx, bvid, false, nil); err != nil {
+		t.Fatalf("crawlAndImport(%s): %v", bvid, err)
+	}
+}
+
+// countRows 执行返回单个计数的查询
+func countRows(t *testing.T, query string, args ...any) int {
+	t.Helper()
+	var n int
+	if err := database.GetDB().QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("查询 %q 失败: %v", query, err)
+	}
+	return n
+}
+
+// commentCount 视频已入库的评论数
+func commentCount(t *testing.T, bvid string) int {
+	t.Helper()
+	return countRows(t, "SELECT COUNT(*) FROM bilibili_comments WHERE bvid = ?", bvid)
+}
+
+// newCrawledEnv 启动离线环境并完整爬取第一个视频（3 页主评论、置顶与多页子评论）
+func newCrawledEnv(t *testing.T) (*fakeEnv, fakebili.Video) {
+	t.Helper()
+	e := newFakeEnv(t)
+	video := e.fx.Videos[0]
+	e.crawl(t, video.Bvid)
+	return e, video
+}
@@ -0,0 +1,364 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"sync"
+
+	"bilibili-comments-viewer-go/crawler/bili_info/util"
+	"bilibili-comments-viewer-go/crawler/blblcd/progress"
+	"bilibili-comments-viewer-go/database"
+	"bilibili-comments-viewer-go/logger"
+)
+
+// JobManager 爬取任务管理器
+// 任务持久化在 crawl_jobs 表中，由固定数量的 worker 依次领取执行，避免突发请求启动大量并行爬虫
+type JobManager struct {
+	workers  int
+	notify   chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	mu        sync.Mutex
+	running   map[int64]context.CancelFunc // 运行中任务的取消函数
+	cancelled map[int64]bool               // 已请求取消的运行中任务
+	resuming  bool                         // 是否已有 goroutine 等待熔断器关闭后恢复暂停的任务
+}
+
+// ErrJobFinished 任务已结束，无法取消
+var ErrJobFinished = errors.New("任务已结束，无法取消")
+
+var (
+	jobManager     *JobManager
+	jobManagerOnce sync.Once
+)
+
+// StartJobManager 启动全局任务管理器，workers 为同时运行的爬取任务上限
+func StartJobManager(workers int) *JobManager {
+	jobManagerOnce.Do(func() {
+		log := logger.GetLogger()
+		if workers <= 0 {
+			workers = 1
+		}
+
+		m := &JobManager{
+			workers:   workers,
+			notify:    make(chan struct{}, 1),
+			stop:      make(chan struct{}),
+			running:   make(map[int64]context.CancelFunc),
+			cancelled: make(map[int64]bool),
+		}
+
+		// 上次退出时仍在运行的任务无法继续，标记为失败
+		if n, err := database.FailInterruptedCrawlJobs(); err != nil {
+			log.Errorf("重置中断任务失败: %v", err)
+		} else if n > 0 {
+			log.Warnf("%d 个爬取任务因服务重启被标记为失败", n)
+		}
+		if _, err := database.InterruptRunningVideoCrawls(); err != nil {
+			log.Errorf("重置中断的爬取记录失败: %v", err)
+		}
+		// 熔断器状态不跨进程保留，上次因风控暂停的任务直接重新排队
+		if n, err := database.RequeuePausedCrawlJobs(); err != nil {
+			log.Errorf("重新排队暂停的任务失败: %v", err)
+		} else if n > 0 {
+			log.Infof("%d 个因风控暂停的爬取任务已重新排队", n)
+		}
+
+		for i := 0; i < workers; i++ {
+			go m.worker(i + 1)
+		}
+		// 唤醒 worker 处理重启前仍在排队的任务
+		m.wake()
+
+		log.Infof("爬取任务管理器已启动 (worker数: %d)", workers)
+		jobManager = m
+	})
+	return jobManager
+}
+
+// GetJobManager 获取全局任务管理器
+func GetJobManager() *JobManager {
+	return jobManager
+}
+
+// Stop 停止领取新任务，正在运行的任务不受影响
+func (m *JobManager) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.stop)
+	})
+}
+
+// SubmitVideo 提交视频评论爬取任务，incremental 为 true 时只爬取新增评论
+func (m *JobManager) SubmitVideo(bvid string, incremental bool) (*database.CrawlJob, error) {
+	return m.submit(database.CrawlJobTypeVideo, bvid, false, incremental)
+}
+
+// SubmitUp 提交UP主视频爬取任务
+func (m *JobManager) SubmitUp(mid int64, fetchAll bool) (*database.CrawlJob, error) {
+	return m.submit(database.CrawlJobTypeUp, strconv.FormatInt(mid, 10), fetchAll, false)
+}
+
+func (m *JobManager) submit(jobType, target string, fetchAll, incremental bool) (*database.CrawlJob, error) {
+	job, err := database.CreateCrawlJob(jobType, target, fetchAll, incremental)
+	if err != nil {
+		return nil, err
+	}
+	logger.GetLogger().Infof("爬取任务已入队: id=%d, type=%s, target=%s", job.ID, jobType, target)
+	m.wake()
+	return job, nil
+}
+
+// Cancel 取消任务：排队中或暂停的任务直接标记为已取消，运行中的任务中断爬取并保存已获取的评论
+// 任务不存在时返回 nil，已结束时返回 ErrJobFinished
+func (m *JobManager) Cancel(id int64) (*database.CrawlJob, error) {
+	ok, err := database.CancelQueuedCrawlJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		logger.GetLogger().Infof("排队中或暂停的爬取任务已取消: id=%d", id)
+		reporter := progress.NewReporter(progress.Default, id)
+		reporter.Emit(progress.Event{Type: progress.EventJobStatus, Status: database.CrawlJobCancelled})
+		progress.Default.Close(id)
+		return database.GetCrawlJob(id)
+	}
+
+	job, err := database.GetCrawlJob(id)
+	if err != nil || job == nil {
+		return nil, err
+	}
+	if job.Status != database.CrawlJobRunning {
+		return job, ErrJobFinished
+	}
+
+	// 任务可能刚被领取、尚未登记取消函数，先记下取消请求，由 run 登记时检查
+	m.mu.Lock()
+	m.cancelled[id] = true
+	if cancel, ok := m.running[id]; ok {
+		cancel()
+	}
+	m.mu.Unlock()
+	logger.GetLogger().Infof("已请求取消运行中的爬取任务: id=%d", id)
+	return job, nil
+}
+
+// CancelTarget 取消指定目标所有排队中或运行中的任务，返回被取消的任务
+func (m *JobManager) CancelTarget(jobType, target string) ([]database.CrawlJob, error) {
+	active, err := database.GetActiveCrawlJobs(jobType, target)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []database.CrawlJob{}
+	for _, j := range active {
+		job, err := m.Cancel(j.ID)
+		if errors.Is(err, ErrJobFinished) || (err == nil && job == nil) {
+			// 查询后任务已自行结束
+			continue
+		}
+		if err != nil {
+			return jobs, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, nil
+}
+
+// register 登记运行中任务的取消函数，若此前已请求取消则立即取消
+func (m *JobManager) register(id int64, cancel context.CancelFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.running[id] = cancel
+	if m.cancelled[id] {
+		cancel()
+	}
+}
+
+// unregister 移除任务登记
+func (m *JobManager) unregister(id int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.running, id)
+	delete(m.cancelled, id)
+}
+
+// isCancelled 任务是否被请求取消
+func (m *JobManager) isCancelled(id int64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cancelled[id]
+}
+
+// wake 非阻塞地通知空闲 worker 有新任务
+func (m *JobManager) wake() {
+	select {
+	case m.notify <- struct{}{}:
+	default:
+	}
+}
+
+// worker 循环领取排队中的任务，队列为空时等待通知
+func (m *JobManager) worker(id int) {
+	log := logger.GetLogger()
+	for {
+		select {
+		case <-m.stop:
+			return
+		default:
+		}
+
+		job, err := database.ClaimNextCrawlJob()
+		if err != nil {
+			log.Errorf("worker %d 领取任务失败: %v", id, err)
+		}
+		if job != nil {
+			// 队列中可能还有任务，继续唤醒其他空闲 worker
+			m.wake()
+			m.run(id, job)
+			continue
+		}
+
+		select {
+		case <-m.stop:
+			return
+		case <-m.notify:
+		}
+	}
+}
+
+// run 执行单个任务并记录最终状态
+func (m *JobManager) run(workerID int, job *database.CrawlJob) {
+	log := logger.GetLogger()
+	log.Infof("worker %d 开始执行任务: id=%d, type=%s, target=%s", workerID, job.ID, job.Type, job.Target)
+
+	status := database.CrawlJobSucceeded
+	commentCount := 0
+	errMsg := ""
+
+	reporter := progress.NewReporter(progress.Default, job.ID)
+	reporter.Emit(progress.Event{Type: progress.EventJobStatus, Status: database.CrawlJobRunning})
+
+	// 风控事件记录到任务上，事件流关闭时结束
+	events, _, unsubscribe := progress.Default.Subscribe(job.ID)
+	defer unsubscribe()
+	go recordRiskControl(job.ID, events)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.register(job.ID, cancel)
+
+	defer func() {
+		cancel()
+		m.unregister(job.ID)
+		if r := recover(); r != nil {
+			log.Errorf("任务 %d PANIC: %v\n%s", job.ID, r, string(debug.Stack()))
+			status = database.CrawlJobFailed
+			errMsg = fmt.Sprintf("panic: %v", r)
+		}
+		if err := database.FinishCrawlJob(job.ID, status, commentCount, errMsg); err != nil {
+			log.Errorf("记录任务 %d 状态失败: %v", job.ID, err)
+		}
+		// 推送最终状态后关闭事件流
+		reporter.Emit(progress.Event{Type: progress.EventJobStatus, Status: status, Downloaded: commentCount, Message: errMsg})
+		progress.Default.Close(job.ID)
+		log.Infof("任务 %d 结束: status=%s, comments=%d", job.ID, status, commentCount)
+		// 事件流关闭后再恢复，重新排队的任务从新的事件流推送进度
+		if status == database.CrawlJobPaused {
+			m.resumeAfterBreaker()
+		}
+	}()
+
+	ctx, finishTraffic, err := withTraffic(ctx, job.ID)
+	if err == nil {
+		defer finishTraffic()
+		switch job.Type {
+		case database.CrawlJobTypeVideo:
+			commentCount, err = crawlAndImport(ctx, job.Target, job.Incremental, reporter)
+		case database.CrawlJobTypeUp:
+			var mid int64
+			mid, err = strconv.ParseInt(job.Target, 10, 64)
+			if err == nil {
+				err = crawlUpVideos(ctx, mid, job.FetchAll, reporter)
+			}
+		default:
+			err = fmt.Errorf("未知任务类型: %s", job.Type)
+		}
+	}
+
+	status, errMsg = jobOutcome(err, m.isCancelled(job.ID))
+}
+
+// jobOutcome 根据执行结果确定任务的最终状态与错误信息，cancelled 为任务是否被请求取消
+func jobOutcome(err error, cancelled bool) (string, string) {
+	switch {
+	case err == nil:
+		return database.CrawlJobSucceeded, ""
+	case errors.Is(err, context.Canceled) && cancelled:
+		// 已获取的评论已保存，断点文件保留以便重新提交后继续
+		return database.CrawlJobCancelled, "任务已取消"
+	}
+	if _, ok := util.AsRiskControl(err); ok {
+		// 多次触发风控：保留断点暂停任务，熔断器关闭后自动重新排队
+		return database.CrawlJobPaused, err.Error() + "（已保存断点，风控冷却结束后自动继续）"
+	}
+	return database.CrawlJobFailed, err.Error()
+}
+
+// resumeAfterBreaker 等待熔断器关闭后将暂停的任务重新排队，同一时间只有一个 goroutine 等待
+// 管理器停止时放弃等待，暂停的任务在下次启动时重新排队
+func (m *JobManager) resumeAfterBreaker() {
+	m.mu.Lock()
+	if m.resuming {
+		m.mu.Unlock()
+		return
+	}
+	m.resuming = true
+	m.mu.Unlock()
+
+	go func() {
+		log := logger.GetLogger()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-m.stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		err := util.WaitBreaker(ctx)
+		// 先清除标记再重新排队：之后暂停的任务要么被本次一并排队，要么启动新的等待
+		m.mu.Lock()
+		m.resuming = false
+		m.mu.Unlock()
+		if err != nil {
+			return
+		}
+
+		n, err := database.RequeuePausedCrawlJobs()
+		if err != nil {
+			log.Errorf("重新排队暂停的任务失败: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Infof("熔断器已关闭，%d 个因风控暂停的爬取任务重新排队", n)
+			m.wake()
+		}
+	}()
+}
+
+// recordRiskControl 将任务的风控事件记录到数据库
+func recordRiskControl(jobID int64, events <-chan progress.Event) {
+	for e := range events {
+		if e.Type != progress.EventRiskControl {
+			continue
+		}
+		if err := database.RecordCrawlJobRiskControl(jobID, e.Message); err != nil {
+			logger.GetLogger().Errorf("记录任务 %d 风控原因失败: %v", jobID, err)
+		}
+	}
+}
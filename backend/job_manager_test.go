@@ -0,0 +1,141 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"bilibili-comments-viewer-go/crawler/bili_info/util"
+	"bilibili-comments-viewer-go/database"
+)
+
+// newTestJobManager 不启动 worker 的任务管理器，任务停留在队列中，由测试直接领取
+func newTestJobManager() *JobManager {
+	return &JobManager{
+		notify:    make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		running:   make(map[int64]context.CancelFunc),
+		cancelled: make(map[int64]bool),
+	}
+}
+
+func TestJobOutcome(t *testing.T) {
+	rc := &util.RiskControlError{Endpoint: util.EndpointReplyMain, Code: util.CodeRiskControl, Message: "风控校验失败"}
+	for _, tc := range []struct {
+		name      string
+		err       error
+		cancelled bool
+		status    string
+		msg       string
+	}{
+		{"成功", nil, false, database.CrawlJobSucceeded, ""},
+		{"用户取消", fmt.Errorf("爬取中断: %w", context.Canceled), true, database.CrawlJobCancelled, "任务已取消"},
+		{"未请求取消的中断", context.Canceled, false, database.CrawlJobFailed, context.Canceled.Error()},
+		{"多次触发风控", fmt.Errorf("第3页连续 6 次触发风控: %w", rc), false, database.CrawlJobPaused, "触发B站风控"},
+		{"请求失败", errors.New("第3页多次请求失败"), false, database.CrawlJobFailed, "第3页多次请求失败"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			status, msg := jobOutcome(tc.err, tc.cancelled)
+			if status != tc.status || !strings.Contains(msg, tc.msg) {
+				t.Errorf("jobOutcome = %s, %q, 期望 %s 且包含 %q", status, msg, tc.status, tc.msg)
+			}
+		})
+	}
+}
+
+// claimJob 提交并领取一个视频任务，返回运行中的任务
+func claimJob(t *testing.T, m *JobManager, bvid string) *database.CrawlJob {
+	t.Helper()
+	if _, err := m.SubmitVideo(bvid, false); err != nil {
+		t.Fatal(err)
+	}
+	job, err := database.ClaimNextCrawlJob()
+	if err != nil || job == nil {
+		t.Fatalf("领取任务失败: %v, %v", job, err)
+	}
+	return job
+}
+
+func jobStatus(t *testing.T, id int64) *database.CrawlJob {
+	t.Helper()
+	job, err := database.GetCrawlJob(id)
+	if err != nil || job == nil {
+		t.Fatalf("查询任务 %d 失败: %v", id, err)
+	}
+	return job
+}
+
+func TestPausedJobRequeuedAfterBreaker(t *testing.T) {
+	newFakeEnv(t)
+	m := newTestJobManager()
+	const bvid = "BV1xx411c7mD"
+
+	paused := claimJob(t, m, bvid)
+	if err := database.FinishCrawlJob(paused.ID, database.CrawlJobPaused, 10, "触发B站风控（已保存断点，风控冷却结束后自动继续）"); err != nil {
+		t.Fatal(err)
+	}
+	failed := claimJob(t, m, "BV1Q541167Qg")
+	if err := database.FinishCrawlJob(failed.ID, database.CrawlJobFailed, 0, "第1页多次请求失败"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 暂停的任务仍是活动任务，定时计划与重复提交不会再为该视频创建任务
+	active, err := database.GetActiveCrawlJobs(database.CrawlJobTypeVideo, bvid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 1 || active[0].ID != paused.ID {
+		t.Fatalf("活动任务 %+v, 期望只有暂停的任务 %d", active, paused.ID)
+	}
+
+	// 熔断器已关闭，立即重新排队并唤醒 worker（先取走提交任务时的唤醒通知）
+	<-m.notify
+	m.resumeAfterBreaker()
+	select {
+	case <-m.notify:
+	case <-time.After(5 * time.Second):
+		t.Fatal("重新排队后未唤醒 worker")
+	}
+	job := jobStatus(t, paused.ID)
+	if job.Status != database.CrawlJobQueued || job.Error != "" || job.FinishedAt != nil {
+		t.Errorf("恢复后任务状态 %s, error = %q, finished_at = %v, 期望重新排队", job.Status, job.Error, job.FinishedAt)
+	}
+	if job := jobStatus(t, failed.ID); job.Status != database.CrawlJobFailed {
+		t.Errorf("失败的任务状态变为 %s", job.Status)
+	}
+	m.mu.Lock()
+	resuming := m.resuming
+	m.mu.Unlock()
+	if resuming {
+		t.Error("恢复结束后 resuming 未清除")
+	}
+
+	// 重新排队的任务可被 worker 再次领取
+	if next, err := database.ClaimNextCrawlJob(); err != nil || next == nil || next.ID != paused.ID {
+		t.Fatalf("重新领取到 %+v, %v, 期望任务 %d", next, err, paused.ID)
+	}
+}
+
+func TestCancelPausedJob(t *testing.T) {
+	newFakeEnv(t)
+	m := newTestJobManager()
+
+	job := claimJob(t, m, "BV1xx411c7mD")
+	if err := database.FinishCrawlJob(job.ID, database.CrawlJobPaused, 0, "触发B站风控"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.Cancel(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != database.CrawlJobCancelled {
+		t.Fatalf("取消后状态 %s, 期望 %s", got.Status, database.CrawlJobCancelled)
+	}
+	// 已取消的任务不会被熔断器关闭后的恢复重新排队
+	if n, err := database.RequeuePausedCrawlJobs(); err != nil || n != 0 {
+		t.Errorf("RequeuePausedCrawlJobs = %d, %v, 期望 0", n, err)
+	}
+}
@@ -3,8 +3,6 @@ package backend
 import (
 	"context"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 
@@ -15,7 +13,7 @@ import (
 	"bilibili-comments-viewer-go/logger"
 )
 
-func FetchVideoMetadata(bvid string) (*model.VideoInfo, error) {
+func FetchVideoMetadata(ctx context.Context, bvid string) (*model.VideoInfo, error) {
 	cfg := config.Get()
 
 	// 加载cookies
@@ -28,9 +26,9 @@ func FetchVideoMetadata(bvid string) (*model.VideoInfo, error) {
 	apiClient := fetch.NewAPIClient(cookies)
 
 	// 获取视频信息
-	info, err := apiClient.GetVideoInfo(context.Background(), bvid)
+	info, err := apiClient.GetVideoInfo(ctx, bvid)
 	if err != nil {
-		return nil, fmt.Errorf("获取视频信息失败: %v", err)
+		return nil, fmt.Errorf("获取视频信息失败: %w", err)
 	}
 
 	// 下载封面
@@ -41,26 +39,27 @@ func FetchVideoMetadata(bvid string) (*model.VideoInfo, error) {
 
 		if err := os.MkdirAll(coverDir, 0755); err != nil {
 			logger.GetLogger().Errorf("创建封面目录失败: %v", err)
+		} else if err := downloadCover(ctx, apiClient, info.Cover, localPath); err != nil {
+			logger.GetLogger().Errorf("封面下载失败: %v", err)
 		} else {
-			resp, err := http.Get(info.Cover)
-			if err != nil {
-				logger.GetLogger().Errorf("封面下载失败: %v", err)
-			} else {
-				defer resp.Body.Close()
-
-				file, err := os.Create(localPath)
-				if err != nil {
-					logger.GetLogger().Errorf("创建封面文件失败: %v", err)
-				} else {
-					defer file.Close()
-					if _, err = io.Copy(file, resp.Body); err != nil {
-						logger.GetLogger().Errorf("保存封面失败: %v", err)
-					} else {
-						info.LocalCover = filepath.Join("cover", fileName)
-					}
-				}
-			}
+			info.LocalCover = filepath.Join("cover", fileName)
 		}
 	}
 	return info, nil
 }
+
+// downloadCover 通过 B 站客户端下载封面，失败时删除不完整的文件
+func downloadCover(ctx context.Context, apiClient *fetch.APIClient, coverURL, localPath string) error {
+	file, err := os.Create(localPath)
+	if err != nil {
+		return fmt.Errorf("创建封面文件失败: %w", err)
+	}
+	err = apiClient.DownloadCover(ctx, coverURL, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(localPath)
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+package backend
+
+import (
+	"strconv"
+
+	"bilibili-comments-viewer-go/database"
+)
+
+// 永久链接返回的相邻评论数
+const (
+	DefaultPermalinkContext = 2
+	MaxPermalinkContext     = 10
+)
+
+// Permalink 单条评论及其在视频评论列表中的上下文
+type Permalink struct {
+	Comment   *database.Comment  `json:"comment"`
+	Parent    *database.Comment  `json:"parent,omitempty"` // 回复的父评论
+	Root      *database.Comment  `json:"root,omitempty"`   // 回复所属的主评论
+	Video     *database.Video    `json:"video,omitempty"`
+	Sort      string             `json:"sort"`
+	Order     string             `json:"order"`
+	Position  int                `json:"position"`   // 在所属列表（主评论列表或父评论的回复列表）中的位置，从 1 开始
+	Total     int                `json:"total"`      // 所属列表的评论数
+	Page      int                `json:"page"`       // 在所属列表中的页码
+	VideoPage int                `json:"video_page"` // 评论（回复时为所属主评论）在视频评论列表中的页码
+	PageSize  int                `json:"pageSize"`
+	Before    []database.Comment `json:"before"` // 列表中紧挨在前面的评论
+	After     []database.Comment `json:"after"`  // 列表中紧挨在后面的评论
+}
+
+// ResolveComment 按 unique_id 或 rpid 查找评论
+func ResolveComment(id string) (*database.Comment, error) {
+	c, err := database.GetCommentByID(id)
+	if err != nil || c != nil {
+		return c, err
+	}
+	if rpid, err := strconv.ParseInt(id, 10, 64); err == nil {
+		if c, err = database.GetCommentByRpid(rpid); err != nil || c != nil {
+			return c, err
+		}
+	}
+	return nil, ErrCommentNotFound
+}
+
+// BuildPermalink 获取评论、父评论与主评论，以及它在 cq 的排序下的位置和前后各 contextSize 条相邻评论
+// cq 只使用排序方式与每页条数，不应用筛选条件；回复的位置按回复列表的点赞数降序计算
+func BuildPermalink(id string, cq *CommentQuery, contextSize int) (*Permalink, error) {
+	c, err := ResolveComment(id)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Permalink{Comment: c, Sort: cq.Filter.Sort, Order: "desc", PageSize: cq.PageSize}
+	if cq.Filter.Asc {
+		p.Order = "asc"
+	}
+	if p.Video, err = database.GetVideoByBVid(c.BVid); err != nil {
+		return nil, err
+	}
+	if c.Parent != "0" {
+		if p.Parent, err = database.GetCommentByID(c.Parent); err != nil {
+			return nil, err
+		}
+		if p.Root, err = threadRoot(c); err != nil {
+			return nil, err
+		}
+	}
+
+	pos, err := database.GetCommentPosition(c, cq.Filter.Sort, cq.Filter.Asc, contextSize)
+	if err != nil {
+		return nil, err
+	}
+	p.Position, p.Total, p.Before, p.After = pos.Index, pos.Total, pos.Before, pos.After
+	p.Page = (pos.Index-1)/cq.PageSize + 1
+	p.VideoPage = p.Page
+	if c.Parent != "0" {
+		p.VideoPage = 0
+		if p.Root != nil {
+			rootPos, err := database.GetCommentPosition(p.Root, cq.Filter.Sort, cq.Filter.Asc, 0)
+			if err != nil {
+				return nil, err
+			}
+			p.VideoPage = (rootPos.Index-1)/cq.PageSize + 1
+		}
+	}
+	return p, nil
+}
+
+// threadRoot 回复所属的主评论：优先按 root 列查找，旧数据沿 parent 向上查找，找不到时返回 nil
+func threadRoot(c *database.Comment) (*database.Comment, error) {
+	if c.Root != 0 {
+		root, err := database.GetCommentByID(c.BVid + "_" + strconv.FormatInt(c.Root, 10))
+		if err != nil || root != nil {
+			return root, err
+		}
+	}
+	cur := c
+	for steps := 0; cur.Parent != "0" && steps < maxAncestors; steps++ {
+		parent, err := database.GetCommentByID(cur.Parent)
+		if err != nil || parent == nil {
+			return nil, err
+		}
+		cur = parent
+	}
+	if cur.Parent != "0" {
+		return nil, nil
+	}
+	return cur, nil
+}
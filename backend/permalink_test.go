@@ -0,0 +1,55 @@
+package backend
+
+import (
+	"net/url"
+	"testing"
+)
+
+// 位置与相邻评论和列表顺序一致
+func TestBuildPermalink(t *testing.T) {
+	_, video := newCrawledEnv(t)
+
+	cq, err := ParseCommentQuery(url.Values{"sort": {"time"}, "pageSize": {"100"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := cq.Run(video.Bvid)
+	if err != nil || len(all.Comments) < 5 {
+		t.Fatalf("查询主评论: %d 条 err=%v", len(all.Comments), err)
+	}
+	i := len(all.Comments) / 2
+	cq.PageSize = 7
+	p, err := BuildPermalink(all.Comments[i].UniqueID, cq, 2)
+	if err != nil {
+		t.Fatalf("BuildPermalink: %v", err)
+	}
+	if p.Position != i+1 || p.Page != i/7+1 || p.VideoPage != p.Page || p.Total != all.Total {
+		t.Errorf("第 %d 条主评论位于第 %d 条、第 %d 页（视频第 %d 页），共 %d 条", i+1, p.Position, p.Page, p.VideoPage, p.Total)
+	}
+	if len(p.Before) != 2 || len(p.After) != 2 ||
+		p.Before[0].UniqueID != all.Comments[i-2].UniqueID || p.Before[1].UniqueID != all.Comments[i-1].UniqueID ||
+		p.After[0].UniqueID != all.Comments[i+1].UniqueID || p.After[1].UniqueID != all.Comments[i+2].UniqueID {
+		t.Errorf("第 %d 条主评论的相邻评论与列表不一致", i+1)
+	}
+}
+
+// 按 rpid 查找回复时返回父评论与主评论
+func TestBuildPermalinkReply(t *testing.T) {
+	_, video := newCrawledEnv(t)
+
+	cq, err := ParseCommentQuery(url.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := BuildPermalink("1000003", cq, 2)
+	if err != nil {
+		t.Fatalf("BuildPermalink: %v", err)
+	}
+	if p.Comment.UniqueID != video.Bvid+"_1000003" || p.Parent == nil || p.Parent.Rpid != 1000002 ||
+		p.Root == nil || p.Root.Rpid != 1000001 || p.Video == nil {
+		t.Errorf("回复 1000003 的永久链接 comment=%s parent=%v root=%v", p.Comment.UniqueID, p.Parent, p.Root)
+	}
+	if p.VideoPage < 1 || p.Position < 1 || p.Position > p.Total {
+		t.Errorf("回复所在楼层位于第 %d 条（共 %d 条）、视频第 %d 页", p.Position, p.Total, p.VideoPage)
+	}
+}
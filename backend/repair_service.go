@@ -166,8 +166,9 @@ func (rs *RepairService) ValidateVideoData(bvid string) (*VideoValidationResult,
 // 拆分：校验单个视频的评论统计
 func (rs *RepairService) validateVideoStats(bvid string) ([]Issue, error) {
 	var issues []Issue
+	// comment_stats 记录的是主评论数（与评论列表分页一致），见 database.UpdateCommentStats
 	var commentCount int
-	err := rs.db.QueryRow("SELECT COUNT(*) FROM bilibili_comments WHERE bvid = ?", bvid).Scan(&commentCount)
+	err := rs.db.QueryRow("SELECT COUNT(*) FROM bilibili_comments WHERE bvid = ? AND parent = '0'", bvid).Scan(&commentCount)
 	if err != nil {
 		return nil, fmt.Errorf("查询评论数失败: %w", err)
 	}
@@ -757,6 +758,7 @@ func (rs *RepairService) validateInconsistentStats() ([]Issue, error) {
 		LEFT JOIN (
 			SELECT bvid, COUNT(*) as actual_count 
 			FROM bilibili_comments 
+			WHERE parent = '0'
 			GROUP BY bvid
 		) c ON s.bvid = c.bvid
 		WHERE s.comment_count != c.actual_count OR c.actual_count IS NULL`).Scan(&inconsistentCount)
@@ -769,6 +771,7 @@ func (rs *RepairService) validateInconsistentStats() ([]Issue, error) {
 			LEFT JOIN (
 				SELECT bvid, COUNT(*) as actual_count 
 				FROM bilibili_comments 
+				WHERE parent = '0'
 				GROUP BY bvid
 			) c ON s.bvid = c.bvid
 			WHERE s.comment_count != c.actual_count OR c.actual_count IS NULL LIMIT 10`)
@@ -909,7 +912,12 @@ func (rs *RepairService) validateVideoComments(bvid string) ([]Issue, error) {
 		})
 	}
 
-	// 检查该视频的评论统计
+	// 检查该视频的评论统计（统计的是主评论数）
+	var rootCount int
+	err = rs.db.QueryRow("SELECT COUNT(*) FROM bilibili_comments WHERE bvid = ? AND parent = '0'", bvid).Scan(&rootCount)
+	if err != nil {
+		return nil, err
+	}
 	var statsCount int
 	err = rs.db.QueryRow("SELECT comment_count FROM comment_stats WHERE bvid = ?", bvid).Scan(&statsCount)
 	if err == sql.ErrNoRows {
@@ -928,19 +936,19 @@ func (rs *RepairService) validateVideoComments(bvid string) ([]Issue, error) {
 		})
 	} else if err != nil {
 		return nil, err
-	} else if statsCount != commentCount {
+	} else if statsCount != rootCount {
 		// 统计不一致
 		issues = append(issues, Issue{
 			Type:          ErrorTypeInconsistentStats,
 			Severity:      ErrorLevelMedium,
 			Level:         ErrorLevelMedium,
 			Category:      ErrorTypeDataConsistency,
-			Description:   fmt.Sprintf("评论统计不一致（实际: %d, 统计: %d）", commentCount, statsCount),
+			Description:   fmt.Sprintf("评论统计不一致（实际: %d, 统计: %d）", rootCount, statsCount),
 			Count:         1,
 			Fixable:       true,
 			Fixed:         false,
 			AffectedBVids: []string{bvid},
-			Details:       fmt.Sprintf("视频 %s 评论统计不一致（实际: %d, 统计: %d）", bvid, commentCount, statsCount),
+			Details:       fmt.Sprintf("视频 %s 评论统计不一致（实际: %d, 统计: %d）", bvid, rootCount, statsCount),
 		})
 	}
 
@@ -1110,7 +1118,7 @@ func (rs *RepairService) fixInconsistentStats() error {
 		SET comment_count = (
 			SELECT COUNT(*) 
 			FROM bilibili_comments 
-			WHERE bilibili_comments.bvid = comment_stats.bvid
+			WHERE bilibili_comments.bvid = comment_stats.bvid AND parent = '0'
 		)`)
 	return err
 }
@@ -1124,6 +1132,7 @@ func (rs *RepairService) fixMissingStats() error {
 		LEFT JOIN (
 			SELECT bvid, COUNT(*) as comment_count
 			FROM bilibili_comments
+			WHERE parent = '0'
 			GROUP BY bvid
 		) c ON v.bvid = c.bvid
 		WHERE NOT EXISTS (
@@ -1135,7 +1144,7 @@ func (rs *RepairService) fixMissingStats() error {
 // 修复视频缺失统计
 func (rs *RepairService) fixVideoMissingStats(bvid string) error {
 	var commentCount int
-	err := rs.db.QueryRow("SELECT COUNT(*) FROM bilibili_comments WHERE bvid = ?", bvid).Scan(&commentCount)
+	err := rs.db.QueryRow("SELECT COUNT(*) FROM bilibili_comments WHERE bvid = ? AND parent = '0'", bvid).Scan(&commentCount)
 	if err != nil {
 		return err
 	}
@@ -1149,7 +1158,7 @@ func (rs *RepairService) fixVideoMissingStats(bvid string) error {
 // 修复视频统计不一致
 func (rs *RepairService) fixVideoInconsistentStats(bvid string) error {
 	var commentCount int
-	err := rs.db.QueryRow("SELECT COUNT(*) FROM bilibili_comments WHERE bvid = ?", bvid).Scan(&commentCount)
+	err := rs.db.QueryRow("SELECT COUNT(*) FROM bilibili_comments WHERE bvid = ? AND parent = '0'", bvid).Scan(&commentCount)
 	if err != nil {
 		return err
 	}
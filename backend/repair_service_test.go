@@ -0,0 +1,58 @@
+package backend
+
+import (
+	"testing"
+
+	"bilibili-comments-viewer-go/database"
+)
+
+func TestRepairVideoData(t *testing.T) {
+	_, video := newCrawledEnv(t)
+
+	rs := NewRepairService()
+	result, err := rs.ValidateVideoData(video.Bvid)
+	if err != nil {
+		t.Fatalf("ValidateVideoData: %v", err)
+	}
+	if len(result.Issues) != 0 {
+		t.Errorf("数据校验发现 %d 个问题: %+v", len(result.Issues), result.Issues)
+	}
+	if _, err := rs.RepairVideoData(video.Bvid); err != nil {
+		t.Fatalf("RepairVideoData: %v", err)
+	}
+	if got, want := commentCount(t, video.Bvid), video.CommentCount(); got != want {
+		t.Errorf("修复后评论数 %d，期望 %d", got, want)
+	}
+}
+
+// comment_stats 记录主评论数：含回复的总数判为统计不一致，修复后恢复为主评论数
+func TestRepairVideoStatsCountsRootComments(t *testing.T) {
+	_, video := newCrawledEnv(t)
+
+	roots := countRows(t, "SELECT COUNT(*) FROM bilibili_comments WHERE bvid = ? AND parent = '0'", video.Bvid)
+	stats := func() int {
+		return countRows(t, "SELECT comment_count FROM comment_stats WHERE bvid = ?", video.Bvid)
+	}
+	if got := stats(); got != roots || roots == video.CommentCount() {
+		t.Fatalf("统计 %d 条，主评论 %d 条，全部评论 %d 条", got, roots, video.CommentCount())
+	}
+	if _, err := database.GetDB().Exec("UPDATE comment_stats SET comment_count = ? WHERE bvid = ?", video.CommentCount(), video.Bvid); err != nil {
+		t.Fatal(err)
+	}
+
+	rs := NewRepairService()
+	result, err := rs.RepairVideoData(video.Bvid)
+	if err != nil {
+		t.Fatalf("RepairVideoData: %v", err)
+	}
+	found := false
+	for _, issue := range result.Issues {
+		found = found || issue.Type == ErrorTypeInconsistentStats
+	}
+	if !found {
+		t.Errorf("统计为全部评论数时未报告统计不一致: %+v", result.Issues)
+	}
+	if got := stats(); got != roots {
+		t.Errorf("修复后统计 %d 条，期望主评论数 %d", got, roots)
+	}
+}
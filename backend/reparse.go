@@ -0,0 +1,109 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"bilibili-comments-viewer-go/config"
+	"bilibili-comments-viewer-go/crawler/blblcd/core"
+	blblcdmodel "bilibili-comments-viewer-go/crawler/blblcd/model"
+	"bilibili-comments-viewer-go/database"
+	"bilibili-comments-viewer-go/logger"
+)
+
+// ErrNoRawPages 视频没有存档的原始响应，无法重新解析
+var ErrNoRawPages = errors.New("没有存档的原始响应")
+
+// dbRawPageStore 将评论接口的原始响应保存到数据库 raw_pages 表
+type dbRawPageStore struct{}
+
+func (dbRawPageStore) SaveRawPage(page *blblcdmodel.RawPage) error {
+	return database.SaveRawPage(&database.RawPage{
+		Bvid:   page.Bvid,
+		Oid:    page.Oid,
+		Kind:   page.Kind,
+		Root:   page.Root,
+		Page:   page.Page,
+		Offset: page.Offset,
+		Body:   page.Body,
+	})
+}
+
+// rawPageStore 按配置返回原始响应存储，未开启存档时返回 nil
+func rawPageStore() blblcdmodel.RawPageStore {
+	if !config.Get().Crawler.ArchiveRawPages {
+		return nil
+	}
+	return dbRawPageStore{}
+}
+
+// ReparseResult 重新解析的结果
+type ReparseResult struct {
+	Bvid     string `json:"bvid"`
+	Pages    int    `json:"pages"`    // 解析的原始响应页数
+	Comments int    `json:"comments"` // 写入的评论数
+	Skipped  int    `json:"skipped"`  // 无法解析的页数
+}
+
+// ReparseVideo 从存档的原始响应重建视频的评论：按获取顺序解析每一页，同一评论以最后获取的为准，
+// 写入 bilibili_comments 后重建回复关系与统计。存档中没有的评论（如 CSV 导入的）保持不变；
+// 观察时间取原始响应的获取时间，不会清除之后的完整爬取标记的删除
+func ReparseVideo(bvid string) (*ReparseResult, error) {
+	log := logger.GetLogger()
+	pages, err := database.GetRawPages(bvid)
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return nil, ErrNoRawPages
+	}
+
+	result := &ReparseResult{Bvid: bvid, Pages: len(pages)}
+	latest := make(map[int64]blblcdmodel.Comment)
+	fetchedAt := make(map[int64]time.Time) // 评论的观察时间为其所在页的获取时间
+	var order []int64
+	for _, page := range pages {
+		comments, err := core.ParsePage(page.Body)
+		if err != nil {
+			log.Warnf("跳过无法解析的原始响应 (id=%d): %v", page.ID, err)
+			result.Skipped++
+			continue
+		}
+		for _, c := range comments {
+			if _, ok := latest[c.Rpid]; !ok {
+				order = append(order, c.Rpid)
+			}
+			if c.Bvid == "" {
+				c.Bvid = bvid
+			}
+			latest[c.Rpid] = c
+			fetchedAt[c.Rpid] = page.FetchedAt
+		}
+	}
+
+	dbComments := make([]*database.Comment, 0, len(order))
+	for _, rpid := range order {
+		c := latest[rpid]
+		if dbc := convertToDBComment(&c); dbc != nil {
+			dbc.LastSeen = fetchedAt[rpid]
+			dbComments = append(dbComments, dbc)
+		}
+	}
+	if len(dbComments) > 0 {
+		if err := database.BatchSaveComments(dbComments); err != nil {
+			return nil, fmt.Errorf("保存重新解析的评论失败: %w", err)
+		}
+	}
+	result.Comments = len(dbComments)
+
+	if err := database.RebuildAllCommentRelations(bvid); err != nil {
+		return nil, fmt.Errorf("重建评论关系失败: %w", err)
+	}
+	if err := database.UpdateCommentStats(bvid); err != nil {
+		return nil, fmt.Errorf("更新评论统计失败: %w", err)
+	}
+
+	log.Infof("视频 %s 重新解析完成: %d 页原始响应，%d 条评论，跳过 %d 页", bvid, result.Pages, result.Comments, result.Skipped)
+	return result, nil
+}
@@ -0,0 +1,26 @@
+package backend
+
+import (
+	"testing"
+
+	"bilibili-comments-viewer-go/database"
+)
+
+// 清空评论后从保存的原始响应重建
+func TestReparseVideo(t *testing.T) {
+	_, video := newCrawledEnv(t)
+
+	if _, err := database.GetDB().Exec("DELETE FROM bilibili_comments WHERE bvid = ?", video.Bvid); err != nil {
+		t.Fatal(err)
+	}
+	result, err := ReparseVideo(video.Bvid)
+	if err != nil {
+		t.Fatalf("ReparseVideo: %v", err)
+	}
+	if result.Skipped != 0 {
+		t.Errorf("重新解析了 %d 页原始响应，跳过 %d 页", result.Pages, result.Skipped)
+	}
+	if got, want := commentCount(t, video.Bvid), video.CommentCount(); got != want {
+		t.Errorf("重新解析后评论数 %d，期望 %d", got, want)
+	}
+}
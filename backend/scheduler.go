@@ -0,0 +1,360 @@
+package backend
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"bilibili-comments-viewer-go/config"
+	"bilibili-comments-viewer-go/crawler/bili_info/util"
+	"bilibili-comments-viewer-go/database"
+	"bilibili-comments-viewer-go/logger"
+)
+
+// schedulerInterval 检查到期计划的间隔
+const schedulerInterval = 30 * time.Second
+
+// Scheduler 定时爬取调度器
+// 按 cron 表达式将到期的计划提交到任务队列，静默时段内或当日接口请求预算用尽时推迟执行
+type Scheduler struct {
+	jobs       *JobManager
+	quietStart int // 静默时段开始（当天分钟数），-1 表示不限制
+	quietEnd   int
+	budget     int64
+
+	lastCount    atomic.Int64 // 上次记录时的进程请求计数
+	budgetNotice string       // 已提示预算用尽的日期，避免重复日志
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+var scheduler *Scheduler
+
+// StartScheduler 启动定时爬取调度器
+func StartScheduler(jobs *JobManager) *Scheduler {
+	log := logger.GetLogger()
+	cfg := config.Get()
+
+	s := &Scheduler{
+		jobs:       jobs,
+		quietStart: -1,
+		budget:     int64(cfg.Scheduler.DailyRequestBudget),
+		stop:       make(chan struct{}),
+	}
+	s.lastCount.Store(util.RequestCount())
+	if start, end, err := parseQuietHours(cfg.Scheduler.QuietHours); err != nil {
+		log.Errorf("静默时段配置无效，已忽略: %v", err)
+	} else {
+		s.quietStart, s.quietEnd = start, end
+	}
+
+	go s.loop()
+	scheduler = s
+	log.Infof("定时爬取调度器已启动 (静默时段: %q, 每日请求预算: %d)", cfg.Scheduler.QuietHours, s.budget)
+	return s
+}
+
+// Stop 停止调度器
+func (s *Scheduler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		s.recordUsage(time.Now())
+	})
+}
+
+func (s *Scheduler) loop() {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+
+	s.tick(time.Now())
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			s.tick(now)
+		}
+	}
+}
+
+// tick 记录请求用量并提交到期计划
+func (s *Scheduler) tick(now time.Time) {
+	log := logger.GetLogger()
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("定时爬取调度 PANIC: %v\n%s", r, string(debug.Stack()))
+		}
+	}()
+
+	s.recordUsage(now)
+
+	if s.inQuietHours(now) {
+		log.Debugf("当前处于静默时段，推迟定时爬取")
+		return
+	}
+
+	if s.budget > 0 {
+		day := now.Format("2006-01-02")
+		used, err := database.GetRequestUsage(day)
+		if err != nil {
+			log.Errorf("查询接口请求用量失败: %v", err)
+			return
+		}
+		if used >= s.budget {
+			if s.budgetNotice != day {
+				log.Warnf("今日接口请求已达预算 (%d/%d)，定时爬取推迟到次日", used, s.budget)
+				s.budgetNotice = day
+			}
+			return
+		}
+	}
+
+	due, err := database.GetDueSchedules(now)
+	if err != nil {
+		log.Errorf("查询到期计划失败: %v", err)
+		return
+	}
+	for i := range due {
+		s.run(&due[i], now)
+	}
+}
+
+// run 提交单个到期计划并计算下次执行时间
+func (s *Scheduler) run(sch *database.Schedule, now time.Time) {
+	log := logger.GetLogger()
+
+	var next *time.Time
+	if cron, err := parseCron(sch.Cron); err != nil {
+		log.Errorf("计划 %d 的 cron 表达式无效，停止调度: %v", sch.ID, err)
+	} else if t := cron.Next(now); !t.IsZero() {
+		next = &t
+	}
+
+	var jobID int64
+	active, err := database.GetActiveCrawlJobs(sch.Type, sch.Target)
+	switch {
+	case err != nil:
+		log.Errorf("查询计划 %d 的活动任务失败: %v", sch.ID, err)
+	case len(active) > 0:
+		log.Infof("计划 %d 的目标 %s 已有任务在排队或运行 (id=%d)，跳过本次执行", sch.ID, sch.Target, active[0].ID)
+	default:
+		job, err := s.submit(sch)
+		if err != nil {
+			log.Errorf("计划 %d 提交爬取任务失败: %v", sch.ID, err)
+		} else {
+			jobID = job.ID
+			log.Infof("计划 %d 已提交爬取任务: id=%d, type=%s, target=%s", sch.ID, job.ID, sch.Type, sch.Target)
+		}
+	}
+
+	if err := database.MarkScheduleRun(sch.ID, now, next, jobID); err != nil {
+		log.Errorf("更新计划 %d 执行时间失败: %v", sch.ID, err)
+	}
+}
+
+func (s *Scheduler) submit(sch *database.Schedule) (*database.CrawlJob, error) {
+	if sch.Type == database.CrawlJobTypeUp {
+		mid, err := strconv.ParseInt(sch.Target, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的UP主mid: %s", sch.Target)
+		}
+		return s.jobs.SubmitUp(mid, sch.FetchAll)
+	}
+	return s.jobs.SubmitVideo(sch.Target, sch.Incremental)
+}
+
+// recordUsage 将上次记录以来的接口请求数累加到当日用量
+func (s *Scheduler) recordUsage(now time.Time) {
+	count := util.RequestCount()
+	delta := count - s.lastCount.Swap(count)
+	if delta <= 0 {
+		return
+	}
+	if err := database.AddRequestUsage(now.Format("2006-01-02"), delta); err != nil {
+		logger.GetLogger().Errorf("记录接口请求用量失败: %v", err)
+		// 下次再记录
+		s.lastCount.Add(-delta)
+	}
+}
+
+// inQuietHours 判断 now 是否处于静默时段，支持跨越午夜的时段
+func (s *Scheduler) inQuietHours(now time.Time) bool {
+	if s.quietStart < 0 {
+		return false
+	}
+	m := now.Hour()*60 + now.Minute()
+	if s.quietStart < s.quietEnd {
+		return m >= s.quietStart && m < s.quietEnd
+	}
+	return m >= s.quietStart || m < s.quietEnd
+}
+
+// parseQuietHours 解析 "HH:MM-HH:MM" 格式的静默时段，为空时返回 -1
+func parseQuietHours(spec string) (int, int, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return -1, -1, nil
+	}
+	parts := strings.Split(spec, "-")
+	if len(parts) != 2 {
+		return -1, -1, fmt.Errorf("格式应为 HH:MM-HH:MM: %q", spec)
+	}
+	var bounds [2]int
+	for i, p := range parts {
+		t, err := time.Parse("15:04", strings.TrimSpace(p))
+		if err != nil {
+			return -1, -1, fmt.Errorf("时间无效 %q: %w", p, err)
+		}
+		bounds[i] = t.Hour()*60 + t.Minute()
+	}
+	if bounds[0] == bounds[1] {
+		return -1, -1, fmt.Errorf("开始与结束时间相同: %q", spec)
+	}
+	return bounds[0], bounds[1], nil
+}
+
+// SchedulerStatus 调度器状态
+type SchedulerStatus struct {
+	Running            bool   `json:"running"`
+	QuietHours         string `json:"quiet_hours"`
+	InQuietHours       bool   `json:"in_quiet_hours"`
+	DailyRequestBudget int64  `json:"daily_request_budget"`
+	RequestsToday      int64  `json:"requests_today"`
+}
+
+// GetSchedulerStatus 获取调度器状态与当日接口请求用量
+func GetSchedulerStatus() (*SchedulerStatus, error) {
+	cfg := config.Get()
+	now := time.Now()
+	status := &SchedulerStatus{
+		Running:            scheduler != nil,
+		QuietHours:         cfg.Scheduler.QuietHours,
+		DailyRequestBudget: int64(cfg.Scheduler.DailyRequestBudget),
+	}
+	if scheduler != nil {
+		status.InQuietHours = scheduler.inQuietHours(now)
+	}
+
+	used, err := database.GetRequestUsage(now.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	// 加上尚未记录到数据库的请求数
+	if scheduler != nil {
+		used += util.RequestCount() - scheduler.lastCount.Load()
+	}
+	status.RequestsToday = used
+	return status, nil
+}
+
+// ScheduleInput 创建或更新计划的参数，更新时未提供的字段保持不变
+type ScheduleInput struct {
+	Type        string `json:"type"`
+	Target      string `json:"target"`
+	Cron        string `json:"cron"`
+	FetchAll    *bool  `json:"fetch_all"`
+	Incremental *bool  `json:"incremental"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+// CreateSchedule 校验参数并创建计划，视频计划默认增量爬取
+func CreateSchedule(in ScheduleInput) (*database.Schedule, error) {
+	sch := &database.Schedule{
+		Type:        in.Type,
+		Incremental: true,
+		Enabled:     true,
+	}
+	if sch.Type == "" {
+		sch.Type = database.CrawlJobTypeVideo
+	}
+	applyScheduleInput(sch, in)
+	if err := prepareSchedule(sch); err != nil {
+		return nil, err
+	}
+	if err := database.CreateSchedule(sch); err != nil {
+		return nil, err
+	}
+	logger.GetLogger().Infof("已创建定时爬取计划: id=%d, type=%s, target=%s, cron=%q", sch.ID, sch.Type, sch.Target, sch.Cron)
+	return sch, nil
+}
+
+// UpdateSchedule 更新计划，计划不存在时返回 nil
+func UpdateSchedule(id int64, in ScheduleInput) (*database.Schedule, error) {
+	sch, err := database.GetSchedule(id)
+	if err != nil || sch == nil {
+		return nil, err
+	}
+	if in.Type != "" {
+		sch.Type = in.Type
+	}
+	applyScheduleInput(sch, in)
+	if err := prepareSchedule(sch); err != nil {
+		return nil, err
+	}
+	if err := database.UpdateSchedule(sch); err != nil {
+		return nil, err
+	}
+	logger.GetLogger().Infof("已更新定时爬取计划: id=%d", sch.ID)
+	return sch, nil
+}
+
+func applyScheduleInput(sch *database.Schedule, in ScheduleInput) {
+	if in.Target != "" {
+		sch.Target = strings.TrimSpace(in.Target)
+	}
+	if in.Cron != "" {
+		sch.Cron = strings.TrimSpace(in.Cron)
+	}
+	if in.FetchAll != nil {
+		sch.FetchAll = *in.FetchAll
+	}
+	if in.Incremental != nil {
+		sch.Incremental = *in.Incremental
+	}
+	if in.Enabled != nil {
+		sch.Enabled = *in.Enabled
+	}
+}
+
+// prepareSchedule 校验计划并计算下次执行时间，校验失败时返回用户输入错误
+func prepareSchedule(sch *database.Schedule) error {
+	invalid := func(msg string) error {
+		return NewCrawlerError(msg, ErrorTypeUserInput, ErrorLevelLow, ErrorTypeUserInput)
+	}
+
+	switch sch.Type {
+	case database.CrawlJobTypeVideo:
+		if !strings.HasPrefix(sch.Target, "BV") {
+			return invalid("视频计划的 target 必须是BV号")
+		}
+	case database.CrawlJobTypeUp:
+		if mid, err := strconv.ParseInt(sch.Target, 10, 64); err != nil || mid <= 0 {
+			return invalid("UP主计划的 target 必须是有效的mid")
+		}
+	default:
+		return invalid("type 必须是 video 或 up")
+	}
+
+	if sch.Cron == "" {
+		return invalid("缺少 cron 表达式")
+	}
+	cron, err := parseCron(sch.Cron)
+	if err != nil {
+		return invalid(err.Error())
+	}
+
+	sch.NextRunAt = nil
+	if sch.Enabled {
+		next := cron.Next(time.Now())
+		if next.IsZero() {
+			return invalid("cron 表达式在五年内不会触发")
+		}
+		sch.NextRunAt = &next
+	}
+	return nil
+}
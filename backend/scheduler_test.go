@@ -0,0 +1,189 @@
+package backend
+
+import (
+	"testing"
+	"time"
+
+	biliutil "bilibili-comments-viewer-go/crawler/bili_info/util"
+	"bilibili-comments-viewer-go/database"
+)
+
+func TestParseQuietHours(t *testing.T) {
+	for _, tc := range []struct {
+		spec       string
+		start, end int
+		wantErr    bool
+	}{
+		{spec: "", start: -1, end: -1},
+		{spec: "  ", start: -1, end: -1},
+		{spec: "01:00-05:30", start: 60, end: 330},
+		{spec: "23:00-07:00", start: 1380, end: 420},
+		{spec: " 22:15 - 06:45 ", start: 1335, end: 405},
+		{spec: "00:00-23:59", start: 0, end: 1439},
+		{spec: "23:00", wantErr: true},
+		{spec: "23:00-07:00-08:00", wantErr: true},
+		{spec: "25:00-07:00", wantErr: true},
+		{spec: "23:00-7点", wantErr: true},
+		{spec: "07:00-07:00", wantErr: true},
+	} {
+		start, end, err := parseQuietHours(tc.spec)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("parseQuietHours(%q) 应返回错误, 得到 %d-%d", tc.spec, start, end)
+			}
+			continue
+		}
+		if err != nil || start != tc.start || end != tc.end {
+			t.Errorf("parseQuietHours(%q) = %d, %d, %v, 期望 %d, %d", tc.spec, start, end, err, tc.start, tc.end)
+		}
+	}
+}
+
+func TestInQuietHours(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2026, 3, 14, hour, min, 0, 0, time.Local)
+	}
+	for _, tc := range []struct {
+		spec string
+		now  time.Time
+		want bool
+	}{
+		{"", at(3, 0), false},
+		// 同一天内的时段，含开始不含结束
+		{"01:00-05:30", at(0, 59), false},
+		{"01:00-05:30", at(1, 0), true},
+		{"01:00-05:30", at(5, 29), true},
+		{"01:00-05:30", at(5, 30), false},
+		// 跨越午夜的时段
+		{"23:00-07:00", at(22, 59), false},
+		{"23:00-07:00", at(23, 0), true},
+		{"23:00-07:00", at(23, 59), true},
+		{"23:00-07:00", at(0, 0), true},
+		{"23:00-07:00", at(6, 59), true},
+		{"23:00-07:00", at(7, 0), false},
+		{"23:00-07:00", at(12, 0), false},
+	} {
+		start, end, err := parseQuietHours(tc.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := &Scheduler{quietStart: start, quietEnd: end}
+		if got := s.inQuietHours(tc.now); got != tc.want {
+			t.Errorf("%q 在 %s: inQuietHours = %v, 期望 %v", tc.spec, tc.now.Format("15:04"), got, tc.want)
+		}
+	}
+}
+
+// newTestScheduler 不启动调度循环与任务 worker 的调度器，提交的任务停留在队列中
+func newTestScheduler(quietHours string, budget int64) *Scheduler {
+	start, end, err := parseQuietHours(quietHours)
+	if err != nil {
+		panic(err)
+	}
+	s := &Scheduler{
+		jobs:       newTestJobManager(),
+		quietStart: start,
+		quietEnd:   end,
+		budget:     budget,
+		stop:       make(chan struct{}),
+	}
+	s.lastCount.Store(biliutil.RequestCount())
+	return s
+}
+
+// createDueSchedule 新建一个在 dueAt 到期、之后每 5 分钟执行一次的视频计划
+func createDueSchedule(t *testing.T, bvid string, dueAt time.Time) *database.Schedule {
+	t.Helper()
+	sch := &database.Schedule{
+		Type:    database.CrawlJobTypeVideo,
+		Target:  bvid,
+		Cron:    "*/5 * * * *",
+		Enabled: true,
+	}
+	sch.NextRunAt = &dueAt
+	if err := database.CreateSchedule(sch); err != nil {
+		t.Fatal(err)
+	}
+	return sch
+}
+
+func queuedJobs(t *testing.T) int {
+	t.Helper()
+	return countRows(t, "SELECT COUNT(*) FROM crawl_jobs WHERE status = ?", database.CrawlJobQueued)
+}
+
+func TestSchedulerQuietHoursDefersSchedules(t *testing.T) {
+	newFakeEnv(t)
+	s := newTestScheduler("23:00-07:00", 0)
+	day := time.Date(2026, 3, 14, 0, 0, 0, 0, time.Local)
+	createDueSchedule(t, "BV1xx411c7mD", day.Add(22*time.Hour+50*time.Minute))
+
+	// 跨越午夜的静默时段两侧都不提交
+	for _, now := range []time.Time{day.Add(23 * time.Hour), day.Add(30 * time.Hour)} {
+		s.tick(now)
+		if n := queuedJobs(t); n != 0 {
+			t.Fatalf("静默时段 %s 提交了 %d 个任务", now.Format("01-02 15:04"), n)
+		}
+	}
+	s.tick(day.Add(31 * time.Hour))
+	if n := queuedJobs(t); n != 1 {
+		t.Fatalf("静默时段结束后提交了 %d 个任务, 期望 1", n)
+	}
+}
+
+func TestSchedulerBudgetRollsOverAtMidnight(t *testing.T) {
+	newFakeEnv(t)
+	const budget = 100
+	s := newTestScheduler("", budget)
+	day1 := time.Date(2026, 3, 14, 0, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+	sch := createDueSchedule(t, "BV1xx411c7mD", day1.Add(23*time.Hour))
+
+	// 午夜前产生的请求计入前一天，用尽当日预算
+	if err := database.AddRequestUsage(day1.Format("2006-01-02"), budget-3); err != nil {
+		t.Fatal(err)
+	}
+	s.lastCount.Add(-3)
+	s.tick(day1.Add(23*time.Hour + 30*time.Minute))
+	if used, _ := database.GetRequestUsage(day1.Format("2006-01-02")); used != budget {
+		t.Fatalf("前一天用量 = %d, 期望 %d", used, budget)
+	}
+	if n := queuedJobs(t); n != 0 {
+		t.Fatalf("预算用尽时提交了 %d 个任务", n)
+	}
+	if got, _ := database.GetSchedule(sch.ID); got.LastRunAt != nil {
+		t.Fatalf("预算用尽时计划被标记为已执行: %v", got.LastRunAt)
+	}
+
+	// 午夜后的请求计入新的一天，预算重新计算，推迟的计划立即执行
+	s.lastCount.Add(-2)
+	s.tick(day2.Add(5 * time.Minute))
+	if used, _ := database.GetRequestUsage(day1.Format("2006-01-02")); used != budget {
+		t.Errorf("前一天用量 = %d, 期望仍为 %d", used, budget)
+	}
+	if used, _ := database.GetRequestUsage(day2.Format("2006-01-02")); used != 2 {
+		t.Errorf("新一天用量 = %d, 期望 2", used)
+	}
+	if n := queuedJobs(t); n != 1 {
+		t.Fatalf("新的一天提交了 %d 个任务, 期望 1", n)
+	}
+	got, err := database.GetSchedule(sch.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LastJobID == 0 || got.NextRunAt == nil || !got.NextRunAt.Equal(day2.Add(10*time.Minute)) {
+		t.Errorf("执行后 last_job_id = %d, next_run_at = %v, 期望非 0 与 %s", got.LastJobID, got.NextRunAt, day2.Add(10*time.Minute))
+	}
+
+	// 新一天的用量达到预算后再次推迟
+	if err := database.AddRequestUsage(day2.Format("2006-01-02"), budget); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.GetDB().Exec("UPDATE crawl_jobs SET status = ?", database.CrawlJobSucceeded); err != nil {
+		t.Fatal(err)
+	}
+	s.tick(day2.Add(15 * time.Minute))
+	if n := queuedJobs(t); n != 0 {
+		t.Errorf("新一天预算用尽后提交了 %d 个任务", n)
+	}
+}
@@ -0,0 +1,114 @@
+package backend
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"bilibili-comments-viewer-go/config"
+	"bilibili-comments-viewer-go/crawler/blblcd"
+	blblcdmodel "bilibili-comments-viewer-go/crawler/blblcd/model"
+	blblcdstore "bilibili-comments-viewer-go/crawler/blblcd/store"
+	"bilibili-comments-viewer-go/database"
+	"bilibili-comments-viewer-go/logger"
+)
+
+// hasSink 配置中是否启用了指定的评论输出目标
+func hasSink(name string) bool {
+	for _, s := range config.Get().Crawler.Sinks {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// newVideoSinks 按配置创建视频的评论输出目标，文件输出位于 output_dir/<BV号>/ 下
+// appendMode: 文件输出是否追加到已有文件（增量爬取、断点续爬）
+// seenAt: 写入数据库的评论的观察时间，即本次爬取的开始时间
+func newVideoSinks(bvid string, appendMode bool, seenAt time.Time) ([]blblcd.Sink, error) {
+	cfg := config.Get()
+	dir := filepath.Join(cfg.Crawler.OutputDir, bvid)
+
+	var sinks []blblcd.Sink
+	for _, name := range cfg.Crawler.Sinks {
+		switch name {
+		case SinkSQLite:
+			sinks = append(sinks, newDBSink(bvid, seenAt))
+		case SinkCSV:
+			sinks = append(sinks, blblcdstore.NewCSVSink(filepath.Join(dir, bvid+".csv"), appendMode))
+		case SinkNDJSON:
+			sinks = append(sinks, blblcdstore.NewNDJSONSink(filepath.Join(dir, bvid+".ndjson"), appendMode))
+		default:
+			return nil, fmt.Errorf("未知的评论输出目标: %s", name)
+		}
+	}
+	if cfg.Crawler.ImgDownload {
+		sinks = append(sinks, blblcdstore.NewImageSink(cfg.ImageStorageDir))
+	}
+	return sinks, nil
+}
+
+// dbSink 将评论转换后直接写入数据库，每批写入后刷新统计，爬取过程中即可在查看器中看到
+type dbSink struct {
+	bvid   string
+	seenAt time.Time // 评论的观察时间
+	saved  int       // 已写入的评论数
+}
+
+func newDBSink(bvid string, seenAt time.Time) *dbSink {
+	return &dbSink{bvid: bvid, seenAt: time.Unix(seenAt.Unix(), 0)}
+}
+
+func (s *dbSink) Open() error {
+	return nil
+}
+
+// Write 转换并保存一批评论及其回复关系，随后刷新评论统计
+func (s *dbSink) Write(batch []blblcdmodel.Comment) error {
+	dbComments := make([]*database.Comment, 0, len(batch))
+	for i := range batch {
+		if c := convertToDBComment(&batch[i]); c != nil {
+			c.LastSeen = s.seenAt
+			dbComments = append(dbComments, c)
+		}
+	}
+	if len(dbComments) == 0 {
+		return nil
+	}
+
+	if err := database.BatchSaveComments(dbComments); err != nil {
+		return fmt.Errorf("批量保存评论失败: %w", err)
+	}
+	s.saved += len(dbComments)
+
+	// 子评论可能先于主评论到达，按 parent 字段建立关系，结束后再统一重建
+	children := make(map[string][]string)
+	for _, c := range dbComments {
+		if c.Parent != "0" {
+			children[c.Parent] = append(children[c.Parent], c.UniqueID)
+		}
+	}
+	for parentID, childIDs := range children {
+		if err := database.SaveCommentRelations(parentID, childIDs); err != nil {
+			logger.GetLogger().Errorf("保存评论关系失败: %v", err)
+		}
+	}
+
+	if err := database.UpdateCommentStats(s.bvid); err != nil {
+		logger.GetLogger().Errorf("更新评论统计失败: %v", err)
+	}
+	return nil
+}
+
+// Close 全部评论写入后重建回复关系
+func (s *dbSink) Close() error {
+	if s.saved == 0 {
+		return nil
+	}
+	logger.GetLogger().Infof("已写入 %d 条评论到数据库 (bvid: %s)，重建评论关系...", s.saved, s.bvid)
+	if err := database.RebuildAllCommentRelations(s.bvid); err != nil {
+		return fmt.Errorf("重建评论关系失败: %w", err)
+	}
+	return database.UpdateCommentStats(s.bvid)
+}
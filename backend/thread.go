@@ -0,0 +1,102 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"bilibili-comments-viewer-go/database"
+)
+
+// 回复树的深度与规模限制
+const (
+	DefaultThreadDepth = 8
+	MaxThreadDepth     = 32
+	DefaultThreadSize  = 200
+	MaxThreadSize      = 1000
+)
+
+// ErrCommentNotFound 评论不存在
+var ErrCommentNotFound = errors.New("评论不存在")
+
+// ThreadNode 回复树中的一条评论
+type ThreadNode struct {
+	database.Comment
+	Depth    int           `json:"depth"`              // 请求的评论为 0
+	Children []*ThreadNode `json:"children,omitempty"` // 按发布时间升序
+	Omitted  int           `json:"omitted,omitempty"`  // 超出深度限制未展开的后代数
+}
+
+// Thread 以一条评论为根的回复树
+type Thread struct {
+	Root      *ThreadNode `json:"root"`
+	Size      int         `json:"size"`      // 树中的回复数，不含根与未展开的后代
+	Omitted   int         `json:"omitted"`   // 超出深度限制未展开的回复数
+	Truncated bool        `json:"truncated"` // 回复数超出规模限制，部分回复未返回
+}
+
+// BuildThread 重建评论下的完整回复树
+// 父节点优先取 parent（即 comment_relations 中的关系），父评论未入库时挂到所属对话的首条评论，
+// 仍找不到时直接挂在根下；深度超过 maxDepth 的回复不展开，计入最近的已展开祖先的 Omitted
+func BuildThread(uniqueID string, maxDepth, maxSize int) (*Thread, error) {
+	if maxDepth < 1 || maxDepth > MaxThreadDepth {
+		return nil, fmt.Errorf("深度限制必须在 1 到 %d 之间", MaxThreadDepth)
+	}
+	if maxSize < 1 || maxSize > MaxThreadSize {
+		return nil, fmt.Errorf("规模限制必须在 1 到 %d 之间", MaxThreadSize)
+	}
+
+	target, err := database.GetCommentByID(uniqueID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, ErrCommentNotFound
+	}
+	replies, truncated, err := database.GetThreadComments(target, maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// 回复总是晚于被回复的评论，按时间顺序处理时父节点已先入树
+	sort.SliceStable(replies, func(i, j int) bool {
+		if !replies[i].Ctime.Equal(replies[j].Ctime) {
+			return replies[i].Ctime.Before(replies[j].Ctime)
+		}
+		return replies[i].UniqueID < replies[j].UniqueID
+	})
+
+	root := &ThreadNode{Comment: *target}
+	thread := &Thread{Root: root, Truncated: truncated}
+	nodes := map[string]*ThreadNode{target.UniqueID: root}
+	// collapsed 超出深度限制的回复 -> 最近的已展开祖先
+	collapsed := map[string]*ThreadNode{}
+	lookup := func(id string) *ThreadNode {
+		if n, ok := nodes[id]; ok {
+			return n
+		}
+		return collapsed[id]
+	}
+
+	for _, c := range replies {
+		parent := lookup(c.Parent)
+		if parent == nil && c.Dialog != 0 {
+			parent = lookup(c.BVid + "_" + strconv.FormatInt(c.Dialog, 10))
+		}
+		if parent == nil {
+			parent = root
+		}
+		if parent.Depth >= maxDepth {
+			parent.Omitted++
+			thread.Omitted++
+			collapsed[c.UniqueID] = parent
+			continue
+		}
+		node := &ThreadNode{Comment: c, Depth: parent.Depth + 1}
+		parent.Children = append(parent.Children, node)
+		nodes[c.UniqueID] = node
+		thread.Size++
+	}
+	return thread, nil
+}
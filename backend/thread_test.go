@@ -0,0 +1,34 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+)
+
+// 1000062 楼层的回复全部出现在树中，限制深度后未展开的回复计入 omitted
+func TestBuildThread(t *testing.T) {
+	_, video := newCrawledEnv(t)
+
+	threadSize := countRows(t, "SELECT COUNT(*) FROM bilibili_comments WHERE bvid = ? AND root = 1000062", video.Bvid)
+	if threadSize == 0 {
+		t.Fatal("1000062 楼层没有回复")
+	}
+	for _, depth := range []int{DefaultThreadDepth, 1} {
+		thread, err := BuildThread(video.Bvid+"_1000062", depth, DefaultThreadSize)
+		if err != nil {
+			t.Errorf("BuildThread(深度 %d): %v", depth, err)
+			continue
+		}
+		if thread.Size+thread.Omitted != threadSize || thread.Truncated {
+			t.Errorf("深度 %d 的回复树含 %d 条回复、%d 条未展开，楼层共 %d 条", depth, thread.Size, thread.Omitted, threadSize)
+		}
+	}
+
+	thread, err := BuildThread(video.Bvid+"_1000062", DefaultThreadDepth, 3)
+	if err != nil || thread.Size != 3 || !thread.Truncated {
+		t.Errorf("规模限制 3 条时返回 %+v err=%v", thread, err)
+	}
+	if _, err := BuildThread(video.Bvid+"_404", 1, 1); !errors.Is(err, ErrCommentNotFound) {
+		t.Errorf("不存在的评论未返回 ErrCommentNotFound: %v", err)
+	}
+}
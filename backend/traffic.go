@@ -0,0 +1,53 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"bilibili-comments-viewer-go/config"
+	"bilibili-comments-viewer-go/crawler/biliapi"
+	"bilibili-comments-viewer-go/logger"
+)
+
+// RecordingPath 任务流量录制存档的路径
+func RecordingPath(jobID int64) string {
+	return filepath.Join(config.Get().Crawler.RecordingDir, fmt.Sprintf("job-%d.tar.zst", jobID))
+}
+
+// withTraffic 按配置为任务开启流量回放或录制，任务结束时调用返回的 finish 关闭存档
+// 配置了 replay_archive 时优先回放，存档无法读取时返回错误，避免任务意外访问网络
+func withTraffic(ctx context.Context, jobID int64) (context.Context, func(), error) {
+	log := logger.GetLogger()
+	cfg := config.Get()
+
+	if archive := cfg.Crawler.ReplayArchive; archive != "" {
+		rp, err := biliapi.OpenReplay(archive)
+		if err != nil {
+			return ctx, func() {}, err
+		}
+		log.Infof("任务 %d 从存档回放接口请求: %s", jobID, archive)
+		return biliapi.WithReplay(ctx, rp), func() {
+			if n := rp.Remaining(); n > 0 {
+				log.Warnf("任务 %d 回放结束，存档中还有 %d 条响应未被请求", jobID, n)
+			}
+		}, nil
+	}
+
+	if !cfg.Crawler.RecordTraffic {
+		return ctx, func() {}, nil
+	}
+	rec, err := biliapi.NewRecorder(RecordingPath(jobID))
+	if err != nil {
+		// 录制失败不影响爬取
+		log.Errorf("任务 %d 开启流量录制失败: %v", jobID, err)
+		return ctx, func() {}, nil
+	}
+	return biliapi.WithRecorder(ctx, rec), func() {
+		if err := rec.Close(); err != nil {
+			log.Errorf("任务 %d 流量录制存档写入失败: %v", jobID, err)
+			return
+		}
+		log.Infof("任务 %d 已录制 %d 次接口请求: %s", jobID, rec.Count(), rec.Path())
+	}, nil
+}
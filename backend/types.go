@@ -4,11 +4,11 @@ import (
 	"time"
 )
 
-// 保存模式常量
+// 评论输出目标，对应配置 crawler.sinks
 const (
-	SaveModeCSVOnly  = "csv_only"
-	SaveModeDBOnly   = "db_only"
-	SaveModeCSVAndDB = "csv_and_db"
+	SinkSQLite = "sqlite"
+	SinkCSV    = "csv"
+	SinkNDJSON = "ndjson"
 )
 
 // 错误级别常量
@@ -0,0 +1,123 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"bilibili-comments-viewer-go/config"
+	"bilibili-comments-viewer-go/crawler/bili_info/util"
+	"bilibili-comments-viewer-go/database"
+	"bilibili-comments-viewer-go/logger"
+)
+
+var (
+	// ErrVideoNotFound 数据库与文件中都没有该视频的数据
+	ErrVideoNotFound = errors.New("视频不存在")
+	// ErrVideoBusy 视频有排队中或运行中的爬取任务，删除后会被重新写入
+	ErrVideoBusy = errors.New("视频有未结束的爬取任务，请先取消")
+	// ErrInvalidBVid BV号格式不正确
+	ErrInvalidBVid = errors.New("无效的BV号")
+)
+
+// DeletedFile 删除（或试运行时将要删除）的文件或目录
+type DeletedFile struct {
+	Path  string `json:"path"`
+	Kind  string `json:"kind"`  // images、cover 或 output
+	Files int    `json:"files"` // 目录时为其中的文件数
+	Bytes int64  `json:"bytes"`
+}
+
+// VideoDeleteResult 删除视频的结果
+type VideoDeleteResult struct {
+	Bvid     string                  `json:"bvid"`
+	DryRun   bool                    `json:"dry_run"`
+	Database *database.VideoDeletion `json:"database"`
+	Files    []DeletedFile           `json:"files"`
+	Errors   []string                `json:"errors,omitempty"` // 删除失败的文件，数据库已删除
+}
+
+// DeleteVideo 删除视频的数据库记录与本地文件：评论图片目录 ImageStorageDir/<BV号>、
+// 封面 ImageStorageDir/cover/<BV号>.jpg 与评论输出目录 Crawler.OutputDir/<BV号>。
+// 数据库在一个事务中删除，随后删除文件；dryRun 时只报告将要删除的内容
+func DeleteVideo(bvid string, dryRun bool) (*VideoDeleteResult, error) {
+	// BV号用于拼接文件路径，拼接前先校验格式
+	if !util.IsValidBVID(bvid) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidBVid, bvid)
+	}
+	active, err := database.GetActiveCrawlJobs(database.CrawlJobTypeVideo, bvid)
+	if err != nil {
+		return nil, err
+	}
+	if len(active) > 0 {
+		return nil, ErrVideoBusy
+	}
+
+	cfg := config.Get()
+	candidates := []DeletedFile{
+		{Path: filepath.Join(cfg.ImageStorageDir, bvid), Kind: "images"},
+		{Path: filepath.Join(cfg.ImageStorageDir, "cover", bvid+".jpg"), Kind: "cover"},
+		{Path: filepath.Join(cfg.Crawler.OutputDir, bvid), Kind: "output"},
+	}
+	result := &VideoDeleteResult{Bvid: bvid, DryRun: dryRun, Files: []DeletedFile{}}
+	for _, f := range candidates {
+		if ok, err := measurePath(&f); err != nil {
+			return nil, err
+		} else if ok {
+			result.Files = append(result.Files, f)
+		}
+	}
+
+	if result.Database, err = database.DeleteVideo(bvid, dryRun); err != nil {
+		return nil, err
+	}
+	if result.Database.Empty() && len(result.Files) == 0 {
+		return nil, ErrVideoNotFound
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	log := logger.GetLogger()
+	for _, f := range result.Files {
+		if err := os.RemoveAll(f.Path); err != nil {
+			log.Errorf("删除视频文件失败: %v", err)
+			result.Errors = append(result.Errors, err.Error())
+		}
+	}
+	log.Infof("视频 %s 已删除: %d 条评论，%d 个文件或目录", bvid, result.Database.Comments, len(result.Files))
+	return result, nil
+}
+
+// measurePath 统计文件或目录中的文件数与大小，路径不存在时返回 false
+func measurePath(f *DeletedFile) (bool, error) {
+	info, err := os.Stat(f.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("读取文件信息失败: %w", err)
+	}
+	if !info.IsDir() {
+		f.Files, f.Bytes = 1, info.Size()
+		return true, nil
+	}
+	err = filepath.WalkDir(f.Path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		f.Files++
+		f.Bytes += fi.Size()
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("统计目录失败: %w", err)
+	}
+	return true, nil
+}
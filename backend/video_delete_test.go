@@ -0,0 +1,59 @@
+package backend
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+// 试运行只报告，删除后关系与统计级联删除，图片、封面与输出文件一并删除
+func TestDeleteVideo(t *testing.T) {
+	_, video := newCrawledEnv(t)
+
+	relationsQuery := `SELECT COUNT(*) FROM comment_relations WHERE child_id IN (SELECT unique_id FROM bilibili_comments WHERE bvid = ?1)
+		OR child_id LIKE ?1 || '\_%' ESCAPE '\'`
+	relations := countRows(t, relationsQuery, video.Bvid)
+
+	dry, err := DeleteVideo(video.Bvid, true)
+	if err != nil {
+		t.Fatalf("试运行删除: %v", err)
+	}
+	if !dry.Database.Video || dry.Database.Comments != video.CommentCount() || dry.Database.Relations != relations ||
+		dry.Database.Stats != 1 || len(dry.Files) != 3 {
+		t.Errorf("试运行删除报告 %d 条评论、%d 条关系（期望 %d）、%d 个文件或目录",
+			dry.Database.Comments, dry.Database.Relations, relations, len(dry.Files))
+	}
+	if got := commentCount(t, video.Bvid); got != video.CommentCount() {
+		t.Errorf("试运行后评论数 %d，期望 %d", got, video.CommentCount())
+	}
+
+	deleted, err := DeleteVideo(video.Bvid, false)
+	if err != nil {
+		t.Fatalf("删除: %v", err)
+	}
+	if len(deleted.Errors) != 0 {
+		t.Errorf("删除文件出错: %v", deleted.Errors)
+	}
+	for _, f := range deleted.Files {
+		if _, err := os.Stat(f.Path); err == nil {
+			t.Errorf("%s 未删除", f.Path)
+		}
+	}
+	for _, query := range []string{
+		"SELECT COUNT(*) FROM bilibili_comments WHERE bvid = ?",
+		relationsQuery,
+		"SELECT COUNT(*) FROM comment_stats WHERE bvid = ?",
+		"SELECT COUNT(*) FROM video_info WHERE bvid = ?",
+	} {
+		if n := countRows(t, query, video.Bvid); n != 0 {
+			t.Errorf("删除后仍有 %d 行: %s", n, query)
+		}
+	}
+
+	if _, err := DeleteVideo(video.Bvid, true); !errors.Is(err, ErrVideoNotFound) {
+		t.Errorf("已删除的视频未返回 ErrVideoNotFound: %v", err)
+	}
+	if _, err := DeleteVideo("../BV1", true); !errors.Is(err, ErrInvalidBVid) {
+		t.Errorf("无效的 BV 号未返回 ErrInvalidBVid: %v", err)
+	}
+}
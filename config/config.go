@@ -36,18 +36,51 @@ type Config struct {
 	} `mapstructure:"logging"`
 
 	Crawler struct {
-		CookieFile    string `mapstructure:"cookie_file"`
-		NoCover       bool   `mapstructure:"no_cover"`
-		OutputDir     string `mapstructure:"output_dir"`
-		Workers       int    `mapstructure:"workers"`
-		MaxTryCount   int    `mapstructure:"max_try_count"`
-		ImgDownload   bool   `mapstructure:"img_download"`
-		UpPages       int    `mapstructure:"up_pages"`
-		UpOrder       string `mapstructure:"up_order"`
-		SaveMode      string `mapstructure:"save_mode"`
-		DelayBaseMs   int    `mapstructure:"delay_base_ms"`
-		DelayJitterMs int    `mapstructure:"delay_jitter_ms"`
+		CookieFile    string   `mapstructure:"cookie_file"`
+		NoCover       bool     `mapstructure:"no_cover"`
+		OutputDir     string   `mapstructure:"output_dir"`
+		Workers       int      `mapstructure:"workers"`
+		MaxTryCount   int      `mapstructure:"max_try_count"`
+		ImgDownload   bool     `mapstructure:"img_download"`
+		UpPages       int      `mapstructure:"up_pages"`
+		UpOrder       string   `mapstructure:"up_order"`
+		Sinks         []string `mapstructure:"sinks"`     // 评论输出目标，可多选: sqlite, csv, ndjson
+		SaveMode      string   `mapstructure:"save_mode"` // 已弃用，未配置 sinks 时按旧的保存模式换算
+		DelayBaseMs   int      `mapstructure:"delay_base_ms"`
+		DelayJitterMs int      `mapstructure:"delay_jitter_ms"`
+		JobWorkers    int      `mapstructure:"job_workers"` // 同时运行的爬取任务数
+		Incremental   bool     `mapstructure:"incremental"` // 重复爬取已入库视频时默认只爬取新增评论
+		IncrementalExtraPages int `mapstructure:"incremental_extra_pages"` // 增量爬取到达已入库评论后继续翻页的页数，用于刷新旧楼层的回复数
+		RateBurst     int      `mapstructure:"rate_burst"`  // 各接口族允许的突发请求数
+		ArchiveRawPages bool   `mapstructure:"archive_raw_pages"` // 保存评论接口的原始响应，可用于重新解析
+		RecordTraffic bool     `mapstructure:"record_traffic"` // 将每个任务的接口请求与响应录制到 recording_dir/job-<id>.tar.zst
+		RecordingDir  string   `mapstructure:"recording_dir"`
+		ReplayArchive string   `mapstructure:"replay_archive"` // 设置后所有任务从该录制存档回放，不访问网络
+
+		// 各接口族每秒请求数上限（所有爬取任务共享），0 为不限流
+		RateLimits struct {
+			ReplyMain  float64 `mapstructure:"reply_main"`  // 主评论列表与评论总数
+			ReplyReply float64 `mapstructure:"reply_reply"` // 子评论列表
+			ArcSearch  float64 `mapstructure:"arc_search"`  // UP主投稿视频列表
+			View       float64 `mapstructure:"view"`        // 视频信息
+			Nav        float64 `mapstructure:"nav"`         // 导航信息（WBI 密钥）
+		} `mapstructure:"rate_limits"`
+
+		// 各接口族的服务地址，可指向镜像或本地模拟服务器
+		BaseURLs struct {
+			ReplyMain  string `mapstructure:"reply_main"`  // 主评论列表与评论总数
+			ReplyReply string `mapstructure:"reply_reply"` // 子评论列表
+			ArcSearch  string `mapstructure:"arc_search"`  // UP主投稿视频列表
+			View       string `mapstructure:"view"`        // 视频信息
+			Nav        string `mapstructure:"nav"`         // 导航信息（WBI 密钥）
+		} `mapstructure:"base_urls"`
 	} `mapstructure:"crawler"`
+
+	Scheduler struct {
+		Enabled            bool   `mapstructure:"enabled"`
+		QuietHours         string `mapstructure:"quiet_hours"`          // 静默时段，如 "23:00-07:00"，为空时不限制
+		DailyRequestBudget int    `mapstructure:"daily_request_budget"` // 每日B站接口请求预算，0 为不限制
+	} `mapstructure:"scheduler"`
 }
 
 // 增强路径规范化函数
@@ -97,6 +130,46 @@ func ensureDir(path string) error {
 	return os.MkdirAll(path, 0755)
 }
 
+// legacySaveModes 旧的 save_mode 取值对应的输出目标
+var legacySaveModes = map[string][]string{
+	"csv_only":   {"csv"},
+	"db_only":    {"sqlite"},
+	"csv_and_db": {"sqlite", "csv"},
+}
+
+// resolveSinks 校验评论输出目标，未配置时按旧的 save_mode 换算，两者都未配置时默认写入数据库和 CSV
+func resolveSinks(sinks []string, saveMode string) ([]string, error) {
+	if len(sinks) == 0 {
+		if saveMode == "" {
+			saveMode = "csv_and_db"
+		}
+		legacy, ok := legacySaveModes[saveMode]
+		if !ok {
+			return nil, fmt.Errorf("unknown crawler.save_mode %q", saveMode)
+		}
+		if viper.IsSet("crawler.save_mode") {
+			fmt.Printf("Warning: crawler.save_mode 已弃用，请改用 crawler.sinks: [%s]\n", strings.Join(legacy, ", "))
+		}
+		return legacy, nil
+	}
+
+	seen := make(map[string]bool)
+	resolved := make([]string, 0, len(sinks))
+	for _, s := range sinks {
+		s = strings.ToLower(strings.TrimSpace(s))
+		switch s {
+		case "sqlite", "csv", "ndjson":
+		default:
+			return nil, fmt.Errorf("unknown crawler.sinks entry %q (expected sqlite, csv or ndjson)", s)
+		}
+		if !seen[s] {
+			seen[s] = true
+			resolved = append(resolved, s)
+		}
+	}
+	return resolved, nil
+}
+
 func LoadConfig() (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
@@ -139,9 +212,29 @@ func LoadConfig() (*Config, error) {
 	viper.SetDefault("crawler.workers", 5)
 	viper.SetDefault("crawler.max_try_count", 3)
 	viper.SetDefault("crawler.img_download", false)
-	viper.SetDefault("crawler.save_mode", "csv_and_db")
 	viper.SetDefault("crawler.delay_base_ms", 3000)
 	viper.SetDefault("crawler.delay_jitter_ms", 2000)
+	viper.SetDefault("crawler.job_workers", 2)
+	viper.SetDefault("crawler.incremental", false)
+	viper.SetDefault("crawler.incremental_extra_pages", 0)
+	viper.SetDefault("crawler.rate_burst", 2)
+	viper.SetDefault("crawler.archive_raw_pages", true)
+	viper.SetDefault("crawler.record_traffic", false)
+	viper.SetDefault("crawler.recording_dir", "{{user_data_dir}}/recordings")
+	viper.SetDefault("crawler.replay_archive", "")
+	viper.SetDefault("crawler.rate_limits.reply_main", 1)
+	viper.SetDefault("crawler.rate_limits.reply_reply", 2)
+	viper.SetDefault("crawler.rate_limits.arc_search", 0.5)
+	viper.SetDefault("crawler.rate_limits.view", 1)
+	viper.SetDefault("crawler.rate_limits.nav", 1)
+	for _, ep := range []string{"reply_main", "reply_reply", "arc_search", "view", "nav"} {
+		viper.SetDefault("crawler.base_urls."+ep, "https://api.bilibili.com")
+	}
+
+	// 设置定时爬取默认值
+	viper.SetDefault("scheduler.enabled", true)
+	viper.SetDefault("scheduler.quiet_hours", "")
+	viper.SetDefault("scheduler.daily_request_budget", 0)
 
 	// 读取配置文件
 	if err := viper.ReadInConfig(); err != nil {
@@ -169,6 +262,8 @@ func LoadConfig() (*Config, error) {
 		&configObj.FrontendDir,
 		&configObj.Crawler.CookieFile,
 		&configObj.Crawler.OutputDir,
+		&configObj.Crawler.RecordingDir,
+		&configObj.Crawler.ReplayArchive,
 		&configObj.Logging.LogFile,
 	}
 
@@ -180,6 +275,12 @@ func LoadConfig() (*Config, error) {
 		*pathPtr = normalized
 	}
 
+	sinks, err := resolveSinks(configObj.Crawler.Sinks, configObj.Crawler.SaveMode)
+	if err != nil {
+		return nil, err
+	}
+	configObj.Crawler.Sinks = sinks
+
 	// 确保关键目录存在
 	if err := ensureDir(configObj.UserDataDir); err != nil {
 		return nil, fmt.Errorf("failed to create user data dir: %w", err)
@@ -210,7 +311,28 @@ func LoadConfig() (*Config, error) {
 	fmt.Printf("  输出目录: %s\n", configObj.Crawler.OutputDir)
 	fmt.Printf("  工作线程数: %d\n", configObj.Crawler.Workers)
 	fmt.Printf("  最大重试次数: %d\n", configObj.Crawler.MaxTryCount)
+	fmt.Printf("  并发任务数: %d\n", configObj.Crawler.JobWorkers)
+	fmt.Printf("  输出目标: %s\n", strings.Join(configObj.Crawler.Sinks, ", "))
+	fmt.Printf("  增量爬取: %v (到达已入库评论后再翻 %d 页)\n", configObj.Crawler.Incremental, configObj.Crawler.IncrementalExtraPages)
 	fmt.Printf("  下载图片: %t\n", configObj.Crawler.ImgDownload)
+	fmt.Printf("  保存原始响应: %t\n", configObj.Crawler.ArchiveRawPages)
+	if configObj.Crawler.ReplayArchive != "" {
+		fmt.Printf("  流量回放: %s\n", configObj.Crawler.ReplayArchive)
+	} else if configObj.Crawler.RecordTraffic {
+		fmt.Printf("  流量录制: %s\n", configObj.Crawler.RecordingDir)
+	}
+	limits := configObj.Crawler.RateLimits
+	fmt.Printf("  接口限流(次/秒): reply_main=%g, reply_reply=%g, arc_search=%g, view=%g, nav=%g, 突发=%d\n",
+		limits.ReplyMain, limits.ReplyReply, limits.ArcSearch, limits.View, limits.Nav, configObj.Crawler.RateBurst)
+	urls := configObj.Crawler.BaseURLs
+	fmt.Printf("  接口地址: reply_main=%s, reply_reply=%s, arc_search=%s, view=%s, nav=%s\n",
+		urls.ReplyMain, urls.ReplyReply, urls.ArcSearch, urls.View, urls.Nav)
+
+	// 打印定时爬取配置
+	fmt.Printf("定时爬取配置:\n")
+	fmt.Printf("  启用: %v\n", configObj.Scheduler.Enabled)
+	fmt.Printf("  静默时段: %s\n", configObj.Scheduler.QuietHours)
+	fmt.Printf("  每日请求预算: %d\n", configObj.Scheduler.DailyRequestBudget)
 
 	// 设置全局配置
 	cfg = &configObj
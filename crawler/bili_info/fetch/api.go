@@ -2,32 +2,23 @@ package fetch
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
+	"io"
 	"net/url"
-	"time"
 
-	"bilibili-comments-viewer-go/crawler/bili_info/config"
 	"bilibili-comments-viewer-go/crawler/bili_info/model"
 	"bilibili-comments-viewer-go/crawler/bili_info/util"
-	"bilibili-comments-viewer-go/logger"
+	"bilibili-comments-viewer-go/crawler/biliapi"
 )
 
 // API 客户端结构体
 type APIClient struct {
-	HTTPClient *http.Client
-	Cookies    string
+	client *biliapi.Client
 }
 
-// 创建新的 API 客户端
+// 创建新的 API 客户端，相同 Cookie 共用同一个底层客户端
 func NewAPIClient(cookies string) *APIClient {
-	return &APIClient{
-		HTTPClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		Cookies: cookies,
-	}
+	return &APIClient{client: biliapi.ForCookie(cookies)}
 }
 
 // 获取视频信息
@@ -35,66 +26,27 @@ func (c *APIClient) GetVideoInfo(ctx context.Context, bvid string) (*model.Video
 	// 1. 构建请求参数
 	params := url.Values{"bvid": []string{bvid}}
 
-	// 2. 生成带WBI签名的查询字符串
-	query := util.GenerateWBIParams(params)
-	apiURL := fmt.Sprintf("https://api.bilibili.com/x/web-interface/view?%s", query)
-
-	// 3. 创建带上下文的请求
-	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("创建请求失败: %v", err)
-	}
-
-	// 4. 设置请求头
-	util.SetHeaders(req, c.Cookies)
-
-	// 5. 发送请求（带重试机制）
-	var resp *http.Response
-	err = util.Retry(config.MaxRetries, func() error {
-		var reqErr error
-		resp, reqErr = c.HTTPClient.Do(req)
-		if reqErr != nil {
-			return reqErr
-		}
-		if resp.StatusCode >= 500 {
-			// 5xx 服务器错误，重试
-			resp.Body.Close()
-			return fmt.Errorf("服务器错误: %d", resp.StatusCode)
-		}
-		if resp.StatusCode >= 400 {
-			// 4xx 直接失败，不重试
-			return util.PermanentError{Err: fmt.Errorf("客户端错误: %d", resp.StatusCode)}
-		}
-		return nil
-	}, func(err error) bool {
-		// 只要不是PermanentError都可重试
-		if _, ok := err.(util.PermanentError); ok {
-			return false
-		}
-		// 这里可扩展为更细致的错误类型判断
-		return true
-	}, logger.GetLogger())
-
-	if err != nil {
-		return nil, fmt.Errorf("API请求失败: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// 6. 解析API响应
+	// 2. 请求接口（签名、限流、风控检测与重试由 biliapi 处理）
 	var apiResp model.VideoResponse
-	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
-		return nil, fmt.Errorf("解析API响应失败: %v", err)
-	}
-
-	// 7. 检查API错误码
-	if apiResp.Code != 0 {
-		return nil, fmt.Errorf("API错误: %d - %s", apiResp.Code, apiResp.Message)
+	err := c.client.Get(ctx, biliapi.Request{
+		Endpoint: util.EndpointView,
+		Path:     "/x/web-interface/view",
+		Params:   params,
+		Signed:   true,
+	}, &apiResp)
+	if err != nil {
+		return nil, fmt.Errorf("获取视频信息失败: %w", err)
 	}
 
-	// 8. 返回视频信息
+	// 3. 返回视频信息
 	return &model.VideoInfo{
 		BVID:  bvid,
 		Title: apiResp.Data.Title,
 		Cover: apiResp.Data.Pic,
 	}, nil
 }
+
+// DownloadCover 下载封面到 w，与接口请求共用连接
+func (c *APIClient) DownloadCover(ctx context.Context, coverURL string, w io.Writer) error {
+	return c.client.Download(ctx, coverURL, w)
+}
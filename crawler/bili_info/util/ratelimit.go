@@ -0,0 +1,183 @@
+package util
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Endpoint B站接口族，同一接口族共用一个令牌桶
+type Endpoint string
+
+const (
+	EndpointReplyMain  Endpoint = "reply_main"  // 主评论列表与评论总数
+	EndpointReplyReply Endpoint = "reply_reply" // 子评论列表
+	EndpointArcSearch  Endpoint = "arc_search"  // UP主投稿视频列表
+	EndpointView       Endpoint = "view"        // 视频信息
+	EndpointNav        Endpoint = "nav"         // 导航信息（WBI 密钥）
+)
+
+// 默认限流参数（请求/秒），未调用 SetRateLimit 时使用（如命令行模式）
+var defaultRateLimits = map[Endpoint]float64{
+	EndpointReplyMain:  1,
+	EndpointReplyReply: 2,
+	EndpointArcSearch:  0.5,
+	EndpointView:       1,
+	EndpointNav:        1,
+}
+
+const (
+	defaultRateBurst = 2
+	rateWindow       = 10 * time.Second // 统计当前请求速率的时间窗口
+)
+
+// tokenBucket 令牌桶，rate 为 0 时不限流
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // 每秒补充的令牌数
+	burst  float64 // 桶容量
+	tokens float64
+	last   time.Time
+	recent []time.Time // 时间窗口内的请求时间，用于统计当前速率
+}
+
+// reserve 取走一个令牌，返回需要等待的时长；令牌不足时预支，后续请求顺延
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rate <= 0 {
+		return 0
+	}
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel 归还未使用的令牌
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.rate > 0 {
+		b.tokens++
+	}
+}
+
+// record 记录一次已放行的请求
+func (b *tokenBucket) record(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.prune(now)
+	b.recent = append(b.recent, now)
+}
+
+// prune 丢弃时间窗口之前的请求记录，调用方需持有锁
+func (b *tokenBucket) prune(now time.Time) {
+	cutoff := now.Add(-rateWindow)
+	i := 0
+	for i < len(b.recent) && b.recent[i].Before(cutoff) {
+		i++
+	}
+	b.recent = b.recent[i:]
+}
+
+// stat 返回限流参数与时间窗口内的平均请求速率（请求/秒）
+func (b *tokenBucket) stat(ep Endpoint, now time.Time) RateStat {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.prune(now)
+	return RateStat{Endpoint: ep, Limit: b.rate, Burst: int(b.burst),
+		CurrentRate: float64(len(b.recent)) / rateWindow.Seconds()}
+}
+
+var (
+	bucketsMu sync.Mutex
+	buckets   = make(map[Endpoint]*tokenBucket)
+)
+
+// bucketFor 返回接口族的令牌桶，不存在时按默认参数创建
+func bucketFor(ep Endpoint) *tokenBucket {
+	bucketsMu.Lock()
+	defer bucketsMu.Unlock()
+	b, ok := buckets[ep]
+	if !ok {
+		b = &tokenBucket{rate: defaultRateLimits[ep], burst: defaultRateBurst, tokens: defaultRateBurst, last: time.Now()}
+		buckets[ep] = b
+	}
+	return b
+}
+
+// SetRateLimit 设置接口族的限流参数，rate 为每秒请求数（0 为不限流），burst 为允许的突发请求数
+func SetRateLimit(ep Endpoint, rate float64, burst int) {
+	if burst <= 0 {
+		burst = 1
+	}
+	b := bucketFor(ep)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rate = rate
+	b.burst = float64(burst)
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// Acquire 等待熔断器冷却结束与接口族的令牌并记录一次请求，所有发往B站接口的请求（包括重试）发送前调用
+// ctx 取消时返回 ctx.Err()，此时不应发送请求
+func Acquire(ctx context.Context, ep Endpoint) error {
+	if err := WaitBreaker(ctx); err != nil {
+		return err
+	}
+	b := bucketFor(ep)
+	if wait := b.reserve(time.Now()); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			b.cancel()
+			return ctx.Err()
+		}
+	}
+	b.record(time.Now())
+	CountRequest()
+	return nil
+}
+
+// RateStat 接口族的限流参数与当前请求速率
+type RateStat struct {
+	Endpoint    Endpoint `json:"endpoint"`
+	Limit       float64  `json:"limit"` // 每秒请求数上限，0 为不限流
+	Burst       int      `json:"burst"`
+	CurrentRate float64  `json:"current_rate"` // 最近 10 秒的平均每秒请求数
+}
+
+// RateStats 返回所有接口族的限流状态，用于诊断
+func RateStats() []RateStat {
+	for ep := range defaultRateLimits {
+		bucketFor(ep)
+	}
+
+	bucketsMu.Lock()
+	snapshot := make(map[Endpoint]*tokenBucket, len(buckets))
+	for ep, b := range buckets {
+		snapshot[ep] = b
+	}
+	bucketsMu.Unlock()
+
+	now := time.Now()
+	stats := make([]RateStat, 0, len(snapshot))
+	for ep, b := range snapshot {
+		stats = append(stats, b.stat(ep, now))
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Endpoint < stats[j].Endpoint })
+	return stats
+}
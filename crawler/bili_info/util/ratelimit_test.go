@@ -0,0 +1,97 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketReserve(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	type step struct {
+		at   time.Duration // 相对 t0 的请求时间
+		wait time.Duration
+	}
+	for _, tc := range []struct {
+		name  string
+		rate  float64
+		burst float64
+		steps []step
+	}{
+		{
+			name: "不限流", rate: 0, burst: 1,
+			steps: []step{{0, 0}, {0, 0}, {0, 0}},
+		},
+		{
+			name: "突发用尽后按速率排队", rate: 2, burst: 2,
+			// 令牌不足时预支，同一时刻的后续请求依次顺延半秒
+			steps: []step{{0, 0}, {0, 0}, {0, 500 * time.Millisecond}, {0, time.Second}},
+		},
+		{
+			name: "按时间补充令牌", rate: 2, burst: 2,
+			steps: []step{{0, 0}, {0, 0}, {250 * time.Millisecond, 250 * time.Millisecond}, {time.Second, 0}},
+		},
+		{
+			name: "补充不超过桶容量", rate: 2, burst: 2,
+			steps: []step{{0, 0}, {10 * time.Second, 0}, {10 * time.Second, 0}, {10 * time.Second, 500 * time.Millisecond}},
+		},
+		{
+			name: "预支的令牌需先补齐", rate: 1, burst: 1,
+			steps: []step{{0, 0}, {0, time.Second}, {0, 2 * time.Second}, {3 * time.Second, 0}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &tokenBucket{rate: tc.rate, burst: tc.burst, tokens: tc.burst, last: t0}
+			for i, s := range tc.steps {
+				if got := b.reserve(t0.Add(s.at)); got != s.wait {
+					t.Errorf("第 %d 个请求 (t0+%v) 等待 %v, 期望 %v", i+1, s.at, got, s.wait)
+				}
+			}
+		})
+	}
+}
+
+func TestSetRateLimitCapsTokens(t *testing.T) {
+	ep := Endpoint("test_set_rate_limit")
+	SetRateLimit(ep, 1, 5)
+	SetRateLimit(ep, 1, 1)
+	b := bucketFor(ep)
+	if got := b.reserve(b.last); got != 0 {
+		t.Fatalf("第 1 个请求等待 %v, 期望 0", got)
+	}
+	if got := b.reserve(b.last); got != time.Second {
+		t.Errorf("容量缩小为 1 后第 2 个请求等待 %v, 期望 1s", got)
+	}
+}
+
+func TestAcquireCancelledWhileWaiting(t *testing.T) {
+	ep := Endpoint("test_acquire_cancel")
+	SetRateLimit(ep, 0.5, 1)
+
+	before := RequestCount()
+	if err := Acquire(context.Background(), ep); err != nil {
+		t.Fatal(err)
+	}
+
+	// 下一个令牌 2 秒后才补充，等待期间 ctx 超时
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := Acquire(ctx, ep)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire 返回 %v, 期望 context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ctx 取消后 %v 才返回", elapsed)
+	}
+	if got := RequestCount() - before; got != 1 {
+		t.Errorf("请求计数增加 %d, 期望只计入放行的 1 个", got)
+	}
+
+	// 取消的请求归还预支的令牌，之后的请求不因它多等一个周期
+	b := bucketFor(ep)
+	if wait := b.reserve(time.Now()); wait > 2*time.Second {
+		t.Errorf("取消后下一个请求等待 %v, 期望不超过 2s", wait)
+	}
+}
@@ -0,0 +1,16 @@
+package util
+
+import "sync/atomic"
+
+// requestCount 进程启动以来发往B站接口的请求数（不含图片下载）
+var requestCount atomic.Int64
+
+// CountRequest 记录一次B站接口请求，每次发送（包括重试）前调用
+func CountRequest() {
+	requestCount.Add(1)
+}
+
+// RequestCount 返回进程启动以来的B站接口请求数
+func RequestCount() int64 {
+	return requestCount.Load()
+}
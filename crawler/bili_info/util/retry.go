@@ -1,6 +1,7 @@
 package util
 
 import (
+	"context"
 	"math"
 	"math/rand"
 	"time"
@@ -35,6 +36,10 @@ func (e PermanentError) Error() string {
 	return e.Err.Error()
 }
 
+func (e PermanentError) Unwrap() error {
+	return e.Err
+}
+
 // Retry 通用重试封装（支持自定义延迟参数）
 // attempts: 最大重试次数
 // fn: 执行的函数，返回 error
@@ -43,12 +48,23 @@ func (e PermanentError) Error() string {
 // baseDelay: 初始延迟
 // maxDelay: 最大延迟
 func RetryWithBackoff(attempts int, baseDelay, maxDelay time.Duration, fn func() error, isRetryable func(error) bool, logger interface{ Warnf(string, ...interface{}) }) error {
+	return RetryWithBackoffContext(context.Background(), attempts, baseDelay, maxDelay, fn, isRetryable, logger)
+}
+
+// RetryWithBackoffContext 与 RetryWithBackoff 相同，但在 ctx 取消时立即停止重试并返回 ctx.Err()
+func RetryWithBackoffContext(ctx context.Context, attempts int, baseDelay, maxDelay time.Duration, fn func() error, isRetryable func(error) bool, logger interface{ Warnf(string, ...interface{}) }) error {
 	var err error
 	for i := 0; i < attempts; i++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 		err = fn()
 		if err == nil {
 			return nil
 		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 		if !isRetryable(err) {
 			return err
 		}
@@ -58,8 +74,16 @@ func RetryWithBackoff(attempts int, baseDelay, maxDelay time.Duration, fn func()
 			jitterMs = cfg.Crawler.DelayJitterMs
 		}
 		delay := RetryDelay(i, baseDelay, maxDelay) + time.Duration(rand.Int63n(int64(jitterMs)))*time.Millisecond
-		logger.Warnf("请求失败: %v，将在 %v 后重试 (%d/%d)", err, delay, i+1, attempts)
-		time.Sleep(delay)
+		if logger != nil {
+			logger.Warnf("请求失败: %v，将在 %v 后重试 (%d/%d)", err, delay, i+1, attempts)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
 	}
 	return err
 }
@@ -68,3 +92,8 @@ func RetryWithBackoff(attempts int, baseDelay, maxDelay time.Duration, fn func()
 func Retry(attempts int, fn func() error, isRetryable func(error) bool, logger interface{ Warnf(string, ...interface{}) }) error {
 	return RetryWithBackoff(attempts, 2*time.Second, 60*time.Second, fn, isRetryable, logger)
 }
+
+// RetryContext 带上下文的默认参数重试
+func RetryContext(ctx context.Context, attempts int, fn func() error, isRetryable func(error) bool, logger interface{ Warnf(string, ...interface{}) }) error {
+	return RetryWithBackoffContext(ctx, attempts, 2*time.Second, 60*time.Second, fn, isRetryable, logger)
+}
@@ -0,0 +1,168 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"bilibili-comments-viewer-go/logger"
+)
+
+// B站风控相关错误码
+const (
+	CodeRiskControl    = -352 // 风控校验失败
+	CodeRequestBlocked = -412 // 请求被拦截
+)
+
+// RiskControlError 请求触发B站风控（错误码 -352/-412、HTTP 412 或返回验证页面）
+type RiskControlError struct {
+	Endpoint Endpoint
+	Code     int // B站返回的错误码，返回验证页面时为 0
+	Message  string
+}
+
+func (e *RiskControlError) Error() string {
+	return fmt.Sprintf("触发B站风控 (%s, code=%d): %s", e.Endpoint, e.Code, e.Message)
+}
+
+// AsRiskControl 判断错误链中是否包含风控错误
+func AsRiskControl(err error) (*RiskControlError, bool) {
+	var rc *RiskControlError
+	if errors.As(err, &rc) {
+		return rc, true
+	}
+	return nil, false
+}
+
+// CheckRiskControl 根据响应判断是否触发风控，未触发时返回 nil
+// 依次检查 HTTP 412、错误码 -352/-412 以及非 JSON 的验证页面
+func CheckRiskControl(ep Endpoint, status int, contentType string, body []byte) *RiskControlError {
+	if status == http.StatusPreconditionFailed {
+		return &RiskControlError{Endpoint: ep, Code: CodeRequestBlocked, Message: "HTTP 412"}
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var resp struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(trimmed, &resp); err == nil && (resp.Code == CodeRiskControl || resp.Code == CodeRequestBlocked) {
+			return &RiskControlError{Endpoint: ep, Code: resp.Code, Message: resp.Message}
+		}
+		return nil
+	}
+
+	if status < 400 && (strings.Contains(contentType, "text/html") || len(trimmed) > 0) {
+		return &RiskControlError{Endpoint: ep, Message: "返回了非 JSON 的验证页面"}
+	}
+	return nil
+}
+
+// 熔断器冷却时间：首次触发等待 breakerBaseCooldown，连续触发时翻倍，最长 breakerMaxCooldown
+const (
+	breakerBaseCooldown = time.Minute
+	breakerMaxCooldown  = 30 * time.Minute
+)
+
+// circuitBreaker 全局熔断器，触发风控后暂停所有B站接口请求
+type circuitBreaker struct {
+	mu        sync.Mutex
+	openUntil time.Time
+	cooldown  time.Duration // 最近一次的冷却时间
+	level     int           // 连续触发次数
+	trips     int64
+	lastCause string
+	lastTrip  time.Time
+}
+
+var breaker circuitBreaker
+
+// TripBreaker 风控触发后打开熔断器，返回剩余冷却时间
+// 熔断器已打开时（并发请求同时被风控）不再延长冷却时间
+func TripBreaker(rc *RiskControlError) time.Duration {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(breaker.openUntil) {
+		return breaker.openUntil.Sub(now)
+	}
+
+	breaker.level++
+	cooldown := breakerBaseCooldown << (breaker.level - 1)
+	if cooldown > breakerMaxCooldown || cooldown <= 0 {
+		cooldown = breakerMaxCooldown
+	}
+	breaker.cooldown = cooldown
+	breaker.openUntil = now.Add(cooldown)
+	breaker.trips++
+	breaker.lastCause = rc.Error()
+	breaker.lastTrip = now
+
+	logger.GetLogger().Warnf("%v，暂停所有B站接口请求 %v（第%d次连续触发）", rc, cooldown, breaker.level)
+	return cooldown
+}
+
+// ReportSuccess 记录一次正常响应；熔断器关闭后持续正常一个冷却周期，连续触发次数清零
+func ReportSuccess() {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	if breaker.level > 0 && time.Now().After(breaker.openUntil.Add(breaker.cooldown)) {
+		breaker.level = 0
+	}
+}
+
+// WaitBreaker 熔断器打开时等待冷却结束，ctx 取消时返回 ctx.Err()
+func WaitBreaker(ctx context.Context) error {
+	for {
+		breaker.mu.Lock()
+		wait := time.Until(breaker.openUntil)
+		breaker.mu.Unlock()
+		if wait <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// BreakerStatus 熔断器状态
+type BreakerStatus struct {
+	Open      bool       `json:"open"`
+	OpenUntil *time.Time `json:"open_until,omitempty"`
+	Level     int        `json:"level"` // 连续触发次数
+	Trips     int64      `json:"trips"` // 进程启动以来的触发次数
+	LastCause string     `json:"last_cause,omitempty"`
+	LastTrip  *time.Time `json:"last_trip,omitempty"`
+}
+
+// GetBreakerStatus 返回熔断器状态，用于诊断
+func GetBreakerStatus() BreakerStatus {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+
+	status := BreakerStatus{Level: breaker.level, Trips: breaker.trips, LastCause: breaker.lastCause}
+	if time.Now().Before(breaker.openUntil) {
+		until := breaker.openUntil
+		status.Open = true
+		status.OpenUntil = &until
+	}
+	if !breaker.lastTrip.IsZero() {
+		last := breaker.lastTrip
+		status.LastTrip = &last
+	}
+	return status
+}
@@ -0,0 +1,167 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"bilibili-comments-viewer-go/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitLogger("", "error", 1, 1, 1)
+	os.Exit(m.Run())
+}
+
+func TestCheckRiskControl(t *testing.T) {
+	const riskPage = `<!DOCTYPE html><html><head><title>验证码</title></head><body>请完成安全验证</body></html>`
+	for _, tc := range []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		wantRisk    bool
+		wantCode    int
+	}{
+		{"错误码 -352", http.StatusOK, "application/json", `{"code":-352,"message":"风控校验失败"}`, true, CodeRiskControl},
+		{"错误码 -412", http.StatusOK, "application/json", `{"code":-412,"message":"请求被拦截"}`, true, CodeRequestBlocked},
+		{"HTTP 412", http.StatusPreconditionFailed, "application/json", `{"code":0}`, true, CodeRequestBlocked},
+		{"HTML 验证页面", http.StatusOK, "text/html; charset=utf-8", riskPage, true, 0},
+		{"未声明类型的验证页面", http.StatusOK, "", riskPage, true, 0},
+		{"正常响应", http.StatusOK, "application/json", `{"code":0,"data":{}}`, false, 0},
+		{"前导空白的正常响应", http.StatusOK, "application/json", " \n{\"code\":0}", false, 0},
+		{"其他错误码", http.StatusOK, "application/json", `{"code":-404,"message":"啥都木有"}`, false, 0},
+		{"服务器错误页面", http.StatusBadGateway, "text/html", "<html>502</html>", false, 0},
+		{"空响应", http.StatusOK, "application/json", "", false, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rc := CheckRiskControl(EndpointReplyMain, tc.status, tc.contentType, []byte(tc.body))
+			if (rc != nil) != tc.wantRisk {
+				t.Fatalf("CheckRiskControl = %v, 期望风控: %v", rc, tc.wantRisk)
+			}
+			if rc == nil {
+				return
+			}
+			if rc.Code != tc.wantCode || rc.Endpoint != EndpointReplyMain {
+				t.Errorf("code = %d, endpoint = %s, 期望 %d, %s", rc.Code, rc.Endpoint, tc.wantCode, EndpointReplyMain)
+			}
+			// 经过多层包装后仍能识别
+			wrapped := fmt.Errorf("获取评论失败: %w", fmt.Errorf("第 3 页: %w", rc))
+			if got, ok := AsRiskControl(wrapped); !ok || got != rc {
+				t.Errorf("AsRiskControl(%v) = %v, %v", wrapped, got, ok)
+			}
+		})
+	}
+
+	if _, ok := AsRiskControl(fmt.Errorf("网络错误")); ok {
+		t.Error("普通错误不应识别为风控")
+	}
+}
+
+// resetBreaker 关闭熔断器并清零状态，测试结束时同样重置，避免其他测试等待冷却
+func resetBreaker(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		breaker.mu.Lock()
+		defer breaker.mu.Unlock()
+		breaker.openUntil = time.Time{}
+		breaker.cooldown = 0
+		breaker.level = 0
+		breaker.trips = 0
+		breaker.lastCause = ""
+		breaker.lastTrip = time.Time{}
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+// expireBreaker 将冷却结束时间提前到 ago 之前，模拟冷却已过去
+func expireBreaker(ago time.Duration) {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	breaker.openUntil = time.Now().Add(-ago)
+}
+
+func TestBreakerCooldownDoubles(t *testing.T) {
+	resetBreaker(t)
+	rc := &RiskControlError{Endpoint: EndpointReplyMain, Code: CodeRiskControl, Message: "风控校验失败"}
+
+	want := []time.Duration{
+		time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 16 * time.Minute,
+		30 * time.Minute, 30 * time.Minute, // 不超过 breakerMaxCooldown
+	}
+	for i, w := range want {
+		if got := TripBreaker(rc); got != w {
+			t.Fatalf("第 %d 次连续触发冷却 %v, 期望 %v", i+1, got, w)
+		}
+		status := GetBreakerStatus()
+		if !status.Open || status.Level != i+1 || status.Trips != int64(i+1) || status.LastCause != rc.Error() {
+			t.Fatalf("第 %d 次触发后状态 %+v", i+1, status)
+		}
+
+		// 冷却期间并发请求再次被风控不延长冷却，也不计入连续触发
+		if got := TripBreaker(rc); got > w || got < w-time.Second {
+			t.Errorf("冷却期间再次触发返回 %v, 期望剩余约 %v", got, w)
+		}
+		if status := GetBreakerStatus(); status.Level != i+1 || status.Trips != int64(i+1) {
+			t.Errorf("冷却期间再次触发后 level = %d, trips = %d, 期望 %d", status.Level, status.Trips, i+1)
+		}
+
+		// 冷却刚结束、尚未稳定一个周期时的正常响应不清零连续触发次数
+		expireBreaker(0)
+		ReportSuccess()
+	}
+}
+
+func TestBreakerLevelResetsAfterStablePeriod(t *testing.T) {
+	resetBreaker(t)
+	rc := &RiskControlError{Endpoint: EndpointReplyReply, Code: CodeRequestBlocked, Message: "HTTP 412"}
+
+	TripBreaker(rc)
+	expireBreaker(0)
+	if got := TripBreaker(rc); got != 2*time.Minute {
+		t.Fatalf("第 2 次连续触发冷却 %v, 期望 2m", got)
+	}
+
+	// 关闭后持续正常超过一个冷却周期，下次触发从基础冷却时间重新开始
+	expireBreaker(2*time.Minute + time.Second)
+	ReportSuccess()
+	if status := GetBreakerStatus(); status.Open || status.Level != 0 {
+		t.Fatalf("稳定一个周期后状态 %+v, 期望已关闭且 level 为 0", status)
+	}
+	if got := TripBreaker(rc); got != time.Minute {
+		t.Errorf("清零后触发冷却 %v, 期望 1m", got)
+	}
+}
+
+func TestWaitBreaker(t *testing.T) {
+	resetBreaker(t)
+	if err := WaitBreaker(context.Background()); err != nil {
+		t.Fatalf("熔断器关闭时 WaitBreaker 返回 %v", err)
+	}
+
+	TripBreaker(&RiskControlError{Endpoint: EndpointReplyMain, Code: CodeRiskControl})
+
+	// 冷却期间 ctx 取消时立即返回
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := WaitBreaker(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("冷却期间 WaitBreaker 返回 %v, 期望 context.DeadlineExceeded", err)
+	}
+
+	// 冷却结束时返回
+	breaker.mu.Lock()
+	breaker.openUntil = time.Now().Add(50 * time.Millisecond)
+	breaker.mu.Unlock()
+	start := time.Now()
+	if err := WaitBreaker(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("冷却结束前 %v 就返回", elapsed)
+	}
+}
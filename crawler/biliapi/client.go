@@ -0,0 +1,307 @@
+// Package biliapi 封装对 B 站接口的访问：共享连接池、统一请求头与 Cookie、
+// WBI 签名、限流、风控检测、重试以及 JSON 解析都在这里完成
+package biliapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"bilibili-comments-viewer-go/crawler/bili_info/config"
+	"bilibili-comments-viewer-go/crawler/bili_info/util"
+	"bilibili-comments-viewer-go/logger"
+)
+
+const (
+	DefaultBaseURL = "https://api.bilibili.com"
+
+	UserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36 Edg/125.0.0.0"
+	Origin    = "https://www.bilibili.com"
+	Referer   = "https://www.bilibili.com/"
+
+	requestTimeout = 30 * time.Second
+)
+
+// sharedTransport 所有客户端共用的连接池，保证不同任务、不同接口之间复用连接
+var sharedTransport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          32,
+	MaxIdleConnsPerHost:   8,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: time.Second,
+}
+
+// baseURLs 各接口族的服务地址，未设置时使用 DefaultBaseURL
+var baseURLs = struct {
+	sync.RWMutex
+	m map[util.Endpoint]string
+}{m: map[util.Endpoint]string{}}
+
+// SetBaseURL 设置接口族的服务地址（如指向测试用的模拟服务器），base 为空时恢复默认地址
+func SetBaseURL(ep util.Endpoint, base string) {
+	base = strings.TrimRight(strings.TrimSpace(base), "/")
+	baseURLs.Lock()
+	changed := baseURLs.m[ep] != base
+	if base == "" {
+		delete(baseURLs.m, ep)
+	} else {
+		baseURLs.m[ep] = base
+	}
+	baseURLs.Unlock()
+
+	if ep == util.EndpointNav && changed {
+		invalidateWbiKeys() // 不同服务器的 WBI 密钥不同
+	}
+}
+
+// BaseURL 返回接口族当前使用的服务地址
+func BaseURL(ep util.Endpoint) string {
+	baseURLs.RLock()
+	defer baseURLs.RUnlock()
+	if base, ok := baseURLs.m[ep]; ok {
+		return base
+	}
+	return DefaultBaseURL
+}
+
+// Client B站接口客户端，可长期持有并在多个 goroutine 间共享
+type Client struct {
+	httpClient *http.Client
+	cookie     string
+}
+
+// New 创建使用指定 Cookie 的客户端，底层连接池与 WBI 密钥缓存在所有客户端间共享
+func New(cookie string) *Client {
+	return &Client{
+		httpClient: &http.Client{Transport: sharedTransport, Timeout: requestTimeout},
+		cookie:     strings.TrimSpace(cookie),
+	}
+}
+
+var clients sync.Map // cookie -> *Client
+
+// ForCookie 返回指定 Cookie 对应的客户端，相同 Cookie 复用同一个实例
+func ForCookie(cookie string) *Client {
+	cookie = strings.TrimSpace(cookie)
+	if c, ok := clients.Load(cookie); ok {
+		return c.(*Client)
+	}
+	c, _ := clients.LoadOrStore(cookie, New(cookie))
+	return c.(*Client)
+}
+
+// httpClientFor 返回本次请求使用的 HTTP 客户端：ctx 中设置了回放存档时不访问网络，设置了录制时记录请求与响应
+func (c *Client) httpClientFor(ctx context.Context) *http.Client {
+	if rp := replayerFrom(ctx); rp != nil {
+		return &http.Client{Transport: rp, Timeout: requestTimeout}
+	}
+	if rec := recorderFrom(ctx); rec != nil {
+		return &http.Client{Transport: &recordingTransport{base: sharedTransport, rec: rec}, Timeout: requestTimeout}
+	}
+	return c.httpClient
+}
+
+// Request 一次接口调用
+type Request struct {
+	Endpoint util.Endpoint // 所属接口族，用于限流与风控统计
+	Path     string        // 接口路径，如 /x/v2/reply/count，服务地址由 Endpoint 决定
+	Params   url.Values
+	Signed   bool        // 是否需要 WBI 签名
+	Header   http.Header // 额外的请求头，覆盖默认值
+}
+
+// APIError 接口返回了非 0 的业务错误码
+type APIError struct {
+	Endpoint util.Endpoint
+	Code     int
+	Message  string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("B站接口返回错误 (%s, code=%d): %s", e.Endpoint, e.Code, e.Message)
+}
+
+// AsAPIError 判断错误链中是否包含业务错误码
+func AsAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	return nil, false
+}
+
+// envelope B站接口统一的响应外层
+type envelope struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Get 请求接口并把响应解析到 out（out 为 nil 时只校验错误码）
+// 错误码非 0 时返回 *APIError，触发风控时返回 *util.RiskControlError
+func (c *Client) Get(ctx context.Context, req Request, out any) error {
+	_, err := c.GetBody(ctx, req, out)
+	return err
+}
+
+// GetBody 与 Get 相同，同时返回原始响应体，供需要存档原始响应的调用方使用
+func (c *Client) GetBody(ctx context.Context, req Request, out any) ([]byte, error) {
+	body, err := c.Raw(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var env envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if env.Code != 0 {
+		return nil, &APIError{Endpoint: req.Endpoint, Code: env.Code, Message: env.Message}
+	}
+	if out != nil {
+		if err := json.Unmarshal(body, out); err != nil {
+			return nil, fmt.Errorf("解析响应失败: %w", err)
+		}
+	}
+
+	if replayerFrom(ctx) == nil {
+		util.ReportSuccess()
+	}
+	return body, nil
+}
+
+// Raw 请求接口并返回原始响应体，不检查业务错误码
+// 每次尝试都会经过限流与熔断器；5xx 与网络错误按 config.MaxRetries 重试，4xx 与风控不重试
+// 回放时签名参数不参与匹配，因此跳过签名、限流与熔断器，回放的风控响应也不会触发熔断
+func (c *Client) Raw(ctx context.Context, req Request) ([]byte, error) {
+	replaying := replayerFrom(ctx) != nil
+	httpClient := c.httpClientFor(ctx)
+
+	query := cloneValues(req.Params)
+	if req.Signed && !replaying {
+		keys, err := wbiKeys(ctx, c)
+		if err != nil {
+			return nil, fmt.Errorf("获取WBI密钥失败: %w", err)
+		}
+		query = keys.sign(query, time.Now())
+	}
+	rawURL := BaseURL(req.Endpoint) + req.Path
+	if encoded := encodeQuery(query); encoded != "" {
+		rawURL += "?" + encoded
+	}
+
+	var body []byte
+	err := util.RetryContext(ctx, config.MaxRetries, func() error {
+		if !replaying {
+			if err := util.Acquire(ctx, req.Endpoint); err != nil {
+				return err
+			}
+		}
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return util.PermanentError{Err: fmt.Errorf("创建请求失败: %w", err)}
+		}
+		c.setHeaders(httpReq, req.Header)
+
+		resp, err := httpClient.Do(httpReq)
+		if errors.Is(err, ErrNotRecorded) {
+			return util.PermanentError{Err: err}
+		}
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if body, err = io.ReadAll(resp.Body); err != nil {
+			return fmt.Errorf("读取响应失败: %w", err)
+		}
+
+		if rc := util.CheckRiskControl(req.Endpoint, resp.StatusCode, resp.Header.Get("Content-Type"), body); rc != nil {
+			if replaying {
+				return util.PermanentError{Err: rc}
+			}
+			if req.Signed {
+				invalidateWbiKeys() // 密钥过期也会导致 -352，下次请求重新获取
+			}
+			util.TripBreaker(rc)
+			return util.PermanentError{Err: rc}
+		}
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("服务器错误: %d", resp.StatusCode)
+		}
+		if resp.StatusCode >= 400 {
+			return util.PermanentError{Err: fmt.Errorf("客户端错误: %d", resp.StatusCode)}
+		}
+		return nil
+	}, func(err error) bool {
+		var permanent util.PermanentError
+		return !errors.As(err, &permanent)
+	}, logger.GetLogger())
+	if err != nil {
+		return nil, fmt.Errorf("请求 %s 失败: %w", req.Path, err)
+	}
+
+	logger.GetLogger().Debugf("B站接口响应: %s, %d 字节", req.Path, len(body))
+	return body, nil
+}
+
+// Download 下载图片等静态资源并写入 w，与接口请求共用连接池和请求头
+func (c *Client) Download(ctx context.Context, rawURL string, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	c.setHeaders(req, nil)
+
+	resp, err := c.httpClientFor(ctx).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("下载失败，状态码: %d", resp.StatusCode)
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("写入失败: %w", err)
+	}
+	return nil
+}
+
+// setHeaders 设置模拟浏览器的默认请求头，extra 中的同名请求头会覆盖默认值
+func (c *Client) setHeaders(req *http.Request, extra http.Header) {
+	req.Header.Set("Accept", "application/json, text/plain, */*")
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("Origin", Origin)
+	req.Header.Set("Referer", Referer)
+	if c.cookie != "" {
+		req.Header.Set("Cookie", c.cookie)
+	}
+	for k, v := range extra {
+		req.Header[http.CanonicalHeaderKey(k)] = v
+	}
+}
+
+func cloneValues(v url.Values) url.Values {
+	out := make(url.Values, len(v))
+	for k, vs := range v {
+		out[k] = append([]string(nil), vs...)
+	}
+	return out
+}
+
+// encodeQuery 按键名排序编码查询参数，空格编码为 %20，与 WBI 签名时的编码保持一致
+func encodeQuery(v url.Values) string {
+	return strings.ReplaceAll(v.Encode(), "+", "%20")
+}
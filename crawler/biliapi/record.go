@@ -0,0 +1,340 @@
+package biliapi
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// 流量录制与回放：录制时把每次请求/响应写入 .tar.zst 存档，回放时由存档代替网络返回响应，
+// 用于复现解析问题。存档中每次交互对应两个文件：
+//
+//	000001.json  请求方法、去除敏感参数后的 URL、状态码与请求/响应头
+//	000001.body  原始响应体
+
+// secretParams 录制时从 URL 中去除的查询参数；WBI 签名参数随时间变化，同样不参与回放匹配
+var secretParams = []string{"w_rid", "wts", "access_key", "csrf", "appkey", "sign"}
+
+// secretHeaders 录制时去除的请求头与响应头
+var secretHeaders = []string{"Cookie", "Authorization", "Set-Cookie"}
+
+// ErrNotRecorded 回放存档中没有与请求匹配的响应
+var ErrNotRecorded = errors.New("回放存档中没有该请求")
+
+// Exchange 存档中一次请求与响应的元数据
+type Exchange struct {
+	Seq           int         `json:"seq"`
+	Time          time.Time   `json:"time"`
+	Method        string      `json:"method"`
+	URL           string      `json:"url"`
+	RequestHeader http.Header `json:"request_header,omitempty"`
+	Status        int         `json:"status,omitempty"`
+	Header        http.Header `json:"header,omitempty"`
+	Error         string      `json:"error,omitempty"` // 网络错误，此时没有响应
+}
+
+// Recorder 把请求与响应写入压缩存档，可在多个 goroutine 间共享
+type Recorder struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	zw   *zstd.Encoder
+	tw   *tar.Writer
+	seq  int
+	err  error // 首次写入错误，之后不再写入
+}
+
+// NewRecorder 创建录制存档，所在目录不存在时自动创建
+func NewRecorder(path string) (*Recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建录制目录失败: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("创建录制存档失败: %w", err)
+	}
+	zw, err := zstd.NewWriter(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("创建录制存档失败: %w", err)
+	}
+	return &Recorder{path: path, file: f, zw: zw, tw: tar.NewWriter(zw)}, nil
+}
+
+// Path 存档路径
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// Count 已录制的交互数
+func (r *Recorder) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.seq
+}
+
+// Close 写完存档并关闭文件，返回录制过程中的首个写入错误
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tw == nil {
+		return r.err
+	}
+	errs := []error{r.err, r.tw.Close(), r.zw.Close(), r.file.Close()}
+	r.tw = nil
+	return errors.Join(errs...)
+}
+
+// record 写入一次交互；resp 为 nil 时记录网络错误
+func (r *Recorder) record(req *http.Request, resp *http.Response, body []byte, netErr error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tw == nil || r.err != nil {
+		return
+	}
+
+	r.seq++
+	ex := Exchange{
+		Seq:           r.seq,
+		Time:          time.Now(),
+		Method:        req.Method,
+		URL:           redactURL(req.URL),
+		RequestHeader: redactHeader(req.Header),
+	}
+	if netErr != nil {
+		ex.Error = netErr.Error()
+	} else {
+		ex.Status = resp.StatusCode
+		ex.Header = redactHeader(resp.Header)
+	}
+
+	meta, err := json.MarshalIndent(ex, "", "  ")
+	if err != nil {
+		r.err = fmt.Errorf("写入录制存档失败: %w", err)
+		return
+	}
+	name := fmt.Sprintf("%06d", r.seq)
+	if err := r.writeFile(name+".json", meta, ex.Time); err != nil {
+		r.err = err
+		return
+	}
+	if err := r.writeFile(name+".body", body, ex.Time); err != nil {
+		r.err = err
+	}
+}
+
+func (r *Recorder) writeFile(name string, data []byte, modTime time.Time) error {
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: modTime, Typeflag: tar.TypeReg}
+	if err := r.tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("写入录制存档失败: %w", err)
+	}
+	if _, err := r.tw.Write(data); err != nil {
+		return fmt.Errorf("写入录制存档失败: %w", err)
+	}
+	return nil
+}
+
+// recordingTransport 透传请求并把完整的响应写入 Recorder
+type recordingTransport struct {
+	base http.RoundTripper
+	rec  *Recorder
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		t.rec.record(req, nil, nil, err)
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.rec.record(req, nil, nil, err)
+		return nil, err
+	}
+	t.rec.record(req, resp, body, nil)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// recordedResponse 存档中的一次交互
+type recordedResponse struct {
+	Exchange
+	body []byte
+}
+
+// Replayer 用录制存档代替网络返回响应
+// 请求按方法、路径和去除敏感参数后的查询参数匹配，不比较服务地址；
+// 相同请求（如重试）按录制顺序依次返回
+type Replayer struct {
+	mu        sync.Mutex
+	responses map[string][]*recordedResponse
+	remaining int
+}
+
+// OpenReplay 读取录制存档
+func OpenReplay(path string) (*Replayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开回放存档失败: %w", err)
+	}
+	defer f.Close()
+	zr, err := zstd.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("打开回放存档失败: %w", err)
+	}
+	defer zr.Close()
+
+	metas := map[string]*Exchange{}
+	bodies := map[string][]byte{}
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取回放存档失败: %w", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("读取回放存档失败: %w", err)
+		}
+		name, ext := strings.TrimSuffix(hdr.Name, filepath.Ext(hdr.Name)), filepath.Ext(hdr.Name)
+		switch ext {
+		case ".json":
+			var ex Exchange
+			if err := json.Unmarshal(data, &ex); err != nil {
+				return nil, fmt.Errorf("解析回放存档 %s 失败: %w", hdr.Name, err)
+			}
+			metas[name] = &ex
+		case ".body":
+			bodies[name] = data
+		}
+	}
+
+	all := make([]*recordedResponse, 0, len(metas))
+	for name, ex := range metas {
+		all = append(all, &recordedResponse{Exchange: *ex, body: bodies[name]})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Seq < all[j].Seq })
+
+	rp := &Replayer{responses: map[string][]*recordedResponse{}, remaining: len(all)}
+	for _, r := range all {
+		u, err := url.Parse(r.URL)
+		if err != nil {
+			return nil, fmt.Errorf("解析回放存档 URL 失败: %w", err)
+		}
+		key := replayKey(r.Method, u)
+		rp.responses[key] = append(rp.responses[key], r)
+	}
+	return rp, nil
+}
+
+// Remaining 尚未被请求的录制响应数
+func (rp *Replayer) Remaining() int {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	return rp.remaining
+}
+
+// RoundTrip 返回与请求匹配的下一条录制响应
+func (rp *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := replayKey(req.Method, req.URL)
+	rp.mu.Lock()
+	queue := rp.responses[key]
+	if len(queue) == 0 {
+		rp.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrNotRecorded, key)
+	}
+	r := queue[0]
+	rp.responses[key] = queue[1:]
+	rp.remaining--
+	rp.mu.Unlock()
+
+	if r.Error != "" {
+		return nil, fmt.Errorf("回放录制的网络错误: %s", r.Error)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+		StatusCode:    r.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(r.body)),
+		ContentLength: int64(len(r.body)),
+		Request:       req,
+	}, nil
+}
+
+type recorderCtxKey struct{}
+type replayerCtxKey struct{}
+
+// WithRecorder 返回在 ctx 范围内录制所有接口请求的上下文
+func WithRecorder(ctx context.Context, rec *Recorder) context.Context {
+	return context.WithValue(ctx, recorderCtxKey{}, rec)
+}
+
+// WithReplay 返回在 ctx 范围内从存档回放所有接口请求、不访问网络的上下文
+func WithReplay(ctx context.Context, rp *Replayer) context.Context {
+	return context.WithValue(ctx, replayerCtxKey{}, rp)
+}
+
+func recorderFrom(ctx context.Context) *Recorder {
+	rec, _ := ctx.Value(recorderCtxKey{}).(*Recorder)
+	return rec
+}
+
+func replayerFrom(ctx context.Context) *Replayer {
+	rp, _ := ctx.Value(replayerCtxKey{}).(*Replayer)
+	return rp
+}
+
+// redactURL 去除 URL 中的敏感参数
+func redactURL(u *url.URL) string {
+	clean := *u
+	clean.User = nil
+	clean.RawQuery = redactQuery(u.Query())
+	return clean.String()
+}
+
+// redactQuery 去除敏感参数后按键名排序编码
+func redactQuery(q url.Values) string {
+	for _, k := range secretParams {
+		q.Del(k)
+	}
+	return encodeQuery(q)
+}
+
+// redactHeader 复制请求头并去除敏感项
+func redactHeader(h http.Header) http.Header {
+	out := h.Clone()
+	for _, k := range secretHeaders {
+		out.Del(k)
+	}
+	return out
+}
+
+// replayKey 回放匹配键：方法、路径与去除敏感参数后的查询参数
+func replayKey(method string, u *url.URL) string {
+	key := method + " " + u.Path
+	if q := redactQuery(u.Query()); q != "" {
+		key += "?" + q
+	}
+	return key
+}
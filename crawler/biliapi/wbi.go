@@ -0,0 +1,177 @@
+package biliapi
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bilibili-comments-viewer-go/crawler/bili_info/util"
+	"bilibili-comments-viewer-go/logger"
+)
+
+// WBI 签名：https://github.com/SocialSisterYi/bilibili-API-collect/blob/master/docs/misc/sign/wbi.md
+
+var mixinKeyEncTab = []int{
+	46, 47, 18, 2, 53, 8, 23, 32, 15, 50, 10, 31, 58, 3, 45, 35, 27, 43, 5, 49,
+	33, 9, 42, 19, 29, 28, 14, 39, 12, 38, 41, 13, 37, 48, 7, 16, 24, 55, 40,
+	61, 26, 17, 0, 1, 60, 51, 30, 4, 22, 25, 54, 21, 56, 59, 6, 63, 57, 62, 11,
+	36, 20, 34, 44, 52,
+}
+
+// wbiKeyTTL WBI 密钥每天轮换，缓存一段时间后重新获取
+const wbiKeyTTL = 10 * time.Minute
+
+// wbiKeyPair 从导航接口获取的 img_key 与 sub_key
+type wbiKeyPair struct {
+	ImgKey string
+	SubKey string
+}
+
+// mixinKey 按混淆表从 img_key+sub_key 中取出 32 位签名密钥
+func (k wbiKeyPair) mixinKey() string {
+	orig := k.ImgKey + k.SubKey
+	var b strings.Builder
+	for _, v := range mixinKeyEncTab {
+		if v < len(orig) {
+			b.WriteByte(orig[v])
+		}
+	}
+	s := b.String()
+	if len(s) > 32 {
+		s = s[:32]
+	}
+	return s
+}
+
+// sign 为参数加上 wts 与 w_rid，返回新的参数集合
+func (k wbiKeyPair) sign(params url.Values, now time.Time) url.Values {
+	signed := make(url.Values, len(params)+2)
+	for key := range params {
+		signed.Set(key, sanitizeWbiValue(params.Get(key)))
+	}
+	signed.Set("wts", strconv.FormatInt(now.Unix(), 10))
+
+	hash := md5.Sum([]byte(encodeQuery(signed) + k.mixinKey()))
+	signed.Set("w_rid", hex.EncodeToString(hash[:]))
+	return signed
+}
+
+// sanitizeWbiValue 移除参数值中的 !'()* 字符，与网页端签名时的处理一致
+func sanitizeWbiValue(s string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune("!'()*", r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// wbiKeyCache 所有客户端共享的 WBI 密钥缓存
+// 刷新在锁外进行，同一时间只有一个请求刷新，其余请求等待刷新结束或自身 ctx 取消
+var wbiKeyCache struct {
+	mu         sync.Mutex
+	keys       wbiKeyPair
+	fetchedAt  time.Time
+	generation int           // 每次使密钥过期时递增，过期前发起的刷新结果不再写入缓存
+	refreshing chan struct{} // 正在刷新时非空，刷新结束后关闭
+}
+
+// wbiKeys 返回缓存的密钥，过期时通过导航接口刷新；刷新失败但有旧密钥时继续使用旧密钥
+func wbiKeys(ctx context.Context, c *Client) (wbiKeyPair, error) {
+	waited := false // 是否等待过其他请求的刷新，刷新失败时直接使用旧密钥而不是再次刷新
+	for {
+		wbiKeyCache.mu.Lock()
+		cached := wbiKeyCache.keys
+		if cached.ImgKey != "" && (waited || time.Since(wbiKeyCache.fetchedAt) < wbiKeyTTL) {
+			wbiKeyCache.mu.Unlock()
+			return cached, nil
+		}
+		if wait := wbiKeyCache.refreshing; wait != nil {
+			wbiKeyCache.mu.Unlock()
+			select {
+			case <-wait:
+				waited = true
+				continue
+			case <-ctx.Done():
+				return wbiKeyPair{}, ctx.Err()
+			}
+		}
+		done := make(chan struct{})
+		wbiKeyCache.refreshing = done
+		generation := wbiKeyCache.generation
+		wbiKeyCache.mu.Unlock()
+
+		keys, err := c.fetchWbiKeys(ctx)
+
+		wbiKeyCache.mu.Lock()
+		wbiKeyCache.refreshing = nil
+		close(done)
+		if err == nil && generation == wbiKeyCache.generation {
+			wbiKeyCache.keys = keys
+			wbiKeyCache.fetchedAt = time.Now()
+		}
+		cached = wbiKeyCache.keys
+		wbiKeyCache.mu.Unlock()
+
+		if err != nil {
+			if cached.ImgKey != "" {
+				logger.GetLogger().Warnf("刷新WBI密钥失败，继续使用旧密钥: %v", err)
+				return cached, nil
+			}
+			return wbiKeyPair{}, err
+		}
+		return keys, nil
+	}
+}
+
+// invalidateWbiKeys 使缓存的密钥过期，下次签名时重新获取
+func invalidateWbiKeys() {
+	wbiKeyCache.mu.Lock()
+	wbiKeyCache.fetchedAt = time.Time{}
+	wbiKeyCache.generation++
+	wbiKeyCache.mu.Unlock()
+}
+
+// fetchWbiKeys 请求导航接口获取密钥；未登录时接口返回 -101，但仍会返回 wbi_img
+func (c *Client) fetchWbiKeys(ctx context.Context) (wbiKeyPair, error) {
+	body, err := c.Raw(ctx, Request{Endpoint: util.EndpointNav, Path: "/x/web-interface/nav"})
+	if err != nil {
+		return wbiKeyPair{}, err
+	}
+	var nav struct {
+		Data struct {
+			WbiImg struct {
+				ImgURL string `json:"img_url"`
+				SubURL string `json:"sub_url"`
+			} `json:"wbi_img"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &nav); err != nil {
+		return wbiKeyPair{}, fmt.Errorf("解析导航接口响应失败: %w", err)
+	}
+	keys := wbiKeyPair{
+		ImgKey: keyFromURL(nav.Data.WbiImg.ImgURL),
+		SubKey: keyFromURL(nav.Data.WbiImg.SubURL),
+	}
+	if keys.ImgKey == "" || keys.SubKey == "" {
+		return wbiKeyPair{}, fmt.Errorf("导航接口未返回WBI密钥")
+	}
+	return keys, nil
+}
+
+// keyFromURL 取出 https://i0.hdslb.com/bfs/wbi/<key>.png 中的 key
+func keyFromURL(u string) string {
+	base := path.Base(u)
+	if base == "." || base == "/" {
+		return ""
+	}
+	return strings.TrimSuffix(base, path.Ext(base))
+}
@@ -0,0 +1,66 @@
+package biliapi
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// 签名文档中的示例密钥：https://github.com/SocialSisterYi/bilibili-API-collect/blob/master/docs/misc/sign/wbi.md
+var docKeys = wbiKeyPair{
+	ImgKey: "7cd084941338484aae1ad9425b84077c",
+	SubKey: "4932caff0ff746eab6f01bf08b70ac45",
+}
+
+func TestWbiMixinKey(t *testing.T) {
+	if got, want := docKeys.mixinKey(), "ea1db124af3c7062474693fa704f4ff8"; got != want {
+		t.Errorf("mixinKey = %s, 期望 %s", got, want)
+	}
+}
+
+func TestWbiSign(t *testing.T) {
+	now := time.Unix(1702204169, 0)
+	for _, tc := range []struct {
+		name   string
+		params url.Values
+		want   url.Values
+	}{
+		{
+			name:   "文档示例",
+			params: url.Values{"foo": {"114"}, "bar": {"514"}, "zab": {"1919810"}},
+			want: url.Values{"foo": {"114"}, "bar": {"514"}, "zab": {"1919810"},
+				"wts": {"1702204169"}, "w_rid": {"8f6f2b5b3d485fe1886cec6a0be8c5d4"}},
+		},
+		{
+			// 移除 !'()* 后签名，空格编码为 %20 而不是 +
+			name:   "过滤特殊字符",
+			params: url.Values{"mid": {"1"}, "keyword": {"评论 (hello)* world!'"}},
+			want: url.Values{"mid": {"1"}, "keyword": {"评论 hello world"},
+				"wts": {"1702204169"}, "w_rid": {"0d50587f3c4c26fe3ea35dc62b5e7cd8"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := docKeys.sign(tc.params, now)
+			if got.Encode() != tc.want.Encode() {
+				t.Errorf("sign = %s, 期望 %s", got.Encode(), tc.want.Encode())
+			}
+			if tc.params.Has("wts") || tc.params.Has("w_rid") {
+				t.Error("sign 修改了传入的参数")
+			}
+		})
+	}
+}
+
+func TestKeyFromURL(t *testing.T) {
+	for _, tc := range []struct {
+		url, want string
+	}{
+		{"https://i0.hdslb.com/bfs/wbi/7cd084941338484aae1ad9425b84077c.png", "7cd084941338484aae1ad9425b84077c"},
+		{"https://i0.hdslb.com/bfs/wbi/4932caff0ff746eab6f01bf08b70ac45", "4932caff0ff746eab6f01bf08b70ac45"},
+		{"", ""},
+	} {
+		if got := keyFromURL(tc.url); got != tc.want {
+			t.Errorf("keyFromURL(%q) = %q, 期望 %q", tc.url, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package blblcd
+
+import (
+	"sync"
+
+	"bilibili-comments-viewer-go/crawler/blblcd/core"
+	"bilibili-comments-viewer-go/crawler/blblcd/model"
+	"bilibili-comments-viewer-go/logger"
+)
+
+// CheckpointCommitter 推迟提交爬虫保存的断点，直到断点之前输出的评论都已写入输出目标
+// 爬虫在评论交给输出通道后就会保存断点，若此时写入失败或进程退出，直接提交的断点会跳过未写入的评论；
+// 爬完时的删除同样推迟到 Finish 确认全部写入成功之后，写入失败时保留最后一个已确认的断点
+type CheckpointCommitter struct {
+	inner  model.CheckpointStore
+	mu     sync.Mutex
+	videos map[string]*videoCommit
+}
+
+// videoCommit 单个视频待提交的断点
+type videoCommit struct {
+	acked    int                 // 已写入所有输出目标的评论数
+	pending  []*model.Checkpoint // 尚未确认的断点，按 Emitted 升序
+	finished bool                // 爬虫已爬完并请求删除断点
+}
+
+// NewCheckpointCommitter 创建包装 inner 的断点提交器，inner 为 nil 时使用 opt 的默认断点存储
+func NewCheckpointCommitter(inner model.CheckpointStore, opt *model.Option) *CheckpointCommitter {
+	if inner == nil {
+		inner = core.CheckpointStore(opt)
+	}
+	return &CheckpointCommitter{inner: inner, videos: make(map[string]*videoCommit)}
+}
+
+func (c *CheckpointCommitter) video(bvid string) *videoCommit {
+	v, ok := c.videos[bvid]
+	if !ok {
+		v = &videoCommit{}
+		c.videos[bvid] = v
+	}
+	return v
+}
+
+func (c *CheckpointCommitter) LoadCheckpoint(bvid string) (*model.Checkpoint, error) {
+	return c.inner.LoadCheckpoint(bvid)
+}
+
+// SaveCheckpoint 断点之前的评论都已写入时直接提交，否则等待 Ack
+func (c *CheckpointCommitter) SaveCheckpoint(cp *model.Checkpoint) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v := c.video(cp.Bvid)
+	if cp.Emitted <= v.acked {
+		v.pending = nil
+		return c.inner.SaveCheckpoint(cp)
+	}
+	v.pending = append(v.pending, cp)
+	return nil
+}
+
+// DeleteCheckpoint 记录爬虫已爬完，由 Finish 在全部写入成功后删除断点
+func (c *CheckpointCommitter) DeleteCheckpoint(bvid string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v := c.video(bvid)
+	v.finished = true
+	v.pending = nil
+	return nil
+}
+
+// Ack 视频的前 written 条评论已写入所有输出目标，提交其中最新的已确认断点，可作为 WriteSinksAck 的回调
+func (c *CheckpointCommitter) Ack(bvid string, written int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v := c.video(bvid)
+	v.acked = written
+	n := 0
+	for n < len(v.pending) && v.pending[n].Emitted <= written {
+		n++
+	}
+	if n == 0 {
+		return
+	}
+	cp := v.pending[n-1]
+	v.pending = v.pending[n:]
+	if err := c.inner.SaveCheckpoint(cp); err != nil {
+		logger.GetLogger().Warnf("保存断点失败: %v", err)
+	}
+}
+
+// Finish 在爬虫退出且评论写入结束后调用：writeErr 为 nil 时提交最后的断点或删除断点，
+// 否则丢弃未确认的断点，保留已提交的断点以便下次继续
+func (c *CheckpointCommitter) Finish(bvid string, writeErr error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v := c.video(bvid)
+	delete(c.videos, bvid)
+	log := logger.GetLogger()
+	switch {
+	case writeErr != nil:
+		if v.finished || len(v.pending) > 0 {
+			log.Warnf("视频 %s 的评论未能全部写入，保留上次确认的断点: %v", bvid, writeErr)
+		}
+	case v.finished:
+		if err := c.inner.DeleteCheckpoint(bvid); err != nil {
+			log.Warnf("删除断点失败: %v", err)
+		}
+	case len(v.pending) > 0:
+		if err := c.inner.SaveCheckpoint(v.pending[len(v.pending)-1]); err != nil {
+			log.Warnf("保存断点失败: %v", err)
+		}
+	}
+}
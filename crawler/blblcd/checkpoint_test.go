@@ -0,0 +1,110 @@
+package blblcd
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"bilibili-comments-viewer-go/crawler/blblcd/model"
+	"bilibili-comments-viewer-go/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitLogger("", "error", 1, 1, 1)
+	os.Exit(m.Run())
+}
+
+// memStore 内存中的断点存储
+type memStore map[string]*model.Checkpoint
+
+func (s memStore) LoadCheckpoint(bvid string) (*model.Checkpoint, error) { return s[bvid], nil }
+func (s memStore) SaveCheckpoint(cp *model.Checkpoint) error             { s[cp.Bvid] = cp; return nil }
+func (s memStore) DeleteCheckpoint(bvid string) error                    { delete(s, bvid); return nil }
+
+// failSink 写入指定批次后失败
+type failSink struct {
+	okBatches int
+	written   int
+}
+
+func (s *failSink) Open() error  { return nil }
+func (s *failSink) Close() error { return nil }
+func (s *failSink) Write(batch []model.Comment) error {
+	if s.okBatches == 0 {
+		return errors.New("写入失败")
+	}
+	s.okBatches--
+	s.written += len(batch)
+	return nil
+}
+
+func TestCheckpointCommitterAck(t *testing.T) {
+	store := memStore{}
+	c := NewCheckpointCommitter(store, nil)
+	for _, emitted := range []int{10, 20, 30} {
+		c.SaveCheckpoint(&model.Checkpoint{Bvid: "BV1", Page: emitted / 10, Emitted: emitted})
+	}
+	if store["BV1"] != nil {
+		t.Fatal("评论写入前提交了断点")
+	}
+	c.Ack("BV1", 25)
+	if cp := store["BV1"]; cp == nil || cp.Emitted != 20 {
+		t.Fatalf("写入 25 条后提交的断点 %+v，期望 Emitted=20", cp)
+	}
+	// 已确认的评论数之内的断点直接提交
+	c.SaveCheckpoint(&model.Checkpoint{Bvid: "BV1", Page: 2, Emitted: 25})
+	if cp := store["BV1"]; cp.Emitted != 25 {
+		t.Fatalf("已写入的断点未直接提交: %+v", cp)
+	}
+}
+
+func TestCheckpointCommitterFinish(t *testing.T) {
+	store := memStore{}
+	c := NewCheckpointCommitter(store, nil)
+	c.SaveCheckpoint(&model.Checkpoint{Bvid: "BV1", Page: 1, Emitted: 10})
+	c.Ack("BV1", 10)
+	c.SaveCheckpoint(&model.Checkpoint{Bvid: "BV1", Page: 2, Emitted: 20})
+	c.DeleteCheckpoint("BV1")
+	if store["BV1"] == nil {
+		t.Fatal("写入结束前删除了断点")
+	}
+
+	// 写入失败：保留已确认的断点
+	c.Finish("BV1", errors.New("写入失败"))
+	if cp := store["BV1"]; cp == nil || cp.Page != 1 {
+		t.Fatalf("写入失败后的断点 %+v，期望第 1 页", cp)
+	}
+
+	// 写入成功：爬完时删除断点，未爬完时提交最后的断点
+	c.DeleteCheckpoint("BV1")
+	c.Finish("BV1", nil)
+	if store["BV1"] != nil {
+		t.Fatal("爬完且写入成功后断点未删除")
+	}
+	c.SaveCheckpoint(&model.Checkpoint{Bvid: "BV2", Page: 3, Emitted: 30})
+	c.Finish("BV2", nil)
+	if cp := store["BV2"]; cp == nil || cp.Page != 3 {
+		t.Fatalf("中断且写入成功后的断点 %+v，期望第 3 页", cp)
+	}
+}
+
+// 任一输出目标失败后不再确认，已确认的评论数取各输出目标的最小值
+func TestWriteSinksAckStopsOnFailure(t *testing.T) {
+	in := make(chan model.Comment)
+	go func() {
+		for i := 0; i < 10; i++ {
+			in <- model.Comment{Rpid: int64(i)}
+		}
+		close(in)
+	}()
+	ok, failing := &failSink{okBatches: -1}, &failSink{okBatches: 2}
+	var acks []int
+	_, err := WriteSinksAck(in, []Sink{ok, failing}, 2, time.Minute, func(written int) { acks = append(acks, written) })
+	if err == nil {
+		t.Fatal("输出目标写入失败时未返回错误")
+	}
+	if len(acks) == 0 || acks[len(acks)-1] > failing.written {
+		t.Errorf("确认的评论数 %v 超过失败的输出目标已写入的 %d 条", acks, failing.written)
+	}
+}
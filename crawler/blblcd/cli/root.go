@@ -1,8 +1,14 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+
+	"bilibili-comments-viewer-go/crawler/biliapi"
+	"bilibili-comments-viewer-go/crawler/blblcd"
+	"bilibili-comments-viewer-go/crawler/blblcd/store"
 
 	"github.com/spf13/cobra"
 )
@@ -17,6 +23,8 @@ var (
 	maxTryCount   int
 	commentOutput string
 	imageOutput   string
+	recordFile    string
+	replayFile    string
 )
 
 var rootCmd = &cobra.Command{
@@ -36,6 +44,51 @@ func init() {
 	rootCmd.PersistentFlags().IntVarP(&corder, "corder", "v", 1, "爬取时评论排序方式，0：按时间，1：按点赞数，2：按回复数")
 	rootCmd.PersistentFlags().StringVar(&commentOutput, "comment-output", "", "评论内容保存路径（默认为output目录下的视频BV号文件夹）")
 	rootCmd.PersistentFlags().StringVar(&imageOutput, "image-output", "", "评论图片保存路径（默认为评论内容保存路径下的images文件夹）")
+	rootCmd.PersistentFlags().StringVar(&recordFile, "record", "", "将接口请求与响应录制到指定的 .tar.zst 存档")
+	rootCmd.PersistentFlags().StringVar(&replayFile, "replay", "", "从 .tar.zst 录制存档回放接口响应，不访问网络")
+}
+
+// videoSinks 命令行模式下单个视频的输出目标：CSV 文件，开启图片下载时同时下载图片
+func videoSinks(bvid string) ([]blblcd.Sink, error) {
+	dir := filepath.Join(output, bvid)
+	if commentOutput != "" {
+		dir = filepath.Join(commentOutput, bvid)
+	}
+	sinks := []blblcd.Sink{store.NewCSVSink(filepath.Join(dir, bvid+".csv"), true)}
+	if imgDownload {
+		imgDir := imageOutput
+		if imgDir == "" {
+			imgDir = filepath.Join(dir, "images")
+		}
+		sinks = append(sinks, store.NewImageSink(imgDir))
+	}
+	return sinks, nil
+}
+
+// crawlContext 按 --replay/--record 参数创建爬取使用的上下文，爬取结束后调用 finish 关闭存档
+func crawlContext() (ctx context.Context, finish func(), err error) {
+	ctx = context.Background()
+	switch {
+	case replayFile != "":
+		rp, err := biliapi.OpenReplay(replayFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return biliapi.WithReplay(ctx, rp), func() {}, nil
+	case recordFile != "":
+		rec, err := biliapi.NewRecorder(recordFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return biliapi.WithRecorder(ctx, rec), func() {
+			if err := rec.Close(); err != nil {
+				fmt.Println(err)
+				return
+			}
+			fmt.Printf("已录制 %d 次接口请求: %s\n", rec.Count(), rec.Path())
+		}, nil
+	}
+	return ctx, func() {}, nil
 }
 
 func Execute(injection *Injection) {
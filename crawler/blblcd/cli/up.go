@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"strconv"
 
-	"bilibili-comments-viewer-go/crawler/blblcd/core"
+	"bilibili-comments-viewer-go/crawler/blblcd"
 	"bilibili-comments-viewer-go/crawler/blblcd/model"
 	"bilibili-comments-viewer-go/crawler/blblcd/utils"
 
@@ -47,7 +47,7 @@ var upCmd = &cobra.Command{
 		}
 
 		opt := model.Option{
-			Mid:           int(mid),
+			Mid:           mid,
 			Pages:         pages,
 			Skip:          skip,
 			Vorder:        vorder,
@@ -59,9 +59,15 @@ var upCmd = &cobra.Command{
 			MaxTryCount:   maxTryCount,
 			CommentOutput: commentOutput,
 			ImageOutput:   imageOutput,
+			Workers:       workers,
 		}
-		sem := make(chan struct{}, workers)
-		core.FindUser(sem, &opt)
+		ctx, finish, err := crawlContext()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer finish()
+		blblcd.CrawlUp(ctx, mid, &opt, videoSinks)
 
 	},
 }
@@ -1,11 +1,9 @@
 package cli
 
 import (
-	"context"
 	"fmt"
-	"sync"
 
-	"bilibili-comments-viewer-go/crawler/blblcd/core"
+	"bilibili-comments-viewer-go/crawler/blblcd"
 	"bilibili-comments-viewer-go/crawler/blblcd/model"
 	"bilibili-comments-viewer-go/crawler/blblcd/utils"
 
@@ -30,6 +28,13 @@ var videoCmd = &cobra.Command{
 			return
 		}
 
+		ctx, finish, err := crawlContext()
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer finish()
+
 		for i := range args {
 			bvid := args[i]
 			opt := model.Option{
@@ -41,15 +46,26 @@ var videoCmd = &cobra.Command{
 				MaxTryCount:   maxTryCount,
 				CommentOutput: commentOutput,
 				ImageOutput:   imageOutput,
+				Workers:       workers,
 			}
 			fmt.Printf("bvid: %s\n", bvid)
-			sem := make(chan struct{}, workers)
-			var wg sync.WaitGroup
+			sinks, _ := videoSinks(bvid)
+			commits := blblcd.NewCheckpointCommitter(nil, &opt)
+			opt.Checkpoints = commits
 			commentChan := make(chan model.Comment, 1000)
-			wg.Add(1)
-			go core.FindComment(context.Background(), sem, &wg, int(core.Bvid2Avid(bvid)), &opt, commentChan)
-			wg.Wait()
-			close(commentChan)
+			crawlErr := make(chan error, 1)
+			go func() {
+				crawlErr <- blblcd.StreamVideo(ctx, bvid, &opt, commentChan)
+			}()
+			_, writeErr := blblcd.WriteSinksAck(commentChan, sinks, blblcd.DefaultSinkBatchSize, blblcd.DefaultSinkFlushInterval,
+				func(written int) { commits.Ack(bvid, written) })
+			if writeErr != nil {
+				fmt.Println(writeErr)
+			}
+			if err := <-crawlErr; err != nil {
+				fmt.Println(err)
+			}
+			commits.Finish(bvid, writeErr)
 		}
 
 	},
@@ -0,0 +1,153 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"sort"
+	"sync"
+
+	"bilibili-comments-viewer-go/crawler/blblcd/model"
+)
+
+// fileCheckpointStore 以 JSON 文件保存断点（命令行模式），文件为 <root>/<bvid>/progress.json
+type fileCheckpointStore struct {
+	root string
+}
+
+func (s fileCheckpointStore) file(bvid string) string {
+	return path.Join(s.root, bvid, "progress.json")
+}
+
+func (s fileCheckpointStore) LoadCheckpoint(bvid string) (*model.Checkpoint, error) {
+	b, err := os.ReadFile(s.file(bvid))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cp model.Checkpoint
+	if err := json.Unmarshal(b, &cp); err != nil {
+		return nil, err
+	}
+	if cp.Offset == "" && cp.Page > 1 {
+		// 旧版断点只记录了页码，无法定位游标
+		return nil, nil
+	}
+	return &cp, nil
+}
+
+func (s fileCheckpointStore) SaveCheckpoint(cp *model.Checkpoint) error {
+	b, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	file := s.file(cp.Bvid)
+	if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, b, 0644)
+}
+
+func (s fileCheckpointStore) DeleteCheckpoint(bvid string) error {
+	err := os.Remove(s.file(bvid))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// CheckpointStore 返回选项中的断点存储，未设置时使用评论输出目录下的文件
+func CheckpointStore(opt *model.Option) model.CheckpointStore {
+	if opt.Checkpoints != nil {
+		return opt.Checkpoints
+	}
+	root := opt.Output
+	if opt.CommentOutput != "" {
+		root = opt.CommentOutput
+	}
+	return fileCheckpointStore{root: root}
+}
+
+// threadState 记录子评论楼层的完成情况，可被主评论遍历与子评论工作池并发访问
+type threadState struct {
+	mu        sync.Mutex
+	completed map[int64]bool
+	pending   map[int64]model.CheckpointThread
+}
+
+// newThreadState 从断点恢复楼层状态，cp 可为 nil
+func newThreadState(cp *model.Checkpoint) *threadState {
+	s := &threadState{
+		completed: make(map[int64]bool),
+		pending:   make(map[int64]model.CheckpointThread),
+	}
+	if cp != nil {
+		for _, rpid := range cp.CompletedRoots {
+			s.completed[rpid] = true
+		}
+		for _, t := range cp.PendingThreads {
+			s.pending[t.Rpid] = t
+		}
+	}
+	return s
+}
+
+// claim 登记即将爬取子评论的楼层；楼层已完成或已在爬取中时返回 false
+func (s *threadState) claim(root model.ReplyItem) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.completed[root.Rpid] {
+		return false
+	}
+	if _, ok := s.pending[root.Rpid]; ok {
+		return false
+	}
+	s.pending[root.Rpid] = model.CheckpointThread{Rpid: root.Rpid, Oid: root.Oid, Rcount: root.Rcount}
+	return true
+}
+
+// complete 标记楼层的子评论已完整爬取
+func (s *threadState) complete(rpid int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, rpid)
+	s.completed[rpid] = true
+}
+
+// pendingThreads 返回尚未完成的楼层，用于恢复时重新提交
+func (s *threadState) pendingThreads() []model.ReplyItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := make([]model.ReplyItem, 0, len(s.pending))
+	for _, t := range s.pending {
+		items = append(items, model.ReplyItem{Rpid: t.Rpid, Oid: t.Oid, Rcount: t.Rcount})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Rpid < items[j].Rpid })
+	return items
+}
+
+// snapshot 生成断点
+func (s *threadState) snapshot(bvid string, page int, offset string, downloaded int) *model.Checkpoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp := &model.Checkpoint{
+		Bvid:            bvid,
+		Page:            page,
+		Offset:          offset,
+		DownloadedCount: downloaded,
+		CompletedRoots:  make([]int64, 0, len(s.completed)),
+		PendingThreads:  make([]model.CheckpointThread, 0, len(s.pending)),
+	}
+	for rpid := range s.completed {
+		cp.CompletedRoots = append(cp.CompletedRoots, rpid)
+	}
+	for _, t := range s.pending {
+		cp.PendingThreads = append(cp.PendingThreads, t)
+	}
+	sort.Slice(cp.CompletedRoots, func(i, j int) bool { return cp.CompletedRoots[i] < cp.CompletedRoots[j] })
+	sort.Slice(cp.PendingThreads, func(i, j int) bool { return cp.PendingThreads[i].Rpid < cp.PendingThreads[j].Rpid })
+	return cp
+}
@@ -2,10 +2,8 @@ package core
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"math/rand"
-	"os"
-	"path"
 	"reflect"
 	"runtime"
 	"runtime/debug"
@@ -15,26 +13,30 @@ import (
 	"time"
 
 	"bilibili-comments-viewer-go/crawler/blblcd/model"
-	"bilibili-comments-viewer-go/crawler/blblcd/store"
+	"bilibili-comments-viewer-go/crawler/blblcd/progress"
 	"bilibili-comments-viewer-go/logger"
 )
 
 // core 包实现了 B 站评论爬取的核心流程，包括主评论、子评论的递归抓取与断点续爬等功能
 
-// FindComment 爬取指定 avid 视频的所有评论（主评论+子评论），支持断点续爬和并发控制
+// FindComment 爬取指定 avid 视频的所有评论（主评论+子评论），支持断点续爬
+// 主评论按 next_offset 游标逐页顺序爬取，回复不完整的楼层交给 opt.Workers 个 worker 并发爬取子评论
 // ctx: 上下文控制
-// sem: 并发信号量
+// sem: 调用方占用的并发名额，退出时释放
 // wg: 外部 WaitGroup（可为 nil）
 // avid: 视频 avid
 // opt: 爬取选项
 // resultChan: 评论结果输出通道
-func FindComment(ctx context.Context, sem chan struct{}, wg *sync.WaitGroup, avid int, opt *model.Option, resultChan chan<- model.Comment) {
+// 返回值: 被取消时返回 ctx 错误；多次请求失败或触发风控而未爬完时返回错误，断点保留以便继续；
+// 结束时无法确认已到达末尾则返回 model.ErrIncomplete
+func FindComment(ctx context.Context, sem chan struct{}, wg *sync.WaitGroup, avid int64, opt *model.Option, resultChan chan<- model.Comment) (err error) {
 	funcName := runtime.FuncForPC(reflect.ValueOf(FindComment).Pointer()).Name()
 	logger.GetLogger().Infof("START %s: avid=%d", funcName, avid)
 
 	defer func() {
 		if r := recover(); r != nil {
 			logger.GetLogger().Errorf("PANIC in %s: %v\n%s", funcName, r, string(debug.Stack()))
+			err = fmt.Errorf("爬取视频 %d 评论时发生panic: %v", avid, r)
 		}
 		if wg != nil {
 			wg.Done()
@@ -43,59 +45,115 @@ func FindComment(ctx context.Context, sem chan struct{}, wg *sync.WaitGroup, avi
 		logger.GetLogger().Infof("END %s: avid=%d", funcName, avid)
 	}()
 
-	oid := strconv.Itoa(avid)
+	oid := strconv.FormatInt(avid, 10)
+	bvid := opt.Bvid
+	if bvid == "" {
+		bvid = Avid2Bvid(avid)
+	}
 	logger.GetLogger().Infof("开始爬取视频评论: oid=%s", oid)
 
-	total, err := FetchCount(oid)
+	total, err := fetchCountRetryingRiskControl(ctx, oid, bvid, avid, opt)
 	if err != nil {
 		logger.GetLogger().Errorf("获取评论总数失败: %v", err)
-		return
+		opt.Progress.Emit(progress.Event{Type: progress.EventFinished, Bvid: bvid, Oid: avid, Message: "获取评论总数失败: " + err.Error()})
+		return fmt.Errorf("获取评论总数失败: %w", err)
 	}
 	logger.GetLogger().Infof("视频 %s 共有 %d 条评论", oid, total)
+	opt.Progress.Emit(progress.Event{Type: progress.EventStart, Bvid: bvid, Oid: avid, Total: total})
 
 	if total == 0 {
 		logger.GetLogger().Infof("视频 %s 没有评论，跳过爬取", oid)
-		return
+		opt.Progress.Emit(progress.Event{Type: progress.EventFinished, Bvid: bvid, Oid: avid, Percent: 100})
+		return nil
 	}
 
-	// 断点续爬相关变量
-	savePath := path.Join(opt.Output, opt.Bvid)
-	if opt.CommentOutput != "" {
-		savePath = path.Join(opt.CommentOutput, opt.Bvid)
+	var mu sync.Mutex    // 保护 downloadedCount、threadErr
+	var threadErr error  // 第一个未能完成的子评论楼层的错误
+	downloadedCount := 0 // 已下载评论数
+	offsetStr := ""      // 分页游标，取自上一页响应的 next_offset
+	page := 1            // 当前页码
+
+	// 尝试加载断点，从上次的游标继续，并恢复子评论楼层的完成情况
+	store := CheckpointStore(opt)
+	cp, err := store.LoadCheckpoint(bvid)
+	if err != nil {
+		logger.GetLogger().Warnf("加载断点失败，从第一页开始爬取: %v", err)
+		cp = nil
 	}
-	imgSavePath := opt.ImageOutput
-	if imgSavePath == "" {
-		imgSavePath = path.Join(savePath, "images")
+	if cp != nil {
+		page = cp.Page
+		offsetStr = cp.Offset
+		downloadedCount = cp.DownloadedCount
+		logger.GetLogger().Infof("断点续爬: 从第%d页、已爬取%d条评论继续，%d个楼层已完成，%d个楼层待继续",
+			page, downloadedCount, len(cp.CompletedRoots), len(cp.PendingThreads))
+	}
+	threads := newThreadState(cp)
+	emitter := newCommentEmitter(ctx, resultChan)
+	saveCheckpoint := func() {
+		mu.Lock()
+		snapshot := downloadedCount
+		mu.Unlock()
+		next := threads.snapshot(bvid, page, offsetStr, snapshot)
+		// 在生成断点之后读取，断点记录的评论都已计入
+		next.Emitted = int(emitter.sent.Load())
+		if err := store.SaveCheckpoint(next); err != nil {
+			logger.GetLogger().Warnf("保存断点失败: %v", err)
+		}
 	}
 
-	var pageWg sync.WaitGroup                            // 控制每一页的并发
-	var mu sync.Mutex                                    // 保护计数和 map
-	downloadedCount := 0                                 // 已下载评论数
-	recordedMap := make(map[int64]bool)                  // 已记录评论去重
-	consecutiveEmptyPages := 0                           // 连续空页计数
-	offsetStr := ""                                      // 分页 offset
-	page := 1                                            // 当前页码
-	progressFile := path.Join(savePath, "progress.json") // 断点文件
-
-	// 尝试加载断点
-	if prog, err := loadProgress(progressFile); err == nil {
-		page = prog.Page
-		downloadedCount = prog.DownloadedCount
-		logger.GetLogger().Infof("断点续爬: 从第%d页、已爬取%d条评论继续", page, downloadedCount)
+	// record 累计新增评论数并发布进度，返回累计值
+	record := func(pageNum, added int) int {
+		mu.Lock()
+		downloadedCount += added
+		snapshot := downloadedCount
+		mu.Unlock()
+		if added > 0 {
+			opt.Progress.Emit(progress.Event{Type: progress.EventCommentsAdded, Bvid: bvid, Oid: avid, Page: pageNum,
+				Added: added, Downloaded: snapshot, Total: total})
+		}
+		return snapshot
 	}
 
-mainLoop:
-	for {
-		select {
-		case <-ctx.Done():
-			logger.GetLogger().Infof("收到取消信号，保存断点并退出...")
-			saveProgress(progressFile, page, downloadedCount)
-			break mainLoop
-		default:
+	// Workers 用于并发爬取子评论楼层，主评论按游标顺序逐页爬取
+	pool := newSubCommentPool(ctx, opt, opt.Workers, func(root model.ReplyItem, replies []model.ReplyItem, err error) {
+		record(0, emitter.emit(replies))
+		// 取消或多次触发风控时 FindSubComment 只返回部分子评论，楼层保持未完成
+		if err != nil {
+			if ctx.Err() == nil {
+				mu.Lock()
+				if threadErr == nil {
+					threadErr = err
+				}
+				mu.Unlock()
+			}
+			return
 		}
+		threads.complete(root.Rpid)
+	})
+	failedThread := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		return threadErr
+	}
 
-		if downloadedCount >= total {
-			logger.GetLogger().Infof("已爬取完成，共获取 %d 条评论，目标 %d 条", downloadedCount, total)
+	// 先继续上次未完成的楼层
+	for _, root := range threads.pendingThreads() {
+		if !pool.submit(root) {
+			break
+		}
+	}
+
+	finished := false // 是否正常爬取到末尾，只有此时才删除断点
+	incomplete := ""  // 结束但无法确认已到达末尾的原因
+	var stopErr error // 未爬完就停止的原因
+	failures := 0     // 当前页连续失败次数
+	riskHits := 0     // 当前页连续触发风控次数
+	emptyPages := 0   // 连续无新评论的页数
+	for {
+		if ctx.Err() != nil {
+			break
+		}
+		if stopErr = failedThread(); stopErr != nil {
 			break
 		}
 
@@ -107,234 +165,255 @@ mainLoop:
 				float64(m.Alloc)/1024/1024, float64(m.TotalAlloc)/1024/1024)
 		}
 
-		if consecutiveEmptyPages >= opt.MaxTryCount {
-			logger.GetLogger().Infof("连续 %d 页无新评论，停止爬取", opt.MaxTryCount)
+		// 延迟与抖动，防止被风控
+		if err := sleepWithJitter(ctx, opt.DelayBaseMs, opt.DelayJitterMs); err != nil {
 			break
 		}
 
-		sem <- struct{}{}
-		pageWg.Add(1)
-
-		// 启动 goroutine 并发爬取每一页评论
-		go func(pageNum int, offset string) {
-			defer func() {
-				if r := recover(); r != nil {
-					logger.GetLogger().Errorf("Page %d goroutine PANIC: %v\n%s", pageNum, r, string(debug.Stack()))
-				}
-				pageWg.Done()
-				<-sem
-			}()
-
-			select {
-			case <-ctx.Done():
-				logger.GetLogger().Debugf("Page %d canceled", pageNum)
-				return
-			default:
-				// 正常执行
-			}
-
-			// 延迟与抖动，防止被风控
-			baseDelay := time.Duration(opt.DelayBaseMs) * time.Millisecond
-			jitter := time.Duration(rand.Int63n(int64(opt.DelayJitterMs))) * time.Millisecond
-			delay := baseDelay + jitter
-			time.Sleep(delay)
-
-			logger.GetLogger().Infof("并发爬取第 %d 页评论 (oid: %s, offset: %s)", pageNum, oid, offset)
-
-			// 计算进度百分比
-			if total > 0 {
-				progressPercent := float64(downloadedCount) / float64(total) * 100
-				logger.GetLogger().Infof("Processing page %d, progress: %.1f%% (%d/%d)",
-					pageNum, progressPercent, downloadedCount, total)
+		logger.GetLogger().Infof("爬取第 %d 页评论 (oid: %s, offset: %s)", page, oid, offsetStr)
+		cmtInfo, err := FetchComment(ctx, oid, page, opt.Corder, opt.Cookie, offsetStr)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
 			}
-			cmtInfo, err := FetchComment(oid, pageNum, opt.Corder, opt.Cookie, offset)
-			if err != nil {
-				logger.GetLogger().Errorf("请求评论失败，视频%s，第%d页: %v", oid, pageNum, err)
-				mu.Lock()
-				consecutiveEmptyPages++
-				mu.Unlock()
-				return
+			if riskControlHit(opt, progress.Event{Bvid: bvid, Oid: avid, Page: page}, err) {
+				// 风控不计入失败次数：熔断器冷却后使用同一游标重试当前页
+				riskHits++
+				if riskHits >= maxRiskControlRetries {
+					stopErr = fmt.Errorf("第%d页连续 %d 次触发风控: %w", page, riskHits, err)
+					break
+				}
+				continue
 			}
-
-			if cmtInfo.Code != 0 {
-				logger.GetLogger().Errorf("请求评论失败，视频%s，第%d页失败: %s", oid, pageNum, cmtInfo.Message)
-				mu.Lock()
-				consecutiveEmptyPages++
-				mu.Unlock()
-				return
+			failures++
+			logger.GetLogger().Errorf("请求评论失败，视频%s，第%d页 (%d/%d): %v", oid, page, failures, opt.MaxTryCount, err)
+			opt.Progress.Emit(progress.Event{Type: progress.EventRetry, Bvid: bvid, Oid: avid, Page: page, Message: err.Error()})
+			if failures >= opt.MaxTryCount {
+				stopErr = fmt.Errorf("第%d页多次请求失败: %w", page, err)
+				break
 			}
+			continue // 使用同一游标重试当前页
+		}
+		failures = 0
+		riskHits = 0
+		archivePage(opt, model.RawPage{Bvid: bvid, Oid: avid, Kind: model.RawPageMain, Page: page, Offset: offsetStr}, &cmtInfo)
 
-			logger.GetLogger().Infof("第 %d 页获取到 %d 条主评论", pageNum, len(cmtInfo.Data.Replies))
-
-			// 检查是否到达末尾
-			if cmtInfo.Data.Cursor.IsEnd {
-				logger.GetLogger().Infof("API返回已到达末尾，停止爬取")
-				mu.Lock()
-				consecutiveEmptyPages = opt.MaxTryCount
-				mu.Unlock()
-				return
-			}
+		logger.GetLogger().Infof("第 %d 页获取到 %d 条主评论", page, len(cmtInfo.Data.Replies))
 
-			var replyCollection []model.ReplyItem
-			replyCollection = append(replyCollection, cmtInfo.Data.Replies...)
+		roots := cmtInfo.Data.Replies
+		if page == 1 {
+			// 置顶评论只在第一页返回
+			roots = append(roots, cmtInfo.Data.TopReplies...)
+		}
 
-			// 处理子评论
-			for _, k := range cmtInfo.Data.Replies {
-				if k.Rcount == 0 {
-					continue
-				}
-				if len(k.Replies) > 0 && len(k.Replies) == k.Rcount {
-					replyCollection = append(replyCollection, k.Replies...)
-				} else {
-					subCmts := FindSubComment(k, opt)
-					replyCollection = append(replyCollection, subCmts...)
+		// 主评论与内嵌的完整回复直接输出，回复不完整且未爬取过的楼层交给工作池
+		items := make([]model.ReplyItem, 0, len(roots))
+		var pending []model.ReplyItem
+		for _, root := range roots {
+			items = append(items, root)
+			if needsSubFetch(root) {
+				if threads.claim(root) {
+					pending = append(pending, root)
 				}
+			} else {
+				items = append(items, root.Replies...)
 			}
-
-			// 处理置顶评论
-			if len(cmtInfo.Data.TopReplies) != 0 {
-				replyCollection = append(replyCollection, cmtInfo.Data.TopReplies...)
-				for _, k := range cmtInfo.Data.TopReplies {
-					if len(k.Replies) > 0 {
-						replyCollection = append(replyCollection, k.Replies...)
-					}
-				}
+		}
+		added := emitter.emit(items)
+		downloadedSnapshot := record(page, added)
+		for _, root := range pending {
+			if !pool.submit(root) {
+				break
 			}
+		}
 
-			var cmtCollection []model.Comment
-			newCommentCount := 0
-
-			// 评论去重与收集
-			mu.Lock()
-			for _, k := range replyCollection {
-				if _, ok := recordedMap[k.Rpid]; !ok {
-					cmt := NewCMT(&k)
-					recordedMap[cmt.Rpid] = true
-					cmtCollection = append(cmtCollection, cmt)
-					resultChan <- cmt
-					newCommentCount++
-				}
-			}
+		if added == 0 {
+			emptyPages++
+		} else {
+			emptyPages = 0
+		}
 
-			if newCommentCount == 0 {
-				consecutiveEmptyPages++
-				logger.GetLogger().Debugf("第%d页无新评论，连续空页计数: %d", pageNum, consecutiveEmptyPages)
-			} else {
-				consecutiveEmptyPages = 0
-			}
+		opt.Progress.Emit(progress.Event{Type: progress.EventPageDone, Bvid: bvid, Oid: avid, Page: page,
+			Added: added, Downloaded: downloadedSnapshot, Total: total, Percent: percentOf(downloadedSnapshot, total)})
+		logger.GetLogger().Infof("视频%s，第%d页已爬取%d条新评论，%d个楼层待爬取子评论，总计%d条",
+			oid, page, added, len(pending), downloadedSnapshot)
 
-			downloadedCount += newCommentCount
-			mu.Unlock()
+		if ctx.Err() != nil {
+			break
+		}
 
-			remaining := total - downloadedCount
-			if remaining < 0 {
-				remaining = 0
-			}
+		next := cmtInfo.Data.Cursor.PaginationReply.NextOffset
+		if cmtInfo.Data.Cursor.IsEnd || next == "" {
+			logger.GetLogger().Infof("API返回已到达末尾，停止爬取")
+			finished = true
+			break
+		}
+		if len(cmtInfo.Data.Replies) == 0 || next == offsetStr {
+			incomplete = fmt.Sprintf("第%d页没有主评论或分页游标未前进，但接口未返回末尾", page)
+			logger.GetLogger().Warnf("%s，停止爬取", incomplete)
+			finished = true
+			break
+		}
 
-			logger.GetLogger().Infof("视频%s，第%d页已爬取%d条新评论，总计%d条，预计剩余%d条",
-				oid, pageNum, newCommentCount, downloadedCount, remaining)
+		offsetStr = next
+		page++
+		if emptyPages >= opt.MaxTryCount {
+			// 接口未返回末尾却连续多页没有新评论，无法确认已爬完：保留断点，下次从下一页继续
+			stopErr = fmt.Errorf("连续 %d 页无新评论，第%d页起未爬取", emptyPages, page)
+			break
+		}
+		// 保存断点：下一页的游标与各楼层状态，已提交但未完成的楼层在恢复时重新爬取
+		// 断点存储可推迟到评论写入输出目标后再提交（见 model.Checkpoint.Emitted）
+		saveCheckpoint()
+	}
 
-			if len(cmtCollection) > 0 {
-				store.Save2CSV(opt.Bvid, cmtCollection, savePath, imgSavePath, opt.ImgDownload)
-			}
+	pool.wait() // 等待已提交的子评论楼层完成
+	if stopErr == nil {
+		stopErr = failedThread()
+	}
 
-			// 保存进度，便于断点续爬
-			saveProgress(progressFile, pageNum+1, downloadedCount)
+	if ctx.Err() != nil || !finished || stopErr != nil {
+		// 已取消、多次请求失败或触发风控：保留断点，下次从断点继续
+		saveCheckpoint()
+		message := "已取消"
+		if ctx.Err() == nil && stopErr != nil {
+			message = stopErr.Error() + "，已保存断点"
+		}
+		logger.GetLogger().Infof("*****爬取视频：%s评论中断（%s），已获取 %d 条评论*****", oid, message, downloadedCount)
+		opt.Progress.Emit(progress.Event{Type: progress.EventFinished, Bvid: bvid, Oid: avid,
+			Downloaded: downloadedCount, Total: total, Percent: percentOf(downloadedCount, total), Message: message})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return stopErr
+	}
 
-			if cmtInfo.Data.Cursor.PaginationReply.NextOffset != "" {
-				mu.Lock()
-				offsetStr = cmtInfo.Data.Cursor.PaginationReply.NextOffset
-				mu.Unlock()
-				logger.GetLogger().Debugf("更新offset: %s", offsetStr)
-			}
-		}(page, offsetStr)
+	logger.GetLogger().Infof("*****爬取视频：%s评论完成，共 %d 页，获取 %d 条评论*****", oid, page, downloadedCount)
+	opt.Progress.Emit(progress.Event{Type: progress.EventFinished, Bvid: bvid, Oid: avid,
+		Downloaded: downloadedCount, Total: total, Percent: percentOf(downloadedCount, total), Message: incomplete})
 
-		page++
+	if err := store.DeleteCheckpoint(bvid); err != nil { // 清理断点
+		logger.GetLogger().Warnf("删除断点失败: %v", err)
 	}
+	if incomplete != "" {
+		return fmt.Errorf("%w: %s", model.ErrIncomplete, incomplete)
+	}
+	return nil
+}
 
-	pageWg.Wait() // 等待所有页 goroutine 完成
-	logger.GetLogger().Infof("*****爬取视频：%s评论完成，共获取 %d 条评论*****", oid, downloadedCount)
-
-	_ = removeProgress(progressFile) // 清理断点文件
+// fetchCountRetryingRiskControl 获取评论总数，触发风控时等待熔断器冷却后重试
+func fetchCountRetryingRiskControl(ctx context.Context, oid, bvid string, avid int64, opt *model.Option) (int, error) {
+	for hits := 1; ; hits++ {
+		total, err := FetchCount(ctx, oid)
+		if err == nil || ctx.Err() != nil || hits >= maxRiskControlRetries ||
+			!riskControlHit(opt, progress.Event{Bvid: bvid, Oid: avid}, err) {
+			return total, err
+		}
+	}
 }
 
+// subCommentPageSize 子评论每页条数，与 FetchSubComment 请求的 ps 一致
+const subCommentPageSize = 20
+
 // FindSubComment 递归爬取某条主评论下的所有子评论
+// 按接口返回的回复总数（缺失时为主评论的 rcount）计算页数，爬完最后一页或遇到空页时结束
+// ctx: 上下文控制，取消时返回已获取的部分子评论
 // cmt: 主评论项
 // opt: 爬取选项
-// 返回值: 子评论集合
-func FindSubComment(cmt model.ReplyItem, opt *model.Option) []model.ReplyItem {
+// 返回值: 子评论集合；被取消、某页多次请求失败或多次触发风控时返回已获取的部分子评论和错误
+func FindSubComment(ctx context.Context, cmt model.ReplyItem, opt *model.Option) (replyCollection []model.ReplyItem, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			logger.GetLogger().Errorf("FindSubComment PANIC: %v\n%s", r, string(debug.Stack()))
+			err = fmt.Errorf("爬取评论 %d 的子评论时发生panic: %v", cmt.Rpid, r)
 		}
 	}()
 
-	oid := strconv.Itoa(cmt.Oid)
+	oid := strconv.FormatInt(cmt.Oid, 10)
 	round := 1
-	replyCollection := []model.ReplyItem{}
-	consecutiveEmptyPages := 0 // 连续空页计数
+	replyCollection = []model.ReplyItem{}
+	failures := 0 // 当前页连续失败次数
+	riskHits := 0 // 当前页连续触发风控次数
 
 	logger.GetLogger().Infof("开始爬取评论 %d 的子评论，预计 %d 条", cmt.Rpid, cmt.Rcount)
 
 	for {
-		// 检查连续空页次数
-		if consecutiveEmptyPages >= opt.MaxTryCount {
-			logger.GetLogger().Infof("连续 %d 页无子评论，停止爬取评论 %d", opt.MaxTryCount, cmt.Rpid)
+		// 延迟逻辑（配置化）
+		if err := sleepWithJitter(ctx, opt.DelayBaseMs, opt.DelayJitterMs); err != nil {
+			logger.GetLogger().Infof("评论 %d 的子评论爬取已取消", cmt.Rpid)
 			break
 		}
 
-		// 延迟逻辑（配置化）
-		baseDelay := time.Duration(opt.DelayBaseMs) * time.Millisecond
-		jitter := time.Duration(rand.Int63n(int64(opt.DelayJitterMs))) * time.Millisecond
-		delay := baseDelay + jitter
-		time.Sleep(delay)
-
 		logger.GetLogger().Infof("爬取评论 %d 的子评论第 %d 页", cmt.Rpid, round)
-		cmtInfo, err := FetchSubComment(oid, cmt.Rpid, round, opt.Cookie)
+		cmtInfo, err := FetchSubComment(ctx, oid, cmt.Rpid, round, opt.Cookie)
 		if err != nil {
-			logger.GetLogger().Errorf("请求子评论失败，父评论%d，第%d页: %v", cmt.Rpid, round, err)
-			consecutiveEmptyPages++
-			round++
-			continue
+			if ctx.Err() != nil {
+				break
+			}
+			if riskControlHit(opt, progress.Event{Oid: cmt.Oid, Rpid: cmt.Rpid, Page: round}, err) {
+				// 风控不计入失败次数：等待熔断器冷却后重试当前页
+				riskHits++
+				if riskHits >= maxRiskControlRetries {
+					return replyCollection, fmt.Errorf("评论 %d 的子评论连续 %d 次触发风控: %w", cmt.Rpid, riskHits, err)
+				}
+				continue
+			}
+			failures++
+			logger.GetLogger().Errorf("请求子评论失败，父评论%d，第%d页 (%d/%d): %v", cmt.Rpid, round, failures, opt.MaxTryCount, err)
+			opt.Progress.Emit(progress.Event{Type: progress.EventRetry, Oid: cmt.Oid, Rpid: cmt.Rpid, Page: round, Message: err.Error()})
+			if failures >= opt.MaxTryCount {
+				return replyCollection, fmt.Errorf("评论 %d 的子评论第%d页多次请求失败: %w", cmt.Rpid, round, err)
+			}
+			continue // 重试当前页
 		}
 
-		round++
-		if cmtInfo.Code != 0 {
-			logger.GetLogger().Errorf("请求子评论失败，父评论%d，第%d页失败: %s", cmt.Rpid, round-1, cmtInfo.Message)
-			consecutiveEmptyPages++
-			continue
+		failures = 0
+		riskHits = 0
+		archivePage(opt, model.RawPage{Bvid: Avid2Bvid(cmt.Oid), Oid: cmt.Oid, Kind: model.RawPageReply,
+			Root: cmt.Rpid, Page: round}, &cmtInfo)
+		opt.Progress.Emit(progress.Event{Type: progress.EventSubComment, Oid: cmt.Oid, Rpid: cmt.Rpid, Page: round,
+			Added: len(cmtInfo.Data.Replies), Total: cmt.Rcount})
+
+		if len(cmtInfo.Data.Replies) == 0 {
+			logger.GetLogger().Debugf("评论 %d 第 %d 页无子评论，已到达末尾", cmt.Rpid, round)
+			break
 		}
+		replyCollection = append(replyCollection, cmtInfo.Data.Replies...)
 
-		if len(cmtInfo.Data.Replies) > 0 {
-			replyCollection = append(replyCollection, cmtInfo.Data.Replies...)
+		// 处理嵌套回复
+		for _, k := range cmtInfo.Data.Replies {
+			if len(k.Replies) > 0 {
+				replyCollection = append(replyCollection, k.Replies...)
+			}
+		}
 
-			// 处理嵌套回复
-			for _, k := range cmtInfo.Data.Replies {
+		// 处理置顶回复
+		if len(cmtInfo.Data.TopReplies) != 0 {
+			replyCollection = append(replyCollection, cmtInfo.Data.TopReplies...)
+			for _, k := range cmtInfo.Data.TopReplies {
 				if len(k.Replies) > 0 {
 					replyCollection = append(replyCollection, k.Replies...)
 				}
 			}
+		}
+		logger.GetLogger().Debugf("评论 %d 第 %d 页获取到 %d 条子评论", cmt.Rpid, round, len(cmtInfo.Data.Replies))
 
-			// 处理置顶回复
-			if len(cmtInfo.Data.TopReplies) != 0 {
-				replyCollection = append(replyCollection, cmtInfo.Data.TopReplies...)
-				for _, k := range cmtInfo.Data.TopReplies {
-					if len(k.Replies) > 0 {
-						replyCollection = append(replyCollection, k.Replies...)
-					}
-				}
-			}
-
-			consecutiveEmptyPages = 0 // 重置连续空页计数
-			logger.GetLogger().Debugf("评论 %d 第 %d 页获取到 %d 条子评论", cmt.Rpid, round-1, len(cmtInfo.Data.Replies))
-		} else {
-			consecutiveEmptyPages++
-			logger.GetLogger().Debugf("评论 %d 第 %d 页无子评论，连续空页计数: %d", cmt.Rpid, round-1, consecutiveEmptyPages)
+		// 接口返回的总数与每页条数决定页数，缺失时按主评论的 rcount 估算
+		total, size := cmtInfo.Data.Page.Count, cmtInfo.Data.Page.Size
+		if total <= 0 {
+			total = cmt.Rcount
+		}
+		if size <= 0 {
+			size = subCommentPageSize
+		}
+		if round*size >= total {
+			break
 		}
+		round++
 	}
 
 	logger.GetLogger().Infof("评论 %d 子评论爬取完成，共获取 %d 条", cmt.Rpid, len(replyCollection))
-	return replyCollection
+	return replyCollection, ctx.Err()
 }
 
 // NewCMT 将 ReplyItem 转换为 Comment 结构体
@@ -348,13 +427,13 @@ func NewCMT(item *model.ReplyItem) model.Comment {
 	// Oid 校验
 	var bvid string
 	if item.Oid > 0 {
-		bvid = Avid2Bvid(int64(item.Oid))
+		bvid = Avid2Bvid(item.Oid)
 	}
 	// 防御性：Bvid 必须以 BV 开头且长度为12
 	if !strings.HasPrefix(bvid, "BV") || len(bvid) != 12 {
 		bvid = ""
 	}
-	return model.Comment{
+	cmt := model.Comment{
 		Uname:         item.Member.Uname,
 		Sex:           item.Member.Sex,
 		Content:       item.Content.Message,
@@ -362,20 +441,50 @@ func NewCMT(item *model.ReplyItem) model.Comment {
 		Oid:           item.Oid,
 		Bvid:          bvid,
 		Mid:           item.Mid,
+		Root:          item.Root,
 		Parent:        item.Parent,
+		Dialog:        item.Dialog,
+		Fansgrade:     item.Fansgrade,
 		Ctime:         item.Ctime,
 		Like:          item.Like,
+		Rcount:        item.Rcount,
 		Following:     item.ReplyControl.Following,
 		Current_level: item.Member.LevelInfo.CurrentLevel,
 		Pictures:      item.Content.Pictures,
 		Location:      strings.Replace(item.ReplyControl.Location, "IP属地：", "", -1),
+		Invisible:     item.Invisible,
+		Pinned:        item.ReplyControl.IsUpTop,
+		UpReplied:     item.UpAction.Reply,
+		TimeDesc:      item.ReplyControl.TimeDesc,
+		Avatar:        item.Member.Avatar,
+		Vip: model.Vip{
+			Type:   item.Member.Vip.VipType,
+			Status: item.Member.Vip.VipStatus,
+			Label:  item.Member.Vip.Label.Text,
+		},
+	}
+	if fd := item.Member.FansDetail; fd != nil && fd.MedalName != "" {
+		cmt.FansMedal = &model.FansMedal{Name: fd.MedalName, Level: fd.Level}
+	}
+	if len(item.Content.Emote) > 0 {
+		cmt.Emotes = make(map[string]string, len(item.Content.Emote))
+		for text, e := range item.Content.Emote {
+			cmt.Emotes[text] = e.URL
+		}
+	}
+	for _, m := range item.Content.Members {
+		mid, _ := strconv.ParseInt(m.Mid, 10, 64)
+		cmt.Members = append(cmt.Members, model.Member{Mid: mid, Uname: m.Uname})
 	}
+	return cmt
 }
 
 // FindUser 爬取指定 up 主的所有视频评论
-// sem: 并发信号量
+// ctx: 上下文控制，取消时停止获取视频列表并中断正在爬取的视频
+// sem: 并发信号量，限制同时爬取的视频数
 // opt: 爬取选项（需包含 mid）
-func FindUser(sem chan struct{}, opt *model.Option) {
+// consume: 消费单个视频的评论，需读取 comments 直到通道关闭；为 nil 时丢弃评论
+func FindUser(ctx context.Context, sem chan struct{}, opt *model.Option, consume func(bvid string, comments <-chan model.Comment)) {
 	defer func() {
 		if r := recover(); r != nil {
 			logger.GetLogger().Errorf("FindUser PANIC: %v\n%s", r, string(debug.Stack()))
@@ -388,22 +497,20 @@ func FindUser(sem chan struct{}, opt *model.Option) {
 
 	for ; round < opt.Pages+opt.Skip; round++ {
 		// 延迟逻辑（配置化）
-		baseDelay := time.Duration(opt.DelayBaseMs) * time.Millisecond
-		jitter := time.Duration(rand.Int63n(int64(opt.DelayJitterMs))) * time.Millisecond
-		delay := baseDelay + jitter
-		time.Sleep(delay)
+		if err := sleepWithJitter(ctx, opt.DelayBaseMs, opt.DelayJitterMs); err != nil {
+			logger.GetLogger().Infof("UP主 %d 视频列表爬取已取消", opt.Mid)
+			return
+		}
 		logger.GetLogger().Infof("爬取视频列表第%d页", round)
-		tempVideoInfo, err := FetchVideoList(opt.Mid, round, opt.Vorder, opt.Cookie)
+		tempVideoInfo, err := FetchVideoList(ctx, opt.Mid, round, opt.Vorder, opt.Cookie)
 		if err != nil {
 			logger.GetLogger().Errorf("请求up主视频列表失败，第%d页失败", round)
 			logger.GetLogger().Error(err)
+			opt.Progress.Emit(progress.Event{Type: progress.EventRetry, Page: round, Message: err.Error()})
 			continue
 		}
-		if tempVideoInfo.Code != 0 {
-			logger.GetLogger().Errorf("请求up主视频列表失败，第%d页失败", round)
-			logger.GetLogger().Error(tempVideoInfo.Message)
-			continue
-		}
+		opt.Progress.Emit(progress.Event{Type: progress.EventVideoList, Page: round,
+			Added: len(tempVideoInfo.Data.List.Vlist), Total: tempVideoInfo.Data.Page.Count})
 		if len(tempVideoInfo.Data.List.Vlist) != 0 {
 			videoCollection = append(videoCollection, tempVideoInfo.Data.List.Vlist...)
 		} else {
@@ -412,55 +519,83 @@ func FindUser(sem chan struct{}, opt *model.Option) {
 	}
 
 	logger.GetLogger().Infof("%d查找到了%d条视频", opt.Mid, len(videoCollection))
+videoLoop:
 	for _, k := range videoCollection {
-		time.Sleep(3 * time.Second)
+		if err := sleepContext(ctx, 3*time.Second); err != nil {
+			break
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break videoLoop
+		}
 		logger.GetLogger().Infof("------启动爬取%d------", k.Aid)
 		wg.Add(1)
-		sem <- struct{}{}
 
-		// 创建结果通道
+		// 每个视频的评论由 consume 消费，结束后才释放视频名额
 		resultChan := make(chan model.Comment, 1000)
+		consumed := make(chan struct{})
+		go func(bvid string) {
+			defer close(consumed)
+			if consume == nil {
+				for range resultChan {
+				}
+				return
+			}
+			consume(bvid, resultChan)
+		}(Avid2Bvid(k.Aid))
 
-		// 传递结果通道作为第5个参数
-		go func(aid int) {
+		go func(aid int64) {
 			defer wg.Done()
-			FindComment(context.Background(), sem, &wg, aid, opt, resultChan)
+			defer func() { <-sem }()
+			// 每个视频使用独立的信号量，避免与视频级并发争抢名额导致死锁
+			if err := FindComment(ctx, NewVideoSem(), nil, aid, opt, resultChan); err != nil && ctx.Err() == nil {
+				logger.GetLogger().Errorf("视频 %d 评论未爬完: %v", aid, err)
+			}
 			close(resultChan)
+			<-consumed
 		}(k.Aid)
 	}
 	wg.Wait()
 }
 
-// 断点续爬相关结构体与方法
-// progress 记录断点信息
-// page: 当前页码
-// downloadedCount: 已下载评论数
-type progress struct {
-	Page            int `json:"page"`
-	DownloadedCount int `json:"downloaded_count"`
+// NewVideoSem 创建单个视频的信号量
+// FindComment 退出时会释放调用方占用的名额，因此预先占用一个；并发度由 opt.Workers 控制子评论工作池
+func NewVideoSem() chan struct{} {
+	sem := make(chan struct{}, 1)
+	sem <- struct{}{}
+	return sem
 }
 
-// saveProgress 保存断点信息到文件
-func saveProgress(filename string, page, downloadedCount int) error {
-	p := progress{Page: page, DownloadedCount: downloadedCount}
-	b, _ := json.Marshal(p)
-	return os.WriteFile(filename, b, 0644)
+// sleepWithJitter 按配置的基础延迟加随机抖动等待，ctx 取消时提前返回
+func sleepWithJitter(ctx context.Context, baseMs, jitterMs int) error {
+	delay := time.Duration(baseMs) * time.Millisecond
+	if jitterMs > 0 {
+		delay += time.Duration(rand.Int63n(int64(jitterMs))) * time.Millisecond
+	}
+	return sleepContext(ctx, delay)
 }
 
-// loadProgress 加载断点信息
-func loadProgress(filename string) (progress, error) {
-	var p progress
-	b, err := os.ReadFile(filename)
-	if err != nil {
-		return p, err
-	}
-	if err := json.Unmarshal(b, &p); err != nil {
-		return p, err
+// sleepContext 等待指定时长，ctx 取消时提前返回 ctx.Err()
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	return p, nil
 }
 
-// removeProgress 删除断点文件
-func removeProgress(filename string) error {
-	return os.Remove(filename)
+// percentOf 计算进度百分比，上限为 100
+func percentOf(downloaded, total int) float64 {
+	if total <= 0 {
+		return 0
+	}
+	p := float64(downloaded) / float64(total) * 100
+	if p > 100 {
+		p = 100
+	}
+	return p
 }
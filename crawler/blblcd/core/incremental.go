@@ -0,0 +1,189 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+
+	"bilibili-comments-viewer-go/crawler/blblcd/model"
+	"bilibili-comments-viewer-go/crawler/blblcd/progress"
+	"bilibili-comments-viewer-go/logger"
+)
+
+// FindNewComments 增量爬取指定 avid 视频的评论（需设置 opt.Incremental）
+// 按时间倒序逐页爬取主评论，遇到已入库的主评论（rpid 已存在或发布时间早于已入库的最新评论）之前的主评论全部输出；
+// 到达已入库评论的那一页之后最多再翻 state.ExtraPages 页即停止，请求数只与新增评论数有关。
+// 已爬取页面上的已入库楼层比较回复数（rcount），输出回复数与入库时不同的楼层；更早的楼层不刷新。
+// 子评论只在新主评论或回复数变化的楼层重新爬取，子评论未爬完时主评论以入库时的回复数输出，下次增量爬取时重新刷新
+// ctx: 上下文控制
+// avid: 视频 avid
+// opt: 爬取选项
+// resultChan: 评论结果输出通道
+// 返回值: 被取消时返回 ctx 错误；多次请求失败或触发风控而未爬完时返回错误；
+// 未到达已入库的评论就没有更多评论且接口未返回末尾时返回 model.ErrIncomplete
+func FindNewComments(ctx context.Context, avid int64, opt *model.Option, resultChan chan<- model.Comment) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.GetLogger().Errorf("FindNewComments PANIC: %v\n%s", r, string(debug.Stack()))
+			err = fmt.Errorf("增量爬取视频 %d 评论时发生panic: %v", avid, r)
+		}
+	}()
+
+	state := opt.Incremental
+	oid := strconv.FormatInt(avid, 10)
+	bvid := opt.Bvid
+	if bvid == "" {
+		bvid = Avid2Bvid(avid)
+	}
+	logger.GetLogger().Infof("开始增量爬取视频评论: oid=%s, 已入库主评论 %d 条", oid, len(state.KnownRoots))
+
+	total, err := fetchCountRetryingRiskControl(ctx, oid, bvid, avid, opt)
+	if err != nil {
+		logger.GetLogger().Errorf("获取评论总数失败: %v", err)
+		opt.Progress.Emit(progress.Event{Type: progress.EventFinished, Bvid: bvid, Oid: avid, Message: "获取评论总数失败: " + err.Error()})
+		return fmt.Errorf("获取评论总数失败: %w", err)
+	}
+	opt.Progress.Emit(progress.Event{Type: progress.EventStart, Bvid: bvid, Oid: avid, Total: total})
+
+	seen := make(map[int64]bool) // 本次已输出的评论，置顶评论会在列表中重复出现
+	emit := func(items []model.ReplyItem) int {
+		count := 0
+		for i := range items {
+			if seen[items[i].Rpid] {
+				continue
+			}
+			seen[items[i].Rpid] = true
+			select {
+			case resultChan <- NewCMT(&items[i]):
+				count++
+			case <-ctx.Done():
+				return count
+			}
+		}
+		return count
+	}
+
+	downloaded := 0
+	refreshed := 0
+	reachedPage := 0 // 到达已入库评论的页码，之后只输出回复数变化的楼层
+	incomplete := "" // 结束但无法确认已到达已入库评论或末尾的原因
+	failures := 0
+	riskHits := 0
+	var stopErr error // 未爬完就停止的原因
+	offsetStr := ""
+	page := 1
+	for {
+		if err := sleepWithJitter(ctx, opt.DelayBaseMs, opt.DelayJitterMs); err != nil {
+			break
+		}
+
+		cmtInfo, err := FetchComment(ctx, oid, page, model.CorderTime, opt.Cookie, offsetStr)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			if riskControlHit(opt, progress.Event{Bvid: bvid, Oid: avid, Page: page}, err) {
+				// 风控不计入失败次数：熔断器冷却后重试当前页
+				riskHits++
+				if riskHits >= maxRiskControlRetries {
+					stopErr = fmt.Errorf("第%d页连续 %d 次触发风控: %w", page, riskHits, err)
+					break
+				}
+				continue
+			}
+			failures++
+			logger.GetLogger().Errorf("请求评论失败，视频%s，第%d页 (%d/%d): %v", oid, page, failures, opt.MaxTryCount, err)
+			opt.Progress.Emit(progress.Event{Type: progress.EventRetry, Bvid: bvid, Oid: avid, Page: page, Message: err.Error()})
+			if failures >= opt.MaxTryCount {
+				stopErr = fmt.Errorf("第%d页多次请求失败: %w", page, err)
+				break
+			}
+			continue // 重试当前页
+		}
+		failures = 0
+		riskHits = 0
+		archivePage(opt, model.RawPage{Bvid: bvid, Oid: avid, Kind: model.RawPageMain, Page: page, Offset: offsetStr}, &cmtInfo)
+
+		added := 0
+		threads := cmtInfo.Data.Replies
+		if page == 1 {
+			// 置顶评论不参与终点判断，但回复数变化时同样需要刷新
+			threads = append(threads, cmtInfo.Data.TopReplies...)
+		}
+		for i, root := range threads {
+			if reachedPage == 0 && i < len(cmtInfo.Data.Replies) && state.Reached(root.Rpid, root.Ctime) {
+				logger.GetLogger().Infof("视频%s 第%d页已到达已入库的评论，之后只刷新回复数变化的楼层，再翻 %d 页后停止", oid, page, state.ExtraPages)
+				reachedPage = page
+			}
+			changed := state.RcountChanged(root.Rpid, root.Rcount)
+			if reachedPage > 0 && !changed {
+				continue
+			}
+			items := []model.ReplyItem{root}
+			if root.Rcount > 0 && changed {
+				stored, known := state.KnownRoots[root.Rpid]
+				if known {
+					refreshed++
+				}
+				if len(root.Replies) == root.Rcount {
+					items = append(items, root.Replies...)
+				} else {
+					replies, err := FindSubComment(ctx, root, opt)
+					items = append(items, replies...)
+					if err != nil {
+						// 子评论未爬完：保留入库时的回复数（新楼层为 0），下次增量爬取时重新刷新
+						items[0].Rcount = stored
+						if ctx.Err() == nil {
+							stopErr = err
+						}
+					}
+				}
+			}
+			added += emit(items)
+			if ctx.Err() != nil || stopErr != nil {
+				break
+			}
+		}
+		downloaded += added
+
+		opt.Progress.Emit(progress.Event{Type: progress.EventPageDone, Bvid: bvid, Oid: avid, Page: page,
+			Added: added, Downloaded: downloaded, Total: total})
+		logger.GetLogger().Infof("视频%s，增量第%d页获取%d条评论，总计%d条", oid, page, added, downloaded)
+
+		if ctx.Err() != nil || stopErr != nil {
+			break
+		}
+		if reachedPage > 0 && page-reachedPage >= state.ExtraPages {
+			logger.GetLogger().Infof("视频%s 已刷新到达已入库评论之后的 %d 页，停止爬取", oid, page-reachedPage)
+			break
+		}
+		next := cmtInfo.Data.Cursor.PaginationReply.NextOffset
+		if cmtInfo.Data.Cursor.IsEnd || next == "" {
+			logger.GetLogger().Infof("API返回已到达末尾，停止爬取")
+			break
+		}
+		if len(cmtInfo.Data.Replies) == 0 || next == offsetStr {
+			incomplete = fmt.Sprintf("第%d页没有主评论或分页游标未前进，但接口未返回末尾", page)
+			logger.GetLogger().Warnf("%s，停止爬取", incomplete)
+			break
+		}
+		offsetStr = next
+		page++
+	}
+
+	message := ""
+	if ctx.Err() != nil {
+		message = "已取消"
+		stopErr = ctx.Err()
+	} else if stopErr != nil {
+		message = stopErr.Error()
+	} else if incomplete != "" {
+		message = incomplete
+		stopErr = fmt.Errorf("%w: %s", model.ErrIncomplete, incomplete)
+	}
+	logger.GetLogger().Infof("*****增量爬取视频：%s结束，共获取 %d 条评论，刷新 %d 个楼层*****", oid, downloaded, refreshed)
+	opt.Progress.Emit(progress.Event{Type: progress.EventFinished, Bvid: bvid, Oid: avid,
+		Downloaded: downloaded, Total: total, Message: message})
+	return stopErr
+}
@@ -0,0 +1,39 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"bilibili-comments-viewer-go/crawler/blblcd/model"
+	"bilibili-comments-viewer-go/logger"
+)
+
+// archivePage 保存一页评论的原始响应，未配置存储或响应为空时跳过；保存失败只记录日志，不影响爬取
+func archivePage(opt *model.Option, page model.RawPage, resp *model.CommentResponse) {
+	if opt.RawPages == nil || len(resp.Raw) == 0 {
+		return
+	}
+	page.Body = resp.Raw
+	if err := opt.RawPages.SaveRawPage(&page); err != nil {
+		logger.GetLogger().Warnf("保存原始响应失败 (%s, %s 第%d页): %v", page.Bvid, page.Kind, page.Page, err)
+	}
+}
+
+// ParsePage 重新解析一页存档的评论接口响应，返回其中的全部评论：
+// 主评论与置顶评论，以及它们内嵌的回复
+func ParsePage(body []byte) ([]model.Comment, error) {
+	var resp model.CommentResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("解析原始响应失败: %w", err)
+	}
+
+	roots := append(resp.Data.Replies, resp.Data.TopReplies...)
+	comments := make([]model.Comment, 0, len(roots))
+	for i := range roots {
+		comments = append(comments, NewCMT(&roots[i]))
+		for j := range roots[i].Replies {
+			comments = append(comments, NewCMT(&roots[i].Replies[j]))
+		}
+	}
+	return comments, nil
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+
+	"bilibili-comments-viewer-go/crawler/bili_info/util"
+	"bilibili-comments-viewer-go/crawler/blblcd/model"
+	"bilibili-comments-viewer-go/crawler/blblcd/progress"
+	"bilibili-comments-viewer-go/logger"
+)
+
+// commentEmitter 对评论去重后写入输出通道，可被主评论遍历与子评论工作池并发调用
+type commentEmitter struct {
+	ctx  context.Context
+	out  chan<- model.Comment
+	mu   sync.Mutex
+	seen map[int64]bool
+	// sendMu 串行化写入与计数：sent 为 n 时输出通道的前 n 个位置都已写入，断点据此判断之前的评论是否已写入输出目标
+	sendMu sync.Mutex
+	sent   atomic.Int64 // 已写入输出通道的评论数
+}
+
+func newCommentEmitter(ctx context.Context, out chan<- model.Comment) *commentEmitter {
+	return &commentEmitter{ctx: ctx, out: out, seen: make(map[int64]bool)}
+}
+
+// emit 写入尚未输出过的评论，返回新增条数；ctx 取消时停止写入
+func (e *commentEmitter) emit(items []model.ReplyItem) int {
+	e.mu.Lock()
+	fresh := make([]model.Comment, 0, len(items))
+	for i := range items {
+		if e.seen[items[i].Rpid] {
+			continue
+		}
+		e.seen[items[i].Rpid] = true
+		fresh = append(fresh, NewCMT(&items[i]))
+	}
+	e.mu.Unlock()
+
+	e.sendMu.Lock()
+	defer e.sendMu.Unlock()
+	for i, cmt := range fresh {
+		select {
+		case e.out <- cmt:
+			e.sent.Add(1)
+		case <-e.ctx.Done():
+			return i
+		}
+	}
+	return len(fresh)
+}
+
+// maxRiskControlRetries 同一请求连续触发风控的最大次数，超过后停止爬取并保留断点，由调用方在熔断器关闭后继续
+// 每次触发后熔断器暂停所有请求，冷却时间逐次翻倍
+const maxRiskControlRetries = 6
+
+// riskControlHit 判断 err 是否为风控错误，是则发布风控事件（e 只需填写定位字段）
+func riskControlHit(opt *model.Option, e progress.Event, err error) bool {
+	rc, ok := util.AsRiskControl(err)
+	if !ok {
+		return false
+	}
+	e.Type = progress.EventRiskControl
+	e.Message = rc.Error()
+	opt.Progress.Emit(e)
+	return true
+}
+
+// needsSubFetch 判断楼层是否需要单独请求子评论：主评论列表只内嵌少量回复，不完整时需翻页获取
+func needsSubFetch(root model.ReplyItem) bool {
+	return root.Rcount > 0 && len(root.Replies) != root.Rcount
+}
+
+// subCommentPool 并发爬取子评论楼层的工作池，主评论遍历将需要翻页的楼层提交给工作池
+type subCommentPool struct {
+	ctx  context.Context
+	jobs chan model.ReplyItem
+	wg   sync.WaitGroup
+}
+
+// newSubCommentPool 启动 workers 个 worker，每个楼层的子评论爬取结束后调用 done（在 worker 协程中执行）
+// err 不为 nil 时 replies 只是部分子评论
+func newSubCommentPool(ctx context.Context, opt *model.Option, workers int, done func(root model.ReplyItem, replies []model.ReplyItem, err error)) *subCommentPool {
+	if workers <= 0 {
+		workers = 1
+	}
+	p := &subCommentPool{ctx: ctx, jobs: make(chan model.ReplyItem)}
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for root := range p.jobs {
+				func() {
+					defer func() {
+						if r := recover(); r != nil {
+							logger.GetLogger().Errorf("子评论 worker PANIC (rpid=%d): %v\n%s", root.Rpid, r, string(debug.Stack()))
+						}
+					}()
+					replies, err := FindSubComment(ctx, root, opt)
+					done(root, replies, err)
+				}()
+			}
+		}()
+	}
+	return p
+}
+
+// submit 提交一个楼层，所有 worker 忙碌时阻塞；ctx 取消时返回 false
+func (p *subCommentPool) submit(root model.ReplyItem) bool {
+	select {
+	case p.jobs <- root:
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
+// wait 停止接收楼层并等待已提交的楼层完成
+func (p *subCommentPool) wait() {
+	close(p.jobs)
+	p.wg.Wait()
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"bilibili-comments-viewer-go/crawler/blblcd/model"
+)
+
+// 并发写入时，emit 返回后读取的 sent 覆盖本次写入的全部评论在输出通道中的位置，
+// 否则据此保存的断点可能在之前的评论写入前被提交
+func TestCommentEmitterSentCoversEmitted(t *testing.T) {
+	const workers, batches, batchSize = 8, 200, 3
+	out := make(chan model.Comment, workers)
+	e := newCommentEmitter(context.Background(), out)
+
+	positions := make(map[int64]int) // rpid -> 输出通道中的位置
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for c := range out {
+			positions[c.Rpid] = len(positions)
+		}
+	}()
+
+	type emitted struct {
+		last int64 // 本次写入的最后一条评论
+		sent int   // emit 返回后读取的 sent
+	}
+	results := make([][]emitted, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for b := 0; b < batches; b++ {
+				items := make([]model.ReplyItem, batchSize)
+				for i := range items {
+					items[i].Rpid = int64((w*batches+b)*batchSize + i + 1)
+				}
+				e.emit(items)
+				results[w] = append(results[w], emitted{items[batchSize-1].Rpid, int(e.sent.Load())})
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(out)
+	<-done
+
+	for _, rs := range results {
+		for _, r := range rs {
+			if pos := positions[r.last]; pos >= r.sent {
+				t.Fatalf("评论 %d 位于输出通道第 %d 个位置，emit 返回后 sent 仅为 %d", r.last, pos, r.sent)
+			}
+		}
+	}
+	if got := int(e.sent.Load()); got != workers*batches*batchSize {
+		t.Errorf("sent=%d，期望 %d", got, workers*batches*batchSize)
+	}
+}
@@ -3,17 +3,18 @@ package blblcd
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"reflect"
 	"runtime"
 	"runtime/debug"
-	"sync"
 
 	"bilibili-comments-viewer-go/crawler/blblcd/core"
 	"bilibili-comments-viewer-go/crawler/blblcd/model"
 	"bilibili-comments-viewer-go/logger"
 )
 
-// CrawlVideo 爬取指定 bvid 视频的所有评论
+// CrawlVideo 爬取指定 bvid 视频的所有评论，opt.Incremental 不为 nil 时只爬取新增评论
 // ctx: 上下文，用于控制取消等
 // bvid: 视频的 BVID
 // opt: 爬取选项，包括并发数等
@@ -35,57 +36,84 @@ func CrawlVideo(ctx context.Context, bvid string, opt *model.Option) ([]model.Co
 	// 用于收集评论的通道，带缓冲区
 	resultChan := make(chan model.Comment, 1000)
 	var comments []model.Comment
-	// 控制并发的信号量，容量为 opt.Workers
-	sem := make(chan struct{}, opt.Workers)
-
-	// 创建独立的 WaitGroup 用于等待 FindComment 完成
-	var findWg sync.WaitGroup
-	findWg.Add(1)
 
-	// 启动 goroutine 进行评论查找
+	crawlErr := make(chan error, 1)
 	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				logger.GetLogger().Errorf("CrawlVideo goroutine PANIC: %v\n%s", r, string(debug.Stack()))
-			}
-			findWg.Done()
-		}()
-		// 将 bvid 转换为 avid
-		avid := core.Bvid2Avid(bvid)
-		// 调用核心查找评论逻辑，wg 传 nil 由内部管理
-		core.FindComment(ctx, sem, nil, int(avid), opt, resultChan)
-	}()
-
-	// 等待评论查找完成
-	findWg.Wait()
-
-	// 关闭通道并收集所有评论
-	close(resultChan)
-	defer func() {
-		for range resultChan {
-		} // 清空通道
+		crawlErr <- StreamVideo(ctx, bvid, opt, resultChan)
 	}()
 
+	// 边爬取边收集，避免评论数超过通道缓冲区时阻塞爬取
 	for comment := range resultChan {
 		comments = append(comments, comment)
 	}
 
+	// 被取消或未爬完时返回已获取的部分评论，由调用方决定是否保存
+	if err := <-crawlErr; err != nil {
+		logger.GetLogger().Infof("视频 %s 爬取中断: %v, 已获取 %d 条评论", bvid, err, len(comments))
+		return comments, err
+	}
+
 	// 记录爬取完成日志
 	logger.GetLogger().Infof("视频 %s 爬取完成, 共获取 %d 条评论", bvid, len(comments))
 
 	return comments, nil
 }
 
+// StreamVideo 爬取指定 bvid 视频的评论并逐条写入 out，爬取结束（完成、失败或 ctx 被取消）后关闭 out
+// 调用方应在另一个 goroutine 中持续消费 out，评论不会在内存中累积
+// ctx: 上下文，用于控制取消等
+// bvid: 视频的 BVID
+// opt: 爬取选项，opt.Incremental 不为 nil 时只爬取新增评论
+// out: 评论输出通道，由 StreamVideo 负责关闭
+// 返回值: 被取消时返回 ctx 错误，多次请求失败或触发风控而未爬完时返回错误（可用 util.AsRiskControl 判断）
+func StreamVideo(ctx context.Context, bvid string, opt *model.Option, out chan<- model.Comment) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.GetLogger().Errorf("StreamVideo PANIC: %v\n%s", r, string(debug.Stack()))
+			err = fmt.Errorf("爬取视频 %s 时发生panic: %v", bvid, r)
+		}
+		close(out)
+	}()
+
+	// 将 bvid 转换为 avid
+	avid := core.Bvid2Avid(bvid)
+	if opt.Incremental != nil {
+		// 增量爬取：只获取上次入库之后的新评论
+		return core.FindNewComments(ctx, avid, opt, out)
+	}
+	// 已预先占用调用方名额的信号量，FindComment 退出时释放
+	sem := core.NewVideoSem()
+	// 调用核心查找评论逻辑，wg 传 nil 由内部管理
+	return core.FindComment(ctx, sem, nil, avid, opt, out)
+}
+
 // CrawlUp 爬取指定 up 主（用户）的所有视频评论
+// ctx: 上下文，用于控制取消等
 // mid: up 主的 mid
 // opt: 爬取选项
-// 返回值: 错误信息
-func CrawlUp(mid int, opt *model.Option) error {
+// sinks: 为每个视频创建评论输出目标
+// 返回值: 错误信息，被取消时返回 ctx.Err()
+func CrawlUp(ctx context.Context, mid int64, opt *model.Option, sinks SinkFactory) error {
 	// 控制并发的信号量，容量为 opt.Workers
 	sem := make(chan struct{}, opt.Workers)
 	// 设置 up 主 mid
 	opt.Mid = mid
-	// 调用核心查找 up 主视频评论逻辑
-	core.FindUser(sem, opt)
-	return nil
+	// 断点在评论写入输出目标后才提交
+	commits := NewCheckpointCommitter(opt.Checkpoints, opt)
+	opt.Checkpoints = commits
+	// 调用核心查找 up 主视频评论逻辑，每个视频的评论写入各自的输出目标
+	core.FindUser(ctx, sem, opt, func(bvid string, comments <-chan model.Comment) {
+		targets, err := sinks(bvid)
+		if err != nil {
+			logger.GetLogger().Errorf("创建视频 %s 的输出目标失败: %v", bvid, err)
+		}
+		_, werr := WriteSinksAck(comments, targets, DefaultSinkBatchSize, DefaultSinkFlushInterval, func(written int) {
+			commits.Ack(bvid, written)
+		})
+		if werr != nil {
+			logger.GetLogger().Errorf("视频 %s 的评论写入失败: %v", bvid, werr)
+		}
+		commits.Finish(bvid, errors.Join(err, werr))
+	})
+	return ctx.Err()
 }
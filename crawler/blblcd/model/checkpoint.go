@@ -0,0 +1,31 @@
+package model
+
+// Checkpoint 视频评论爬取的断点，记录主评论分页游标与子评论楼层的完成情况
+type Checkpoint struct {
+	Bvid            string             `json:"bvid"`
+	Page            int                `json:"page"`             // 下一页页码
+	Offset          string             `json:"offset"`           // 下一页的分页游标，为空表示从第一页开始
+	DownloadedCount int                `json:"downloaded_count"` // 已输出的评论数
+	CompletedRoots  []int64            `json:"completed_roots"`  // 子评论已完整爬取的主评论 rpid
+	PendingThreads  []CheckpointThread `json:"pending_threads"`  // 已输出主评论但子评论尚未爬完的楼层
+
+	// Emitted 本次运行中保存断点时已写入输出通道的评论数，不持久化
+	// 断点只有在前 Emitted 条评论都写入输出目标后才能提交，否则恢复后会漏掉未写入的评论
+	Emitted int `json:"-"`
+}
+
+// CheckpointThread 未完成的子评论楼层
+type CheckpointThread struct {
+	Rpid   int64 `json:"rpid"`
+	Oid    int64 `json:"oid"`
+	Rcount int   `json:"rcount"`
+}
+
+// CheckpointStore 断点存储，FindComment 每爬完一页保存一次，正常结束后删除
+// 评论写入输出目标前就会调用 SaveCheckpoint 与 DeleteCheckpoint，需要等待写入确认时使用 blblcd.CheckpointCommitter
+type CheckpointStore interface {
+	// LoadCheckpoint 加载断点，不存在时返回 nil
+	LoadCheckpoint(bvid string) (*Checkpoint, error)
+	SaveCheckpoint(cp *Checkpoint) error
+	DeleteCheckpoint(bvid string) error
+}
@@ -25,35 +25,83 @@ type Cursor struct {
 	Name        string `json:"name"`
 }
 type Comment struct {
-    Uname         string    //姓名
-    Sex           string    //性别
-    Content       string    //评论内容
-    Rpid          int64     //评论id
-    Oid           int       //评论区id
-    Bvid          string    //视频bv
-    Mid           int       //发送者id
-    Parent        int       //父评论ID
-    Fansgrade     int       //是否粉丝标签
-    Ctime         int       //评论时间戳
-    Like          int       //喜欢数
-    Following     bool      //是否关注
-    Current_level int       //当前等级
-    Location      string    //位置
-    Pictures      []Picture // 图片
-    Replies       []ReplyItem // 添加回复字段
+	Uname         string            //姓名
+	Sex           string            //性别
+	Content       string            //评论内容
+	Rpid          int64             //评论id
+	Oid           int64             //评论区id
+	Bvid          string            //视频bv
+	Mid           int64             //发送者id
+	Root          int64             //所属主评论ID，主评论为0
+	Parent        int64             //父评论ID
+	Dialog        int64             //所属对话（楼中楼回复链）的首条评论ID
+	Fansgrade     int               //是否粉丝标签
+	Ctime         int               //评论时间戳
+	Like          int               //喜欢数
+	Rcount        int               //回复数（主评论）
+	Following     bool              //是否关注
+	Current_level int               //当前等级
+	Location      string            //位置
+	Invisible     bool              //是否被隐藏
+	Pinned        bool              //是否被UP主置顶
+	UpReplied     bool              //UP主是否回复过该评论
+	TimeDesc      string            //发布时间描述，如"3天前发布"
+	Avatar        string            //头像
+	Vip           Vip               //大会员信息
+	FansMedal     *FansMedal        //粉丝勋章，未佩戴时为 nil
+	Emotes        map[string]string //表情：文本 -> 图片地址
+	Members       []Member          //@提及的用户
+	Pictures      []Picture         // 图片
+	Replies       []ReplyItem       // 添加回复字段
+}
+
+// Vip 评论者的大会员信息
+type Vip struct {
+	Type   int    `json:"type"`   // 0: 无，1: 月度大会员，2: 年度及以上大会员
+	Status int    `json:"status"` // 1: 有效
+	Label  string `json:"label,omitempty"`
+}
+
+// FansMedal 评论者佩戴的粉丝勋章
+type FansMedal struct {
+	Name  string `json:"name"`
+	Level int    `json:"level"`
+}
+
+// Member 评论中@提及的用户
+type Member struct {
+	Mid   int64  `json:"mid"`
+	Uname string `json:"uname"`
 }
 
 type Picture struct {
 	Img_src string `json:"img_src"`
 }
+
+// Emote 评论中的表情
+type Emote struct {
+	ID        int64  `json:"id"`
+	PackageID int64  `json:"package_id"`
+	State     int    `json:"state"`
+	Type      int    `json:"type"`
+	Attr      int    `json:"attr"`
+	Text      string `json:"text"`
+	URL       string `json:"url"`
+	Meta      struct {
+		Size int `json:"size"`
+	} `json:"meta"`
+	Mtime     int64  `json:"mtime"`
+	JumpTitle string `json:"jump_title"`
+}
+
 type ReplyItem struct {
 	Rpid      int64  `json:"rpid"`
-	Oid       int    `json:"oid"`
+	Oid       int64  `json:"oid"`
 	Type      int    `json:"type"`
-	Mid       int    `json:"mid"`
-	Root      int    `json:"root"`
-	Parent    int    `json:"parent"`
-	Dialog    int    `json:"dialog"`
+	Mid       int64  `json:"mid"`
+	Root      int64  `json:"root"`
+	Parent    int64  `json:"parent"`
+	Dialog    int64  `json:"dialog"`
 	Count     int    `json:"count"`
 	Rcount    int    `json:"rcount"`
 	State     int    `json:"state"`
@@ -87,36 +135,37 @@ type ReplyItem struct {
 			AccessStatus  int    `json:"accessStatus"`
 			VipStatus     int    `json:"vipStatus"`
 			VipStatusWarn string `json:"vipStatusWarn"`
+			Label         struct {
+				Text string `json:"text"`
+			} `json:"label"`
 		} `json:"vip"`
-		FansDetail any `json:"fans_detail"`
+		FansDetail *struct {
+			UID       int64  `json:"uid"`
+			MedalID   int64  `json:"medal_id"`
+			MedalName string `json:"medal_name"`
+			Level     int    `json:"level"`
+		} `json:"fans_detail"`
 	} `json:"member"`
 	Content struct {
 		Message  string    `json:"message"`
 		Pictures []Picture `json:"pictures"`
-		Members  []any     `json:"members"`
-		Emote    struct {
-			NAMING_FAILED struct {
-				ID        int    `json:"id"`
-				PackageID int    `json:"package_id"`
-				State     int    `json:"state"`
-				Type      int    `json:"type"`
-				Attr      int    `json:"attr"`
-				Text      string `json:"text"`
-				URL       string `json:"url"`
-				Meta      struct {
-					Size int `json:"size"`
-				} `json:"meta"`
-				Mtime     int    `json:"mtime"`
-				JumpTitle string `json:"jump_title"`
-			} `json:"[吃瓜]"`
-		} `json:"emote"`
+		Members  []struct {
+			Mid   string `json:"mid"`
+			Uname string `json:"uname"`
+		} `json:"members"`
+		Emote   map[string]Emote `json:"emote"` // 表情文本（如 [吃瓜]）-> 表情
 		JumpURL struct {
 		} `json:"jump_url"`
 		MaxLine int `json:"max_line"`
 	} `json:"content"`
-	Replies      []ReplyItem `json:"replies"`
-	Invisible    bool        `json:"invisible"`
+	Replies   []ReplyItem `json:"replies"`
+	Invisible bool        `json:"invisible"`
+	UpAction  struct {
+		Like  bool `json:"like"`
+		Reply bool `json:"reply"`
+	} `json:"up_action"`
 	ReplyControl struct {
+		IsUpTop   bool   `json:"is_up_top"`
 		Following bool   `json:"following"`
 		MaxLine   int    `json:"max_line"`
 		TimeDesc  string `json:"time_desc"`
@@ -125,6 +174,7 @@ type ReplyItem struct {
 }
 
 type CommentResponse struct {
+	Raw     []byte `json:"-"` // 原始响应体，用于存档后重新解析
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 	TTL     int    `json:"ttl"`
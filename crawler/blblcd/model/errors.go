@@ -0,0 +1,7 @@
+package model
+
+import "errors"
+
+// ErrIncomplete 爬取正常结束，但无法确认已获取全部评论（如分页游标未前进、接口未返回末尾却没有更多评论）
+// 评论已全部输出，断点已删除；调用方不应据此推断未出现的评论已被删除
+var ErrIncomplete = errors.New("未能确认已爬取全部评论")
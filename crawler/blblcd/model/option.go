@@ -1,8 +1,17 @@
 package model
 
+import "bilibili-comments-viewer-go/crawler/blblcd/progress"
+
+// 评论排序方式
+const (
+	CorderTime  = 0 // 按时间
+	CorderLike  = 1 // 按点赞数
+	CorderReply = 2 // 按回复数
+)
+
 type Option struct {
 	Cookie        string
-	Mid           int
+	Mid           int64
 	Pages         int
 	Skip          int
 	Vorder        string
@@ -14,20 +23,47 @@ type Option struct {
 	CommentOutput string
 	ImageOutput   string
 	Workers       int
-	FetchAll      bool // 新增：是否爬取所有视频
-	DelayBaseMs   int  // 新增
-	DelayJitterMs int  // 新增
+	FetchAll      bool               // 新增：是否爬取所有视频
+	DelayBaseMs   int                // 新增
+	DelayJitterMs int                // 新增
+	Progress      *progress.Reporter // 进度事件发布者，可为 nil
+	Incremental   *IncrementalState  // 增量爬取状态，为 nil 时全量爬取
+	Checkpoints   CheckpointStore    // 断点存储，为 nil 时使用评论输出目录下的 progress.json
+	RawPages      RawPageStore       // 原始响应存储，为 nil 时不保存
+}
+
+// IncrementalState 增量爬取所需的已入库评论信息，由调用方从存储中加载
+type IncrementalState struct {
+	KnownRoots  map[int64]int // 已入库主评论 rpid -> 入库时的回复数
+	LatestCtime int           // 已入库主评论的最新发布时间
+	ExtraPages  int           // 到达已入库评论的那一页之后继续翻页的页数，用于刷新旧楼层的回复数，0 为在该页停止
+}
+
+// Reached 判断按时间倒序翻页时是否已到达已入库的评论
+func (s *IncrementalState) Reached(rpid int64, ctime int) bool {
+	if _, ok := s.KnownRoots[rpid]; ok {
+		return true
+	}
+	// 同一秒内可能有未入库的评论，因此只有严格早于最新时间才视为已到达
+	return s.LatestCtime > 0 && ctime < s.LatestCtime
+}
+
+// RcountChanged 判断主评论的回复数是否与入库时不同，新评论视为已变化
+func (s *IncrementalState) RcountChanged(rpid int64, rcount int) bool {
+	stored, ok := s.KnownRoots[rpid]
+	return !ok || stored != rcount
 }
 
 // 新增构造函数确保默认值
 func NewDefaultOption() *Option {
 	return &Option{
-		Pages:         10,        // 默认获取10页视频
-		Vorder:        "pubdate", // 默认按最新发布排序
-		Workers:       5,         // 默认5个协程
-		MaxTryCount:   3,         // 默认重试3次
-		FetchAll:      false,     // 默认不爬取所有视频
-		DelayBaseMs:   3000,      // 新增默认值
-		DelayJitterMs: 2000,      // 新增默认值
+		Pages:         10,         // 默认获取10页视频
+		Vorder:        "pubdate",  // 默认按最新发布排序
+		Corder:        CorderLike, // 默认按热度排序
+		Workers:       5,          // 默认5个协程
+		MaxTryCount:   3,          // 默认重试3次
+		FetchAll:      false,      // 默认不爬取所有视频
+		DelayBaseMs:   3000,       // 新增默认值
+		DelayJitterMs: 2000,       // 新增默认值
 	}
 }
@@ -0,0 +1,23 @@
+package model
+
+// 原始响应页的类型
+const (
+	RawPageMain  = "main"  // 主评论列表
+	RawPageReply = "reply" // 子评论列表
+)
+
+// RawPage 一页评论接口的原始响应，保存后可在不重新爬取的情况下重新解析
+type RawPage struct {
+	Bvid   string
+	Oid    int64
+	Kind   string // RawPageMain 或 RawPageReply
+	Root   int64  // 子评论页所属的主评论 rpid，主评论页为 0
+	Page   int    // 页码
+	Offset string // 主评论页请求时的分页游标
+	Body   []byte // 原始响应体
+}
+
+// RawPageStore 原始响应存储，每成功获取一页评论保存一次
+type RawPageStore interface {
+	SaveRawPage(page *RawPage) error
+}
@@ -11,11 +11,11 @@ type VideoItem struct {
 	Title            string `json:"title"`
 	Review           int    `json:"review"`
 	Author           string `json:"author"`
-	Mid              int    `json:"mid"`
+	Mid              int64  `json:"mid"`
 	Created          int    `json:"created"`
 	Length           string `json:"length"`
 	VideoReview      int    `json:"video_review"`
-	Aid              int    `json:"aid"`
+	Aid              int64  `json:"aid"`
 	Bvid             string `json:"bvid"`
 	HideClick        bool   `json:"hide_click"`
 	IsPay            int    `json:"is_pay"`
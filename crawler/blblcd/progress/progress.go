@@ -0,0 +1,137 @@
+// progress 包提供爬取进度事件总线，爬虫核心流程发布事件，Web 层按任务订阅并推送给前端
+package progress
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType 进度事件类型
+type EventType string
+
+const (
+	EventStart         EventType = "start"          // 开始爬取某个视频，Total 为评论总数
+	EventPageDone      EventType = "page_done"      // 一页主评论处理完成
+	EventCommentsAdded EventType = "comments_added" // 新增评论写入结果
+	EventSubComment    EventType = "sub_comment"    // 一页子评论获取完成
+	EventRetry         EventType = "retry"          // 请求失败，等待重试或跳过
+	EventRiskControl   EventType = "risk_control"   // 触发B站风控，所有爬取暂停等待冷却后重试
+	EventVideoList     EventType = "video_list"     // UP主视频列表一页获取完成
+	EventFinished      EventType = "finished"       // 某个视频爬取结束
+	EventJobStatus     EventType = "job_status"     // 任务状态变化
+)
+
+// Event 进度事件
+type Event struct {
+	Type       EventType `json:"type"`
+	Time       time.Time `json:"time"`
+	Bvid       string    `json:"bvid,omitempty"`
+	Oid        int64     `json:"oid,omitempty"`
+	Page       int       `json:"page,omitempty"`
+	Rpid       int64     `json:"rpid,omitempty"` // 子评论所属主评论
+	Added      int       `json:"added,omitempty"`
+	Downloaded int       `json:"downloaded,omitempty"`
+	Total      int       `json:"total,omitempty"`
+	Percent    float64   `json:"percent,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	Message    string    `json:"message,omitempty"`
+}
+
+// subscriberBuffer 每个订阅者的缓冲区大小，缓冲区满时丢弃事件，保证发布方不被阻塞
+const subscriberBuffer = 64
+
+// Bus 按主题（任务ID）分发进度事件
+type Bus struct {
+	mu   sync.Mutex
+	subs map[int64]map[chan Event]struct{}
+	last map[int64]Event
+}
+
+// NewBus 创建事件总线
+func NewBus() *Bus {
+	return &Bus{
+		subs: make(map[int64]map[chan Event]struct{}),
+		last: make(map[int64]Event),
+	}
+}
+
+// Default 全局事件总线
+var Default = NewBus()
+
+// Publish 发布事件，不会阻塞
+func (b *Bus) Publish(topic int64, e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.last[topic] = e
+	for ch := range b.subs[topic] {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe 订阅主题，返回事件通道、最近一次事件（若有）以及取消订阅函数
+func (b *Bus) Subscribe(topic int64) (<-chan Event, *Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subs[topic] == nil {
+		b.subs[topic] = make(map[chan Event]struct{})
+	}
+	b.subs[topic][ch] = struct{}{}
+	var last *Event
+	if e, ok := b.last[topic]; ok {
+		last = &e
+	}
+	b.mu.Unlock()
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[topic][ch]; ok {
+			delete(b.subs[topic], ch)
+			close(ch)
+			if len(b.subs[topic]) == 0 {
+				delete(b.subs, topic)
+			}
+		}
+	}
+	return ch, last, cancel
+}
+
+// Close 结束主题：关闭所有订阅者通道并清理状态
+func (b *Bus) Close(topic int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs[topic] {
+		close(ch)
+	}
+	delete(b.subs, topic)
+	delete(b.last, topic)
+}
+
+// Reporter 绑定到单个主题的发布者，nil Reporter 的所有方法均为空操作
+type Reporter struct {
+	bus   *Bus
+	topic int64
+}
+
+// NewReporter 创建发布到指定主题的 Reporter
+func NewReporter(bus *Bus, topic int64) *Reporter {
+	return &Reporter{bus: bus, topic: topic}
+}
+
+// Emit 发布事件
+func (r *Reporter) Emit(e Event) {
+	if r == nil || r.bus == nil {
+		return
+	}
+	r.bus.Publish(r.topic, e)
+}
@@ -0,0 +1,172 @@
+package blblcd
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"bilibili-comments-viewer-go/crawler/blblcd/model"
+	"bilibili-comments-viewer-go/logger"
+)
+
+// Sink 评论输出目标（CSV 文件、数据库等）
+// 每个 Sink 只由一个写入 goroutine 依次调用 Open、Write、Close，实现无需自行加锁
+// Write 收到的批次会同时分发给其他 Sink，实现不得修改其中的评论
+type Sink interface {
+	Open() error
+	Write(batch []model.Comment) error
+	Close() error
+}
+
+// SinkFactory 为指定视频创建输出目标，用于UP主爬取等多视频场景
+type SinkFactory func(bvid string) ([]Sink, error)
+
+// 默认批次参数
+const (
+	DefaultSinkBatchSize     = 200
+	DefaultSinkFlushInterval = 3 * time.Second
+)
+
+// sinkQueueSize 每个 Sink 待写入批次的队列长度，写入较慢时反压到爬虫
+const sinkQueueSize = 4
+
+// WriteSinks 从 in 读取评论直到通道关闭，按批（达到 batchSize 条或每隔 flushInterval）分发给所有 sink
+// 每个 sink 由独立的 goroutine 写入；某个 sink 打开或写入失败时继续消费，避免阻塞爬虫
+// 返回读取到的评论数和各 sink 的错误
+func WriteSinks(in <-chan model.Comment, sinks []Sink, batchSize int, flushInterval time.Duration) (int, error) {
+	return WriteSinksAck(in, sinks, batchSize, flushInterval, nil)
+}
+
+// WriteSinksAck 与 WriteSinks 相同，每当所有 sink 都写入了更多评论时以累计条数（按读取顺序）调用 ack
+// 任一 sink 失败后不再调用 ack；ack 依次调用，可为 nil
+func WriteSinksAck(in <-chan model.Comment, sinks []Sink, batchSize int, flushInterval time.Duration, ack func(written int)) (int, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultSinkBatchSize
+	}
+	if flushInterval <= 0 {
+		flushInterval = DefaultSinkFlushInterval
+	}
+
+	var ackMu sync.Mutex
+	written := make([]int, len(sinks)) // 各 sink 已写入的评论数
+	acked := 0
+	failed := false
+	onWritten := func(i, n int, err error) {
+		ackMu.Lock()
+		defer ackMu.Unlock()
+		if err != nil {
+			failed = true
+			return
+		}
+		written[i] += n
+		if failed || ack == nil {
+			return
+		}
+		low := written[0]
+		for _, w := range written[1:] {
+			low = min(low, w)
+		}
+		if low > acked {
+			acked = low
+			ack(acked)
+		}
+	}
+
+	queues := make([]chan []model.Comment, len(sinks))
+	errs := make([]error, len(sinks))
+	var wg sync.WaitGroup
+	for i, sink := range sinks {
+		queues[i] = make(chan []model.Comment, sinkQueueSize)
+		wg.Add(1)
+		go func(i int, sink Sink) {
+			defer wg.Done()
+			errs[i] = runSink(sink, queues[i], func(n int, err error) { onWritten(i, n, err) })
+		}(i, sink)
+	}
+
+	count := 0
+	batch := make([]model.Comment, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		for _, q := range queues {
+			q <- batch
+		}
+		// 批次已交给各 sink，重新分配而不是复用底层数组
+		batch = make([]model.Comment, 0, batchSize)
+	}
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+loop:
+	for {
+		select {
+		case cmt, ok := <-in:
+			if !ok {
+				break loop
+			}
+			count++
+			batch = append(batch, cmt)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+	flush()
+
+	for _, q := range queues {
+		close(q)
+	}
+	wg.Wait()
+	return count, errors.Join(errs...)
+}
+
+// runSink 单个 sink 的写入循环，返回第一次失败的错误
+// written 在每批写入后以该批条数调用，失败时以错误调用
+func runSink(sink Sink, queue <-chan []model.Comment, written func(n int, err error)) (err error) {
+	log := logger.GetLogger()
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("输出目标 %T PANIC: %v\n%s", sink, r, string(debug.Stack()))
+			err = fmt.Errorf("输出目标 %T panic: %v", sink, r)
+			written(0, err)
+			// 排空队列，避免分发端阻塞
+			for range queue {
+			}
+		}
+	}()
+
+	if err := sink.Open(); err != nil {
+		log.Errorf("打开输出目标 %T 失败: %v", sink, err)
+		written(0, err)
+		for range queue {
+		}
+		return fmt.Errorf("打开输出目标失败: %w", err)
+	}
+
+	for batch := range queue {
+		if werr := sink.Write(batch); werr != nil {
+			log.Errorf("写入输出目标 %T 失败: %v", sink, werr)
+			written(0, werr)
+			if err == nil {
+				err = fmt.Errorf("写入输出目标失败: %w", werr)
+			}
+			continue
+		}
+		written(len(batch), nil)
+	}
+
+	if cerr := sink.Close(); cerr != nil {
+		log.Errorf("关闭输出目标 %T 失败: %v", sink, cerr)
+		if err == nil {
+			err = fmt.Errorf("关闭输出目标失败: %w", cerr)
+		}
+	}
+	return err
+}
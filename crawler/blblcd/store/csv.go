@@ -2,11 +2,11 @@ package store
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
-	"strings"
 
 	"bilibili-comments-viewer-go/crawler/blblcd/model"
 	"bilibili-comments-viewer-go/crawler/blblcd/utils"
@@ -21,95 +21,112 @@ func parseInt(num int) string {
 	return strconv.Itoa(num)
 }
 
+// jsonField 将表情、@提及等结构化字段编码为 JSON 写入单个单元格，为空时写入空字符串
+func jsonField[T any](v []T) string {
+	if len(v) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
 func CMT2Record(cmt model.Comment) (record []string) {
 	picURLs := ""
 	for _, pic := range cmt.Pictures {
 		picURLs += pic.Img_src + ";"
 	}
+	emotes := ""
+	if len(cmt.Emotes) > 0 {
+		b, _ := json.Marshal(cmt.Emotes)
+		emotes = string(b)
+	}
+	medalName, medalLevel := "", 0
+	if cmt.FansMedal != nil {
+		medalName, medalLevel = cmt.FansMedal.Name, cmt.FansMedal.Level
+	}
 	return []string{
 		cmt.Bvid, cmt.Uname, cmt.Sex, cmt.Content, picURLs,
-		parseInt64(cmt.Rpid), parseInt(cmt.Oid), parseInt(cmt.Mid),
-		parseInt(cmt.Parent), parseInt(cmt.Fansgrade), parseInt(cmt.Ctime),
+		parseInt64(cmt.Rpid), parseInt64(cmt.Oid), parseInt64(cmt.Mid),
+		parseInt64(cmt.Parent), parseInt(cmt.Fansgrade), parseInt(cmt.Ctime),
 		parseInt(cmt.Like), fmt.Sprint(cmt.Following), parseInt(cmt.Current_level), cmt.Location,
+		parseInt(cmt.Rcount), parseInt64(cmt.Root), parseInt64(cmt.Dialog), fmt.Sprint(cmt.Invisible), cmt.TimeDesc,
+		cmt.Avatar, parseInt(cmt.Vip.Type), parseInt(cmt.Vip.Status), cmt.Vip.Label,
+		medalName, parseInt(medalLevel), emotes, jsonField(cmt.Members),
+		fmt.Sprint(cmt.Pinned), fmt.Sprint(cmt.UpReplied),
 	}
 }
 
-func Save2CSV(filename string, cmts []model.Comment, output string, imgOutput string, downloadIMG bool) {
-	defer func() {
-		if err := recover(); err != nil {
-			logger.GetLogger().Error("写入CSV错误:", err)
-		}
-	}()
-	utils.PresetPath(output)
-	if len(cmts) == 0 {
-		return
-	}
-	csv_path := filepath.Join(output, filename+".csv")
-	if utils.FileOrPathExists(csv_path) {
-		file, err := os.OpenFile(csv_path, os.O_WRONLY|os.O_APPEND, 0644)
-		if err != nil {
-			logger.GetLogger().Errorf("打开csv文件错误，oid:%d", cmts[0].Oid)
-			return
-		}
-		defer file.Close()
+// CSVHeader CSV 文件的列名，与 CMT2Record 的字段顺序一致
+// emotes 为 {"[表情]": "图片地址"} 形式的 JSON，members 为 [{"mid":..,"uname":..}] 形式的 JSON
+var CSVHeader = []string{"bvid", "upname", "sex", "content", "pictures", "rpid", "oid", "mid",
+	"parent", "fans_grade", "ctime", "like", "following", "level", "location",
+	"rcount", "root", "dialog", "invisible", "time_desc",
+	"avatar", "vip_type", "vip_status", "vip_label",
+	"fans_medal", "fans_medal_level", "emotes", "members", "pinned", "up_replied"}
 
-		writer := csv.NewWriter(file)
-		defer writer.Flush()
+// CSVSink 将评论写入单个 CSV 文件
+type CSVSink struct {
+	path       string
+	appendMode bool
+	file       *os.File
+	writer     *csv.Writer
+}
 
-		for _, cmt := range cmts {
-			if cmt.Uname == "" {
-				continue
-			}
-			if downloadIMG {
-				if len(cmt.Pictures) != 0 {
-					go WriteImage(cmt.Uname, cmt.Pictures, output+"/"+"images")
-				}
-			}
+// NewCSVSink 创建 CSV 输出目标，appendMode 为 true 且文件已存在时追加写入（断点续爬、增量爬取），否则重新创建文件
+func NewCSVSink(path string, appendMode bool) *CSVSink {
+	return &CSVSink{path: path, appendMode: appendMode}
+}
 
-			record := CMT2Record(cmt)
-			err = writer.Write(record)
-			if err != nil {
-				logger.GetLogger().Errorf("追加评论至csv文件错误，oid:%d", cmt.Oid)
-			}
-		}
+func (s *CSVSink) Open() error {
+	utils.PresetPath(filepath.Dir(s.path))
 
-		logger.GetLogger().Infof("追加评论至csv文件成功，oid:%d", cmts[0].Oid)
+	writeHeader := true
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if s.appendMode && utils.FileOrPathExists(s.path) {
+		flags = os.O_WRONLY | os.O_APPEND
+		writeHeader = false
+	}
+	file, err := os.OpenFile(s.path, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("打开csv文件错误: %w", err)
+	}
+	s.file = file
+	s.writer = csv.NewWriter(file)
 
-	} else {
-		file, err := os.Create(csv_path)
-		if err != nil {
-			logger.GetLogger().Errorf("创建csv文件错误，oid:%d", cmts[0].Oid)
-			return
+	if writeHeader {
+		if err := s.writer.Write(CSVHeader); err != nil {
+			return fmt.Errorf("写入csv文件字段错误: %w", err)
 		}
-		defer file.Close()
+	}
+	return nil
+}
 
-		writer := csv.NewWriter(file)
-		defer writer.Flush()
-		headers := "bvid,upname,sex,content,pictures,rpid,oid,mid,parent,fans_grade,ctime,like,following,level,location"
-		headerErr := writer.Write(strings.Split(headers, ","))
-		if headerErr != nil {
-			logger.GetLogger().Errorf("写入csv文件字段错误，oid:%d", cmts[0].Oid)
-			return
+func (s *CSVSink) Write(batch []model.Comment) error {
+	for _, cmt := range batch {
+		if cmt.Uname == "" {
+			continue
 		}
-
-		for _, cmt := range cmts {
-			if cmt.Uname == "" {
-				continue
-			}
-			if downloadIMG {
-				if len(cmt.Pictures) != 0 {
-					go WriteImage(cmt.Uname, cmt.Pictures, imgOutput)
-				}
-			}
-
-			record := CMT2Record(cmt)
-			err := writer.Write(record)
-			if err != nil {
-				logger.GetLogger().Errorf("写入csv文件错误，oid:%d", cmt.Oid)
-				return
-			}
+		if err := s.writer.Write(CMT2Record(cmt)); err != nil {
+			return fmt.Errorf("写入csv文件错误，rpid:%d: %w", cmt.Rpid, err)
 		}
-		logger.GetLogger().Infof("写入csv文件成功，oid:%d", cmts[0].Oid)
 	}
+	// 每批写完立即落盘，中断时文件中只会缺少未写入的批次
+	s.writer.Flush()
+	if err := s.writer.Error(); err != nil {
+		return fmt.Errorf("写入csv文件错误: %w", err)
+	}
+	logger.GetLogger().Debugf("已写入 %d 条评论至csv文件: %s", len(batch), s.path)
+	return nil
+}
 
+func (s *CSVSink) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	s.writer.Flush()
+	err := s.writer.Error()
+	if cerr := s.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
 }
@@ -99,3 +99,30 @@ func WriteImage(bvid string, pics []model.Picture, output string) {
 	}
 	wg.Wait()
 }
+
+// ImageSink 下载评论中的图片，按BV号分目录保存
+type ImageSink struct {
+	output string
+}
+
+// NewImageSink 创建图片下载输出目标
+func NewImageSink(output string) *ImageSink {
+	return &ImageSink{output: output}
+}
+
+func (s *ImageSink) Open() error {
+	return os.MkdirAll(s.output, os.ModePerm)
+}
+
+func (s *ImageSink) Write(batch []model.Comment) error {
+	for _, cmt := range batch {
+		if len(cmt.Pictures) > 0 && cmt.Bvid != "" {
+			WriteImage(cmt.Bvid, cmt.Pictures, s.output)
+		}
+	}
+	return nil
+}
+
+func (s *ImageSink) Close() error {
+	return nil
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"bilibili-comments-viewer-go/crawler/blblcd/model"
+	"bilibili-comments-viewer-go/crawler/blblcd/utils"
+)
+
+// ndjsonRecord NDJSON 每行的评论结构，字段名与 CSV 列名一致
+type ndjsonRecord struct {
+	Bvid      string   `json:"bvid"`
+	Upname    string   `json:"upname"`
+	Sex       string   `json:"sex"`
+	Content   string   `json:"content"`
+	Pictures  []string `json:"pictures,omitempty"`
+	Rpid      int64    `json:"rpid"`
+	Oid       int64    `json:"oid"`
+	Mid       int64    `json:"mid"`
+	Parent    int64    `json:"parent"`
+	FansGrade int      `json:"fans_grade"`
+	Ctime     int      `json:"ctime"`
+	Like      int      `json:"like"`
+	Rcount    int      `json:"rcount"`
+	Following bool     `json:"following"`
+	Level     int      `json:"level"`
+	Location  string   `json:"location"`
+
+	Root      int64             `json:"root"`
+	Dialog    int64             `json:"dialog"`
+	Invisible bool              `json:"invisible"`
+	Pinned    bool              `json:"pinned"`
+	UpReplied bool              `json:"up_replied"`
+	TimeDesc  string            `json:"time_desc,omitempty"`
+	Avatar    string            `json:"avatar,omitempty"`
+	Vip       model.Vip         `json:"vip"`
+	FansMedal *model.FansMedal  `json:"fans_medal,omitempty"`
+	Emotes    map[string]string `json:"emotes,omitempty"`
+	Members   []model.Member    `json:"members,omitempty"`
+}
+
+// NDJSONSink 将评论按行写入 JSON 文件（每行一条评论）
+type NDJSONSink struct {
+	path       string
+	appendMode bool
+	file       *os.File
+	buf        *bufio.Writer
+	enc        *json.Encoder
+}
+
+// NewNDJSONSink 创建 NDJSON 输出目标，appendMode 为 true 时追加到已有文件，否则重新创建文件
+func NewNDJSONSink(path string, appendMode bool) *NDJSONSink {
+	return &NDJSONSink{path: path, appendMode: appendMode}
+}
+
+func (s *NDJSONSink) Open() error {
+	utils.PresetPath(filepath.Dir(s.path))
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if s.appendMode {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	file, err := os.OpenFile(s.path, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("打开ndjson文件错误: %w", err)
+	}
+	s.file = file
+	s.buf = bufio.NewWriter(file)
+	s.enc = json.NewEncoder(s.buf)
+	s.enc.SetEscapeHTML(false)
+	return nil
+}
+
+func (s *NDJSONSink) Write(batch []model.Comment) error {
+	for _, cmt := range batch {
+		record := ndjsonRecord{
+			Bvid: cmt.Bvid, Upname: cmt.Uname, Sex: cmt.Sex, Content: cmt.Content,
+			Rpid: cmt.Rpid, Oid: cmt.Oid, Mid: cmt.Mid, Parent: cmt.Parent,
+			FansGrade: cmt.Fansgrade, Ctime: cmt.Ctime, Like: cmt.Like, Rcount: cmt.Rcount,
+			Following: cmt.Following, Level: cmt.Current_level, Location: cmt.Location,
+			Root: cmt.Root, Dialog: cmt.Dialog, Invisible: cmt.Invisible, Pinned: cmt.Pinned, UpReplied: cmt.UpReplied, TimeDesc: cmt.TimeDesc,
+			Avatar: cmt.Avatar, Vip: cmt.Vip, FansMedal: cmt.FansMedal, Emotes: cmt.Emotes, Members: cmt.Members,
+		}
+		for _, pic := range cmt.Pictures {
+			record.Pictures = append(record.Pictures, pic.Img_src)
+		}
+		if err := s.enc.Encode(&record); err != nil {
+			return fmt.Errorf("写入ndjson文件错误，rpid:%d: %w", cmt.Rpid, err)
+		}
+	}
+	if err := s.buf.Flush(); err != nil {
+		return fmt.Errorf("写入ndjson文件错误: %w", err)
+	}
+	return nil
+}
+
+func (s *NDJSONSink) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.buf.Flush()
+	if cerr := s.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CrawlCheckpoint 视频评论爬取断点，用于任务取消或服务重启后继续爬取
+type CrawlCheckpoint struct {
+	Bvid            string             `json:"bvid"`
+	Page            int                `json:"page"`             // 下一页页码
+	Offset          string             `json:"offset"`           // 下一页的分页游标
+	DownloadedCount int                `json:"downloaded_count"` // 已输出的评论数
+	CompletedRoots  []int64            `json:"completed_roots"`  // 子评论已完整爬取的主评论 rpid
+	PendingThreads  []CheckpointThread `json:"pending_threads"`  // 子评论尚未爬完的楼层
+	UpdatedAt       time.Time          `json:"updated_at"`
+}
+
+// CheckpointThread 未完成的子评论楼层
+type CheckpointThread struct {
+	Rpid   int64 `json:"rpid"`
+	Oid    int64 `json:"oid"`
+	Rcount int   `json:"rcount"`
+}
+
+// createCheckpointsTable 创建爬取断点表
+func createCheckpointsTable(tx *sql.Tx) error {
+	checkpointTableSQL := `
+	CREATE TABLE IF NOT EXISTS crawl_checkpoints (
+		bvid TEXT PRIMARY KEY,
+		page INTEGER NOT NULL DEFAULT 1,
+		cursor_offset TEXT NOT NULL DEFAULT '',
+		downloaded_count INTEGER NOT NULL DEFAULT 0,
+		completed_roots TEXT NOT NULL DEFAULT '[]', -- JSON 数组
+		pending_threads TEXT NOT NULL DEFAULT '[]', -- JSON 数组
+		updated_at INTEGER NOT NULL
+	);`
+
+	if _, err := tx.Exec(checkpointTableSQL); err != nil {
+		return fmt.Errorf("创建爬取断点表失败: %w", err)
+	}
+	return nil
+}
+
+// GetCrawlCheckpoint 获取视频的爬取断点，不存在时返回 nil
+func GetCrawlCheckpoint(bvid string) (*CrawlCheckpoint, error) {
+	var cp CrawlCheckpoint
+	var completed, pending string
+	var updatedAt int64
+	err := db.QueryRow(`
+		SELECT bvid, page, cursor_offset, downloaded_count, completed_roots, pending_threads, updated_at
+		FROM crawl_checkpoints WHERE bvid = ?`, bvid,
+	).Scan(&cp.Bvid, &cp.Page, &cp.Offset, &cp.DownloadedCount, &completed, &pending, &updatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询爬取断点失败: %w", err)
+	}
+	if err := json.Unmarshal([]byte(completed), &cp.CompletedRoots); err != nil {
+		return nil, fmt.Errorf("解析已完成楼层失败: %w", err)
+	}
+	if err := json.Unmarshal([]byte(pending), &cp.PendingThreads); err != nil {
+		return nil, fmt.Errorf("解析未完成楼层失败: %w", err)
+	}
+	cp.UpdatedAt = time.Unix(updatedAt, 0)
+	return &cp, nil
+}
+
+// SaveCrawlCheckpoint 保存视频的爬取断点（存在则覆盖）
+func SaveCrawlCheckpoint(cp *CrawlCheckpoint) error {
+	completed := cp.CompletedRoots
+	if completed == nil {
+		completed = []int64{}
+	}
+	pending := cp.PendingThreads
+	if pending == nil {
+		pending = []CheckpointThread{}
+	}
+	completedJSON, err := json.Marshal(completed)
+	if err != nil {
+		return fmt.Errorf("序列化已完成楼层失败: %w", err)
+	}
+	pendingJSON, err := json.Marshal(pending)
+	if err != nil {
+		return fmt.Errorf("序列化未完成楼层失败: %w", err)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO crawl_checkpoints (bvid, page, cursor_offset, downloaded_count, completed_roots, pending_threads, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(bvid) DO UPDATE SET
+			page = excluded.page,
+			cursor_offset = excluded.cursor_offset,
+			downloaded_count = excluded.downloaded_count,
+			completed_roots = excluded.completed_roots,
+			pending_threads = excluded.pending_threads,
+			updated_at = excluded.updated_at`,
+		cp.Bvid, cp.Page, cp.Offset, cp.DownloadedCount, string(completedJSON), string(pendingJSON), time.Now().Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("保存爬取断点失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteCrawlCheckpoint 删除视频的爬取断点
+func DeleteCrawlCheckpoint(bvid string) error {
+	if _, err := db.Exec(`DELETE FROM crawl_checkpoints WHERE bvid = ?`, bvid); err != nil {
+		return fmt.Errorf("删除爬取断点失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// CommentSnapshot 评论在一次变化之前的状态
+type CommentSnapshot struct {
+	ObservedAt time.Time `json:"observed_at"`       // 观察到变化的时间，此前的状态即本快照
+	SeenAt     time.Time `json:"seen_at"`           // 本快照的状态最后一次被观察到的时间
+	Content    *string   `json:"content,omitempty"` // 变化前的内容，只有点赞数变化时为空
+	LikeCount  int       `json:"like_count"`        // 变化前的点赞数
+}
+
+// CommentEdit 两次爬取之间内容被修改的评论
+type CommentEdit struct {
+	Comment
+	Before   string    `json:"before"`    // 窗口开始时的内容
+	After    string    `json:"after"`     // 窗口结束时的内容
+	EditedAt time.Time `json:"edited_at"` // 窗口内首次观察到修改的时间
+}
+
+// CommentChanges 视频在一段时间内观察到的评论删除与修改
+type CommentChanges struct {
+	Deleted []Comment     `json:"deleted"` // 按删除时间升序
+	Edited  []CommentEdit `json:"edited"`  // 按修改时间升序
+}
+
+// GetCommentSnapshots 获取评论的历史状态，按观察时间升序
+func GetCommentSnapshots(uniqueID string) ([]CommentSnapshot, error) {
+	rows, err := db.Query(`
+		SELECT observed_at, seen_at, content, like_count
+		FROM comment_snapshots
+		WHERE unique_id = ?
+		ORDER BY observed_at, id`, uniqueID)
+	if err != nil {
+		return nil, fmt.Errorf("查询评论快照失败: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := []CommentSnapshot{}
+	for rows.Next() {
+		var s CommentSnapshot
+		var observedAt, seenAt int64
+		if err := rows.Scan(&observedAt, &seenAt, &s.Content, &s.LikeCount); err != nil {
+			return nil, fmt.Errorf("扫描评论快照失败: %w", err)
+		}
+		s.ObservedAt, s.SeenAt = time.Unix(observedAt, 0), time.Unix(seenAt, 0)
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
+
+// GetCommentChanges 获取视频在 (from, to] 之间观察到的评论删除与内容修改
+// 快照保存的是变化前的状态：窗口开始时的内容为窗口内第一个内容快照，
+// 窗口结束时的内容为窗口之后的第一个内容快照，没有时为当前内容
+func GetCommentChanges(bvid string, from, to time.Time) (*CommentChanges, error) {
+	changes := &CommentChanges{Deleted: []Comment{}, Edited: []CommentEdit{}}
+
+	rows, err := db.Query(`
+		SELECT `+commentSelect("")+`
+		FROM bilibili_comments
+		WHERE bvid = ? AND deleted_at > ? AND deleted_at <= ?
+		ORDER BY deleted_at, unique_id`, bvid, from.Unix(), to.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("查询已删除评论失败: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描评论行失败: %w", err)
+		}
+		changes.Deleted = append(changes.Deleted, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历已删除评论失败: %w", err)
+	}
+	rows.Close()
+
+	rows, err = db.Query(`
+		SELECT `+commentSelect("c")+`,
+			(SELECT s.content FROM comment_snapshots s
+			 WHERE s.unique_id = c.unique_id AND s.content IS NOT NULL AND s.observed_at > ?2
+			 ORDER BY s.observed_at, s.id LIMIT 1),
+			COALESCE((SELECT s.content FROM comment_snapshots s
+			 WHERE s.unique_id = c.unique_id AND s.content IS NOT NULL AND s.observed_at > ?3
+			 ORDER BY s.observed_at, s.id LIMIT 1), c.content, ''),
+			e.edited_at
+		FROM (
+			SELECT unique_id, MIN(observed_at) AS edited_at
+			FROM comment_snapshots
+			WHERE bvid = ?1 AND content IS NOT NULL AND observed_at > ?2 AND observed_at <= ?3
+			GROUP BY unique_id
+		) e
+		JOIN bilibili_comments c ON c.unique_id = e.unique_id
+		ORDER BY e.edited_at, c.unique_id`, bvid, from.Unix(), to.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("查询修改过的评论失败: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var edit CommentEdit
+		var editedAt int64
+		edit.Comment, err = scanComment(withTrailing(rows, &edit.Before, &edit.After, &editedAt))
+		if err != nil {
+			return nil, fmt.Errorf("扫描评论行失败: %w", err)
+		}
+		edit.EditedAt = time.Unix(editedAt, 0)
+		changes.Edited = append(changes.Edited, edit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历修改过的评论失败: %w", err)
+	}
+	return changes, nil
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 评论列表的排序字段
+const (
+	CommentSortTime    = "time"
+	CommentSortLikes   = "likes"
+	CommentSortReplies = "replies"
+)
+
+// commentSortColumns 排序字段对应的列
+var commentSortColumns = map[string]string{
+	CommentSortTime:    "ctime",
+	CommentSortLikes:   "like_count",
+	CommentSortReplies: "rcount",
+}
+
+// CommentFilter 主评论列表的筛选与排序条件，零值表示不筛选、按点赞数降序
+type CommentFilter struct {
+	Keyword     string
+	Mid         int64
+	Location    string // 包含匹配，如 "北京" 匹配 "IP属地：北京"
+	MinLevel    *int
+	MaxLevel    *int
+	Sex         string
+	From        time.Time // 发布时间下限（含）
+	To          time.Time // 发布时间上限（不含）
+	MinLikes    int
+	HasPictures *bool
+	Pinned      *bool
+	UpReplied   *bool
+	Sort        string // time / likes / replies，为空时按点赞数
+	Asc         bool
+}
+
+// IsZero 是否未设置任何筛选条件（不考虑排序）
+func (f *CommentFilter) IsZero() bool {
+	return f.Keyword == "" && f.Mid == 0 && f.Location == "" && f.MinLevel == nil && f.MaxLevel == nil &&
+		f.Sex == "" && f.From.IsZero() && f.To.IsZero() && f.MinLikes == 0 &&
+		f.HasPictures == nil && f.Pinned == nil && f.UpReplied == nil
+}
+
+// where 生成筛选条件，返回以 AND 连接的条件与参数
+func (f *CommentFilter) where() (string, []interface{}) {
+	conds := []string{}
+	args := []interface{}{}
+	add := func(cond string, condArgs ...interface{}) {
+		conds = append(conds, cond)
+		args = append(args, condArgs...)
+	}
+
+	if f.Keyword != "" {
+		cond, condArgs := keywordCondition(f.Keyword, "content", "rowid", "comments_fts")
+		add(cond, condArgs...)
+	}
+	if f.Mid != 0 {
+		add("mid = ?", f.Mid)
+	}
+	if f.Location != "" {
+		add(`location LIKE ? ESCAPE '\'`, "%"+escapeLike(f.Location)+"%")
+	}
+	if f.MinLevel != nil {
+		add("level >= ?", *f.MinLevel)
+	}
+	if f.MaxLevel != nil {
+		add("level <= ?", *f.MaxLevel)
+	}
+	if f.Sex != "" {
+		add("sex = ?", f.Sex)
+	}
+	if !f.From.IsZero() {
+		add("ctime >= ?", f.From.Unix())
+	}
+	if !f.To.IsZero() {
+		add("ctime < ?", f.To.Unix())
+	}
+	if f.MinLikes > 0 {
+		add("like_count >= ?", f.MinLikes)
+	}
+	if f.HasPictures != nil {
+		if *f.HasPictures {
+			add("COALESCE(pictures, '') != ''")
+		} else {
+			add("COALESCE(pictures, '') = ''")
+		}
+	}
+	if f.Pinned != nil {
+		add("pinned = ?", *f.Pinned)
+	}
+	if f.UpReplied != nil {
+		add("up_replied = ?", *f.UpReplied)
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// QueryComments 按条件分页查询视频的主评论，Total 为满足条件的主评论数
+func QueryComments(bvid string, f CommentFilter, pr PageRequest) (*CommentPage, error) {
+	where := "bvid = ? AND parent = '0'"
+	args := []interface{}{bvid}
+	if cond, condArgs := f.where(); cond != "" {
+		where += " AND " + cond
+		args = append(args, condArgs...)
+	}
+
+	page := &CommentPage{}
+	// 未筛选时直接使用统计表，没有统计记录或有筛选条件时实时计数
+	counted := false
+	if f.IsZero() {
+		err := db.QueryRow("SELECT comment_count FROM comment_stats WHERE bvid = ?", bvid).Scan(&page.Total)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("获取评论统计失败: %w", err)
+		}
+		counted = err == nil
+	}
+	if !counted {
+		if err := db.QueryRow("SELECT COUNT(*) FROM bilibili_comments WHERE "+where, args...).Scan(&page.Total); err != nil {
+			return nil, fmt.Errorf("获取评论总数失败: %w", err)
+		}
+	}
+
+	var err error
+	page.Comments, page.NextCursor, err = queryCommentPage(where, args, f.Sort, f.Asc, pr)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
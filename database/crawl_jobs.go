@@ -0,0 +1,279 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// 爬取任务类型
+const (
+	CrawlJobTypeVideo = "video"
+	CrawlJobTypeUp    = "up"
+)
+
+// 爬取任务状态
+const (
+	CrawlJobQueued    = "queued"
+	CrawlJobRunning   = "running"
+	CrawlJobSucceeded = "succeeded"
+	CrawlJobFailed    = "failed"
+	CrawlJobCancelled = "cancelled"
+	CrawlJobPaused    = "paused" // 多次触发风控后暂停，熔断器关闭后自动重新排队
+)
+
+// CrawlJob 爬取任务记录
+type CrawlJob struct {
+	ID           int64      `json:"id"`
+	Type         string     `json:"type"`
+	Target       string     `json:"target"` // 视频任务为bvid，UP主任务为mid
+	FetchAll     bool       `json:"fetch_all,omitempty"`
+	Incremental  bool       `json:"incremental,omitempty"` // 视频任务是否增量爬取
+	Status       string     `json:"status"`
+	CommentCount int        `json:"comment_count"`
+	Error        string     `json:"error,omitempty"`
+	RiskControl  string     `json:"risk_control,omitempty"` // 最近一次触发B站风控的原因
+	RiskHits     int        `json:"risk_hits,omitempty"`    // 触发风控的次数
+	CreatedAt    time.Time  `json:"created_at"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+}
+
+// createCrawlJobsTable 创建爬取任务表
+func createCrawlJobsTable(tx *sql.Tx) error {
+	jobTableSQL := `
+	CREATE TABLE IF NOT EXISTS crawl_jobs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		type TEXT NOT NULL,
+		target TEXT NOT NULL,
+		fetch_all BOOLEAN NOT NULL DEFAULT 0,
+		incremental BOOLEAN NOT NULL DEFAULT 0,
+		status TEXT NOT NULL,
+		comment_count INTEGER NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL,
+		started_at INTEGER,
+		finished_at INTEGER
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_crawl_jobs_status ON crawl_jobs(status, id);`
+
+	if _, err := tx.Exec(jobTableSQL); err != nil {
+		return fmt.Errorf("创建爬取任务表失败: %w", err)
+	}
+	if err := addColumnIfMissing(tx, "crawl_jobs", "incremental", "BOOLEAN NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(tx, "crawl_jobs", "risk_control", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	return addColumnIfMissing(tx, "crawl_jobs", "risk_hits", "INTEGER NOT NULL DEFAULT 0")
+}
+
+const crawlJobColumns = `id, type, target, fetch_all, incremental, status, comment_count, error, risk_control, risk_hits, created_at, started_at, finished_at`
+
+// scanCrawlJob 扫描一行任务记录
+func scanCrawlJob(scanner interface{ Scan(...any) error }) (*CrawlJob, error) {
+	var job CrawlJob
+	var createdAt int64
+	var startedAt, finishedAt sql.NullInt64
+	if err := scanner.Scan(&job.ID, &job.Type, &job.Target, &job.FetchAll, &job.Incremental, &job.Status,
+		&job.CommentCount, &job.Error, &job.RiskControl, &job.RiskHits, &createdAt, &startedAt, &finishedAt); err != nil {
+		return nil, err
+	}
+	job.CreatedAt = time.Unix(createdAt, 0)
+	if startedAt.Valid {
+		t := time.Unix(startedAt.Int64, 0)
+		job.StartedAt = &t
+	}
+	if finishedAt.Valid {
+		t := time.Unix(finishedAt.Int64, 0)
+		job.FinishedAt = &t
+	}
+	return &job, nil
+}
+
+// CreateCrawlJob 新建一个排队中的爬取任务
+func CreateCrawlJob(jobType, target string, fetchAll, incremental bool) (*CrawlJob, error) {
+	now := time.Now()
+	res, err := db.Exec(`
+		INSERT INTO crawl_jobs (type, target, fetch_all, incremental, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		jobType, target, fetchAll, incremental, CrawlJobQueued, now.Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("创建爬取任务失败: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("获取任务ID失败: %w", err)
+	}
+	return &CrawlJob{
+		ID:          id,
+		Type:        jobType,
+		Target:      target,
+		FetchAll:    fetchAll,
+		Incremental: incremental,
+		Status:      CrawlJobQueued,
+		CreatedAt:   time.Unix(now.Unix(), 0),
+	}, nil
+}
+
+// ClaimNextCrawlJob 取出最早排队的任务并标记为运行中，没有任务时返回 nil
+func ClaimNextCrawlJob() (*CrawlJob, error) {
+	row := db.QueryRow(`
+		UPDATE crawl_jobs SET status = ?, started_at = ?
+		WHERE id = (SELECT id FROM crawl_jobs WHERE status = ? ORDER BY id LIMIT 1)
+		RETURNING `+crawlJobColumns,
+		CrawlJobRunning, time.Now().Unix(), CrawlJobQueued,
+	)
+	job, err := scanCrawlJob(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("领取爬取任务失败: %w", err)
+	}
+	return job, nil
+}
+
+// FinishCrawlJob 记录任务的最终状态
+func FinishCrawlJob(id int64, status string, commentCount int, errMsg string) error {
+	_, err := db.Exec(`
+		UPDATE crawl_jobs SET status = ?, comment_count = ?, error = ?, finished_at = ?
+		WHERE id = ?`,
+		status, commentCount, errMsg, time.Now().Unix(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("更新爬取任务状态失败: %w", err)
+	}
+	return nil
+}
+
+// RecordCrawlJobRiskControl 记录任务触发B站风控的原因并累加次数
+func RecordCrawlJobRiskControl(id int64, cause string) error {
+	_, err := db.Exec(`UPDATE crawl_jobs SET risk_control = ?, risk_hits = risk_hits + 1 WHERE id = ?`, cause, id)
+	if err != nil {
+		return fmt.Errorf("记录任务风控原因失败: %w", err)
+	}
+	return nil
+}
+
+// CancelQueuedCrawlJob 取消仍在排队或因风控暂停的任务，任务不在这两种状态时返回 false
+func CancelQueuedCrawlJob(id int64) (bool, error) {
+	res, err := db.Exec(`
+		UPDATE crawl_jobs SET status = ?, error = ?, finished_at = ?
+		WHERE id = ? AND status IN (?, ?)`,
+		CrawlJobCancelled, "任务已取消", time.Now().Unix(), id, CrawlJobQueued, CrawlJobPaused,
+	)
+	if err != nil {
+		return false, fmt.Errorf("取消爬取任务失败: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("取消爬取任务失败: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetActiveCrawlJobs 获取指定目标排队中、运行中或因风控暂停的任务
+func GetActiveCrawlJobs(jobType, target string) ([]CrawlJob, error) {
+	rows, err := db.Query(`SELECT `+crawlJobColumns+` FROM crawl_jobs
+		WHERE type = ? AND target = ? AND status IN (?, ?, ?) ORDER BY id`,
+		jobType, target, CrawlJobQueued, CrawlJobRunning, CrawlJobPaused)
+	if err != nil {
+		return nil, fmt.Errorf("查询活动任务失败: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []CrawlJob{}
+	for rows.Next() {
+		job, err := scanCrawlJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描任务行失败: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历任务行失败: %w", err)
+	}
+	return jobs, nil
+}
+
+// FailInterruptedCrawlJobs 将上次进程退出时仍在运行的任务标记为失败
+func FailInterruptedCrawlJobs() (int64, error) {
+	res, err := db.Exec(`
+		UPDATE crawl_jobs SET status = ?, error = ?, finished_at = ?
+		WHERE status = ?`,
+		CrawlJobFailed, "服务重启，任务被中断，重新提交后从断点继续", time.Now().Unix(), CrawlJobRunning,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("重置中断任务失败: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// RequeuePausedCrawlJobs 将因风控暂停的任务重新排队，返回重新排队的任务数
+// 重新领取时从断点继续，风控原因与次数保留
+func RequeuePausedCrawlJobs() (int64, error) {
+	res, err := db.Exec(`
+		UPDATE crawl_jobs SET status = ?, error = '', finished_at = NULL
+		WHERE status = ?`,
+		CrawlJobQueued, CrawlJobPaused,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("重新排队暂停的任务失败: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// GetCrawlJob 按ID获取任务，不存在时返回 nil
+func GetCrawlJob(id int64) (*CrawlJob, error) {
+	row := db.QueryRow(`SELECT `+crawlJobColumns+` FROM crawl_jobs WHERE id = ?`, id)
+	job, err := scanCrawlJob(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询爬取任务失败: %w", err)
+	}
+	return job, nil
+}
+
+// GetCrawlJobsPaginated 分页获取任务列表（按创建时间倒序），status 为空时不过滤
+func GetCrawlJobsPaginated(page, pageSize int, status string) ([]CrawlJob, int, error) {
+	offset := (page - 1) * pageSize
+
+	where := ""
+	var args []interface{}
+	if status != "" {
+		where = " WHERE status = ?"
+		args = append(args, status)
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM crawl_jobs"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("获取任务总数失败: %w", err)
+	}
+
+	args = append(args, pageSize, offset)
+	rows, err := db.Query(`SELECT `+crawlJobColumns+` FROM crawl_jobs`+where+
+		` ORDER BY id DESC LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询任务列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []CrawlJob{}
+	for rows.Next() {
+		job, err := scanCrawlJob(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("扫描任务行失败: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("遍历任务行失败: %w", err)
+	}
+	return jobs, total, nil
+}
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidCursor 游标无法解析，或与当前的排序方式不一致
+var ErrInvalidCursor = errors.New("无效的游标")
+
+// PageRequest 分页参数：Cursor 为空时按 Page 偏移翻页，否则从游标位置继续并忽略 Page
+// 偏移翻页在深页时需要扫描并丢弃前面的全部行，且爬取写入期间会重复或漏掉评论；
+// 游标翻页按 (排序键, unique_id) 定位，走索引直接跳到下一页
+type PageRequest struct {
+	Page     int
+	PageSize int
+	Cursor   string
+}
+
+// CommentPage 一页评论，NextCursor 为空表示没有下一页
+type CommentPage struct {
+	Comments   []Comment
+	Total      int
+	NextCursor string
+}
+
+// pageCursor 上一页最后一条评论的位置，同时记录排序方式，换了排序的旧游标会被拒绝
+type pageCursor struct {
+	Sort string `json:"s"`
+	Asc  bool   `json:"a,omitempty"`
+	Key  int64  `json:"k"`
+	ID   string `json:"i"`
+}
+
+func (c pageCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor 解析游标并校验排序方式
+func decodeCursor(s, sort string, asc bool) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != sort || c.Asc != asc {
+		return nil, fmt.Errorf("%w: 游标属于另一种排序方式", ErrInvalidCursor)
+	}
+	return &c, nil
+}
+
+// sortKey 评论在指定排序下的排序键
+func sortKey(c *Comment, sort string) int64 {
+	switch sort {
+	case CommentSortTime:
+		return c.Ctime.Unix()
+	case CommentSortReplies:
+		return int64(c.Rcount)
+	default:
+		return int64(c.LikeCount)
+	}
+}
+
+// queryCommentPage 按 (排序键, unique_id) 查询一页评论并填充回复数，where 为不含排序条件的筛选
+// 多取一行判断是否还有下一页
+func queryCommentPage(where string, args []interface{}, sort string, asc bool, pr PageRequest) ([]Comment, string, error) {
+	col, ok := commentSortColumns[sort]
+	if !ok {
+		sort, col = CommentSortLikes, commentSortColumns[CommentSortLikes]
+	}
+	dir, cmp := "DESC", "<"
+	if asc {
+		dir, cmp = "ASC", ">"
+	}
+
+	var offset int
+	if pr.Cursor != "" {
+		cur, err := decodeCursor(pr.Cursor, sort, asc)
+		if err != nil {
+			return nil, "", err
+		}
+		// 行值比较可以直接使用 (…, 排序列, unique_id) 索引定位
+		where += fmt.Sprintf(" AND (%s, unique_id) %s (?, ?)", col, cmp)
+		args = append(args, cur.Key, cur.ID)
+	} else if pr.Page > 1 {
+		offset = (pr.Page - 1) * pr.PageSize
+	}
+
+	query := `
+		SELECT ` + commentSelect("") + `
+		FROM bilibili_comments
+		WHERE ` + where + `
+		ORDER BY ` + fmt.Sprintf("%s %s, unique_id %s", col, dir, dir) + `
+		LIMIT ? OFFSET ?`
+
+	rows, err := db.Query(query, append(args, pr.PageSize+1, offset)...)
+	if err != nil {
+		return nil, "", fmt.Errorf("查询评论失败: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []Comment
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("扫描评论行失败: %w", err)
+		}
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("遍历评论失败: %w", err)
+	}
+
+	var next string
+	if len(comments) > pr.PageSize {
+		comments = comments[:pr.PageSize]
+		last := &comments[len(comments)-1]
+		next = pageCursor{Sort: sort, Asc: asc, Key: sortKey(last, sort), ID: last.UniqueID}.encode()
+	}
+	if err := fillReplyStats(comments); err != nil {
+		return nil, "", err
+	}
+	return comments, next, nil
+}
+
+// CommentPosition 评论在所属列表（视频的主评论或父评论的回复）中的位置
+type CommentPosition struct {
+	Index  int       // 从 1 开始
+	Total  int       // 列表中的评论数
+	Before []Comment // 紧挨在前面的评论，按列表顺序
+	After  []Comment // 紧挨在后面的评论
+}
+
+// GetCommentPosition 计算评论在所属列表中的位置并取前后各 n 条相邻评论
+// 主评论按 sort/asc 排序；回复与回复列表一致，按点赞数降序
+func GetCommentPosition(c *Comment, sort string, asc bool, n int) (*CommentPosition, error) {
+	where, args := "bvid = ? AND parent = '0'", []interface{}{c.BVid}
+	if c.Parent != "0" {
+		where, args = "parent = ?", []interface{}{c.Parent}
+		sort, asc = CommentSortLikes, false
+	}
+	col, ok := commentSortColumns[sort]
+	if !ok {
+		sort, col = CommentSortLikes, commentSortColumns[CommentSortLikes]
+	}
+	ahead := ">"
+	if asc {
+		ahead = "<"
+	}
+	key := sortKey(c, sort)
+
+	pos := &CommentPosition{Before: []Comment{}, After: []Comment{}}
+	err := db.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM bilibili_comments WHERE %s AND (%s, unique_id) %s (?, ?)`, where, col, ahead),
+		append(args, key, c.UniqueID)...).Scan(&pos.Index)
+	if err != nil {
+		return nil, fmt.Errorf("计算评论位置失败: %w", err)
+	}
+	pos.Index++
+	if err := db.QueryRow(`SELECT COUNT(*) FROM bilibili_comments WHERE `+where, args...).Scan(&pos.Total); err != nil {
+		return nil, fmt.Errorf("获取评论总数失败: %w", err)
+	}
+	if n <= 0 {
+		return pos, nil
+	}
+
+	// 向后按列表方向翻页，向前按相反方向翻页后倒序
+	after := PageRequest{PageSize: n, Cursor: pageCursor{Sort: sort, Asc: asc, Key: key, ID: c.UniqueID}.encode()}
+	if pos.After, _, err = queryCommentPage(where, args, sort, asc, after); err != nil {
+		return nil, err
+	}
+	before := PageRequest{PageSize: n, Cursor: pageCursor{Sort: sort, Asc: !asc, Key: key, ID: c.UniqueID}.encode()}
+	if pos.Before, _, err = queryCommentPage(where, args, sort, !asc, before); err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(pos.Before)-1; i < j; i, j = i+1, j-1 {
+		pos.Before[i], pos.Before[j] = pos.Before[j], pos.Before[i]
+	}
+	return pos, nil
+}
@@ -0,0 +1,25 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"bilibili-comments-viewer-go/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitLogger("", "error", 1, 1, 1)
+	os.Exit(m.Run())
+}
+
+// openTestDB 在测试的临时目录中创建并升级数据库，测试结束时关闭
+func openTestDB(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "bilibili.db")
+	if err := InitDB(path); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	t.Cleanup(CloseDB)
+	return path
+}
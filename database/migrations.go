@@ -0,0 +1,534 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"bilibili-comments-viewer-go/logger"
+)
+
+// Migration 一个表结构版本：Up 从上一版本升级到该版本，Down 回退到上一版本
+// 每个步骤与 schema_migrations 的记录在同一事务中执行，失败时整体回滚
+type Migration struct {
+	Version     int
+	Name        string
+	Destructive bool // Up 会删除或改写已有数据，执行前先备份数据库；Down 总是先备份
+	Up          func(tx *sql.Tx) error
+	Down        func(tx *sql.Tx) error
+}
+
+// migrations 按版本号升序排列
+// 已发布的版本不要再修改，表结构变化一律追加新版本；Up 需兼容迁移机制引入前已存在的列与表
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial_schema",
+		Up:      createTables,
+		Down: execSQL(
+			"DROP TABLE IF EXISTS comment_stats",
+			"DROP TABLE IF EXISTS comment_relations",
+			"DROP TABLE IF EXISTS bilibili_comments",
+			"DROP TABLE IF EXISTS video_info",
+		),
+	},
+	{
+		Version: 2,
+		Name:    "crawl_jobs",
+		Up:      createCrawlJobsTable,
+		Down:    execSQL("DROP TABLE IF EXISTS crawl_jobs"),
+	},
+	{
+		Version: 3,
+		Name:    "comment_rcount",
+		Up: addColumns("bilibili_comments", [][2]string{
+			{"rcount", "INTEGER NOT NULL DEFAULT 0"}, // 主评论的回复数，用于增量爬取判断楼层是否有新回复
+		}),
+		Down: dropColumns("bilibili_comments", "rcount"),
+	},
+	{
+		Version: 4,
+		Name:    "crawl_schedules",
+		Up:      createSchedulesTable,
+		Down: execSQL(
+			"DROP TABLE IF EXISTS request_usage",
+			"DROP TABLE IF EXISTS crawl_schedules",
+		),
+	},
+	{
+		Version: 5,
+		Name:    "crawl_checkpoints",
+		Up:      createCheckpointsTable,
+		Down:    execSQL("DROP TABLE IF EXISTS crawl_checkpoints"),
+	},
+	{
+		Version: 6,
+		Name:    "raw_pages",
+		Up:      createRawPagesTable,
+		Down:    execSQL("DROP TABLE IF EXISTS raw_pages"),
+	},
+	{
+		Version: 7,
+		Name:    "comment_full_model",
+		Up: addColumns("bilibili_comments", [][2]string{
+			{"root", "INTEGER NOT NULL DEFAULT 0"},   // 所属主评论 rpid，主评论为 0
+			{"dialog", "INTEGER NOT NULL DEFAULT 0"}, // 所属对话（楼中楼回复链）首条评论的 rpid
+			{"invisible", "BOOLEAN NOT NULL DEFAULT 0"},
+			{"time_desc", "TEXT NOT NULL DEFAULT ''"},
+			{"avatar", "TEXT NOT NULL DEFAULT ''"},
+			{"vip_type", "INTEGER NOT NULL DEFAULT 0"},
+			{"vip_status", "INTEGER NOT NULL DEFAULT 0"},
+			{"vip_label", "TEXT NOT NULL DEFAULT ''"},
+			{"fans_medal", "TEXT NOT NULL DEFAULT ''"}, // 粉丝勋章名称
+			{"fans_medal_level", "INTEGER NOT NULL DEFAULT 0"},
+			{"emotes", "TEXT NOT NULL DEFAULT ''"},  // JSON 对象：表情文本 -> 图片地址
+			{"members", "TEXT NOT NULL DEFAULT ''"}, // JSON 数组：@提及的用户
+		}),
+		Down: dropColumns("bilibili_comments", "root", "dialog", "invisible", "time_desc", "avatar",
+			"vip_type", "vip_status", "vip_label", "fans_medal", "fans_medal_level", "emotes", "members"),
+	},
+	{
+		Version: 8,
+		Name:    "full_text_search",
+		Up:      createSearchTables,
+		Down: execSQL(
+			"DROP TRIGGER IF EXISTS comments_fts_ai",
+			"DROP TRIGGER IF EXISTS comments_fts_ad",
+			"DROP TRIGGER IF EXISTS comments_fts_au",
+			"DROP TABLE IF EXISTS comments_fts",
+			"DROP TRIGGER IF EXISTS videos_fts_ai",
+			"DROP TRIGGER IF EXISTS videos_fts_ad",
+			"DROP TRIGGER IF EXISTS videos_fts_au",
+			"DROP TABLE IF EXISTS videos_fts",
+		),
+	},
+	{
+		Version: 9,
+		Name:    "comment_query_filters",
+		Up: func(tx *sql.Tx) error {
+			if err := addColumns("bilibili_comments", [][2]string{
+				{"pinned", "BOOLEAN NOT NULL DEFAULT 0"},     // 是否被UP主置顶
+				{"up_replied", "BOOLEAN NOT NULL DEFAULT 0"}, // UP主是否回复过该评论
+			})(tx); err != nil {
+				return err
+			}
+			return execSQL(
+				"CREATE INDEX IF NOT EXISTS idx_comments_bvid_like ON bilibili_comments(bvid, like_count)",
+				"CREATE INDEX IF NOT EXISTS idx_comments_bvid_rcount ON bilibili_comments(bvid, rcount)",
+			)(tx)
+		},
+		Down: func(tx *sql.Tx) error {
+			if err := execSQL(
+				"DROP INDEX IF EXISTS idx_comments_bvid_like",
+				"DROP INDEX IF EXISTS idx_comments_bvid_rcount",
+			)(tx); err != nil {
+				return err
+			}
+			return dropColumns("bilibili_comments", "pinned", "up_replied")(tx)
+		},
+	},
+	{
+		Version: 10,
+		Name:    "keyset_pagination",
+		// 游标翻页按 (排序键, unique_id) 定位，索引覆盖筛选列与两个排序列
+		Up: execSQL(
+			"DROP INDEX IF EXISTS idx_comments_bvid_like",
+			"DROP INDEX IF EXISTS idx_comments_bvid_rcount",
+			"CREATE INDEX IF NOT EXISTS idx_comments_roots_like ON bilibili_comments(bvid, parent, like_count, unique_id)",
+			"CREATE INDEX IF NOT EXISTS idx_comments_roots_ctime ON bilibili_comments(bvid, parent, ctime, unique_id)",
+			"CREATE INDEX IF NOT EXISTS idx_comments_roots_rcount ON bilibili_comments(bvid, parent, rcount, unique_id)",
+			"CREATE INDEX IF NOT EXISTS idx_comments_parent_like ON bilibili_comments(parent, like_count, unique_id)",
+		),
+		Down: execSQL(
+			"DROP INDEX IF EXISTS idx_comments_roots_like",
+			"DROP INDEX IF EXISTS idx_comments_roots_ctime",
+			"DROP INDEX IF EXISTS idx_comments_roots_rcount",
+			"DROP INDEX IF EXISTS idx_comments_parent_like",
+			"CREATE INDEX IF NOT EXISTS idx_comments_bvid_like ON bilibili_comments(bvid, like_count)",
+			"CREATE INDEX IF NOT EXISTS idx_comments_bvid_rcount ON bilibili_comments(bvid, rcount)",
+		),
+	},
+	{
+		Version: 11,
+		Name:    "comment_thread_index",
+		// 按 root 列查找整个楼层的回复
+		Up:   execSQL("CREATE INDEX IF NOT EXISTS idx_comments_root ON bilibili_comments(bvid, root)"),
+		Down: execSQL("DROP INDEX IF EXISTS idx_comments_root"),
+	},
+	{
+		Version:     12,
+		Name:        "video_delete_cascade",
+		Destructive: true,
+		// SQLite 不能修改已有外键，重建关系表与统计表加上 ON DELETE CASCADE；
+		// 外键未开启时写入的悬空记录（评论或视频已不存在）在重建时丢弃
+		Up: execSQL(
+			`CREATE TABLE comment_relations_new (
+				parent_id TEXT NOT NULL,
+				child_id TEXT NOT NULL,
+				PRIMARY KEY (parent_id, child_id),
+				FOREIGN KEY (parent_id) REFERENCES bilibili_comments(unique_id) ON DELETE CASCADE,
+				FOREIGN KEY (child_id) REFERENCES bilibili_comments(unique_id) ON DELETE CASCADE
+			)`,
+			`INSERT INTO comment_relations_new (parent_id, child_id)
+				SELECT r.parent_id, r.child_id FROM comment_relations r
+				WHERE EXISTS (SELECT 1 FROM bilibili_comments WHERE unique_id = r.parent_id)
+				  AND EXISTS (SELECT 1 FROM bilibili_comments WHERE unique_id = r.child_id)`,
+			"DROP TABLE comment_relations",
+			"ALTER TABLE comment_relations_new RENAME TO comment_relations",
+			"CREATE INDEX IF NOT EXISTS idx_parent_child ON comment_relations(parent_id, child_id)",
+			// 删除评论时按 child_id 查找要级联删除的关系
+			"CREATE INDEX IF NOT EXISTS idx_relations_child ON comment_relations(child_id)",
+			`CREATE TABLE comment_stats_new (
+				bvid TEXT PRIMARY KEY,
+				comment_count INTEGER NOT NULL DEFAULT 0,
+				last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (bvid) REFERENCES video_info(bvid) ON DELETE CASCADE
+			)`,
+			`INSERT INTO comment_stats_new (bvid, comment_count, last_updated)
+				SELECT s.bvid, s.comment_count, s.last_updated FROM comment_stats s
+				WHERE EXISTS (SELECT 1 FROM video_info WHERE bvid = s.bvid)`,
+			"DROP TABLE comment_stats",
+			"ALTER TABLE comment_stats_new RENAME TO comment_stats",
+		),
+		Down: execSQL(
+			`CREATE TABLE comment_relations_old (
+				parent_id TEXT NOT NULL,
+				child_id TEXT NOT NULL,
+				PRIMARY KEY (parent_id, child_id),
+				FOREIGN KEY (parent_id) REFERENCES bilibili_comments(unique_id),
+				FOREIGN KEY (child_id) REFERENCES bilibili_comments(unique_id)
+			)`,
+			"INSERT INTO comment_relations_old (parent_id, child_id) SELECT parent_id, child_id FROM comment_relations",
+			"DROP TABLE comment_relations",
+			"ALTER TABLE comment_relations_old RENAME TO comment_relations",
+			"CREATE INDEX IF NOT EXISTS idx_parent_child ON comment_relations(parent_id, child_id)",
+			`CREATE TABLE comment_stats_old (
+				bvid TEXT PRIMARY KEY,
+				comment_count INTEGER NOT NULL DEFAULT 0,
+				last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (bvid) REFERENCES video_info(bvid)
+			)`,
+			"INSERT INTO comment_stats_old (bvid, comment_count, last_updated) SELECT bvid, comment_count, last_updated FROM comment_stats",
+			"DROP TABLE comment_stats",
+			"ALTER TABLE comment_stats_old RENAME TO comment_stats",
+		),
+	},
+	{
+		Version: 13,
+		Name:    "comment_history",
+		Up: func(tx *sql.Tx) error {
+			if err := addColumns("bilibili_comments", [][2]string{
+				{"first_seen", "INTEGER NOT NULL DEFAULT 0"}, // 首次被爬取观察到的时间
+				{"last_seen", "INTEGER NOT NULL DEFAULT 0"},  // 最近一次被爬取观察到的时间
+				{"deleted_at", "INTEGER"},                    // 完整爬取未再观察到的时间，未删除为 NULL
+			})(tx); err != nil {
+				return err
+			}
+			return execSQL(
+				// 已有评论无从得知观察时间，以升级时间为准
+				"UPDATE bilibili_comments SET first_seen = CAST(strftime('%s', 'now') AS INTEGER) WHERE first_seen = 0",
+				"UPDATE bilibili_comments SET last_seen = first_seen WHERE last_seen = 0",
+				"CREATE INDEX IF NOT EXISTS idx_comments_deleted ON bilibili_comments(bvid, deleted_at)",
+				// 每行记录一次变化前的状态：内容未变时 content 为 NULL
+				`CREATE TABLE IF NOT EXISTS comment_snapshots (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					unique_id TEXT NOT NULL REFERENCES bilibili_comments(unique_id) ON DELETE CASCADE,
+					bvid TEXT NOT NULL,
+					observed_at INTEGER NOT NULL, -- 观察到变化的爬取时间
+					seen_at INTEGER NOT NULL,     -- 变化前的状态最后一次被观察到的时间
+					content TEXT,
+					like_count INTEGER NOT NULL
+				)`,
+				"CREATE INDEX IF NOT EXISTS idx_snapshots_comment ON comment_snapshots(unique_id, observed_at)",
+				"CREATE INDEX IF NOT EXISTS idx_snapshots_bvid ON comment_snapshots(bvid, observed_at)",
+				`CREATE TABLE IF NOT EXISTS video_crawls (
+					id INTEGER PRIMARY KEY AUTOINCREMENT,
+					bvid TEXT NOT NULL REFERENCES video_info(bvid) ON DELETE CASCADE,
+					mode TEXT NOT NULL,
+					status TEXT NOT NULL,
+					started_at INTEGER NOT NULL,
+					finished_at INTEGER,
+					since INTEGER,                 -- 完整爬取（含断点续爬前的部分）的开始时间，增量爬取为 NULL
+					comments INTEGER NOT NULL DEFAULT 0,
+					deleted INTEGER NOT NULL DEFAULT 0
+				)`,
+				"CREATE INDEX IF NOT EXISTS idx_video_crawls_bvid ON video_crawls(bvid, id)",
+			)(tx)
+		},
+		Down: func(tx *sql.Tx) error {
+			if err := execSQL(
+				"DROP TABLE IF EXISTS video_crawls",
+				"DROP TABLE IF EXISTS comment_snapshots",
+				"DROP INDEX IF EXISTS idx_comments_deleted",
+			)(tx); err != nil {
+				return err
+			}
+			return dropColumns("bilibili_comments", "first_seen", "last_seen", "deleted_at")(tx)
+		},
+	},
+	{
+		Version: 14,
+		Name:    "video_crawl_incomplete",
+		Up: addColumns("video_crawls", [][2]string{
+			{"incomplete", "INTEGER NOT NULL DEFAULT 0"}, // 结束时无法确认已爬取全部评论，未标记删除
+		}),
+		Down: dropColumns("video_crawls", "incomplete"),
+	},
+}
+
+// MigrationStatus 一个版本的应用状态
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"` // 未应用时为 nil
+}
+
+// execSQL 依次执行 SQL 语句的迁移步骤
+func execSQL(stmts ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, stmt := range stmts {
+			if _, err := tx.Exec(stmt); err != nil {
+				return fmt.Errorf("执行 %q 失败: %w", stmt, err)
+			}
+		}
+		return nil
+	}
+}
+
+// addColumns 为表补充列的迁移步骤，已存在的列跳过
+func addColumns(table string, cols [][2]string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, col := range cols {
+			if err := addColumnIfMissing(tx, table, col[0], col[1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// dropColumns 删除列的迁移步骤
+func dropColumns(table string, cols ...string) func(tx *sql.Tx) error {
+	stmts := make([]string, 0, len(cols))
+	for _, col := range cols {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, col))
+	}
+	return execSQL(stmts...)
+}
+
+// LatestVersion 程序支持的最新表结构版本
+func LatestVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// ensureMigrationsTable 创建版本记录表
+func ensureMigrationsTable() error {
+	_, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at INTEGER NOT NULL
+	);`)
+	if err != nil {
+		return fmt.Errorf("创建版本记录表失败: %w", err)
+	}
+	return nil
+}
+
+// appliedMigrations 已应用的版本及应用时间
+func appliedMigrations() (map[int]time.Time, error) {
+	if err := ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("查询版本记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt int64
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("扫描版本记录失败: %w", err)
+		}
+		applied[version] = time.Unix(appliedAt, 0)
+	}
+	return applied, rows.Err()
+}
+
+// SchemaVersion 数据库当前的表结构版本，未应用任何版本时为 0
+func SchemaVersion() (int, error) {
+	applied, err := appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+// MigrationStatuses 返回全部版本的应用状态
+func MigrationStatuses() ([]MigrationStatus, error) {
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		st := MigrationStatus{Version: m.Version, Name: m.Name}
+		if t, ok := applied[m.Version]; ok {
+			st.AppliedAt = &t
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses, nil
+}
+
+// MigrateUp 依次应用未应用的版本直到 target，target <= 0 时升级到最新版本
+// 数据库版本高于程序支持的版本时返回错误，避免旧程序写坏新版本的数据
+func MigrateUp(target int) error {
+	if target <= 0 {
+		target = LatestVersion()
+	}
+	applied, err := appliedMigrations()
+	if err != nil {
+		return err
+	}
+	for v := range applied {
+		if v > LatestVersion() {
+			return fmt.Errorf("数据库结构版本 %d 高于程序支持的版本 %d，请升级程序", v, LatestVersion())
+		}
+	}
+
+	backedUp := false
+	for _, m := range migrations {
+		if m.Version > target {
+			break
+		}
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if m.Destructive && !backedUp {
+			if err := backupCurrent(); err != nil {
+				return err
+			}
+			backedUp = true
+		}
+		if err := applyMigration(m, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateDown 按版本倒序回退已应用的版本，直到数据库版本为 target
+// 回退会删除表或列，执行前先备份数据库
+func MigrateDown(target int) error {
+	if target < 0 {
+		return fmt.Errorf("无效的目标版本: %d", target)
+	}
+	applied, err := appliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	backedUp := false
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version <= target {
+			break
+		}
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if !backedUp {
+			if err := backupCurrent(); err != nil {
+				return err
+			}
+			backedUp = true
+		}
+		if err := applyMigration(m, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyMigration 在一个事务中执行迁移步骤并更新版本记录
+func applyMigration(m Migration, up bool) error {
+	log := logger.GetLogger()
+	direction, step := "升级", m.Up
+	if !up {
+		direction, step = "回退", m.Down
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := step(tx); err != nil {
+		return fmt.Errorf("%s数据库结构版本 %d (%s) 失败: %w", direction, m.Version, m.Name, err)
+	}
+	if up {
+		_, err = tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			m.Version, m.Name, time.Now().Unix())
+	} else {
+		_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, m.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("更新版本记录失败: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	log.Infof("已%s数据库结构版本 %d (%s)", direction, m.Version, m.Name)
+	return nil
+}
+
+// backupCurrent 以当前表结构版本备份数据库
+func backupCurrent() error {
+	version, err := SchemaVersion()
+	if err != nil {
+		return err
+	}
+	_, err = backupDB(version)
+	return err
+}
+
+// backupDB 将数据库完整复制到同目录下的备份文件，返回备份路径
+// version 为备份时的表结构版本，写入文件名便于回滚时辨认
+func backupDB(version int) (string, error) {
+	if dbFile == "" || strings.HasPrefix(dbFile, ":memory:") {
+		return "", nil
+	}
+	if _, err := os.Stat(dbFile); err != nil {
+		return "", fmt.Errorf("备份数据库失败: %w", err)
+	}
+	// 文件名精确到秒，同一秒内多次备份（如回退后立即升级）时追加序号，VACUUM INTO 不会覆盖已有文件
+	base := fmt.Sprintf("%s.v%d-%s", dbFile, version, time.Now().Format("20060102-150405"))
+	path := base + ".bak"
+	for n := 2; ; n++ {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("备份数据库失败: %w", err)
+		}
+		path = fmt.Sprintf("%s-%d.bak", base, n)
+	}
+	if _, err := db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return "", fmt.Errorf("备份数据库失败: %w", err)
+	}
+	logger.GetLogger().Infof("已备份数据库: %s", path)
+	return path, nil
+}
@@ -0,0 +1,277 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// baselineSchema 迁移机制引入前的程序创建的表结构与数据
+const baselineSchema = `
+CREATE TABLE video_info (
+	bvid TEXT PRIMARY KEY,
+	title TEXT NOT NULL,
+	cover TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE bilibili_comments (
+	unique_id TEXT PRIMARY KEY,
+	bvid TEXT NOT NULL,
+	rpid INTEGER NOT NULL,
+	content TEXT,
+	pictures TEXT,
+	oid INTEGER,
+	mid INTEGER,
+	parent TEXT,
+	fans_grade INTEGER,
+	ctime INTEGER,
+	like_count INTEGER,
+	upname TEXT,
+	sex TEXT,
+	following BOOLEAN,
+	level INTEGER,
+	location TEXT
+);
+CREATE INDEX idx_bvid ON bilibili_comments(bvid);
+CREATE INDEX idx_rpid ON bilibili_comments(rpid);
+CREATE INDEX idx_mid ON bilibili_comments(mid);
+CREATE INDEX idx_bvid_ctime ON bilibili_comments(bvid, ctime);
+CREATE TABLE comment_relations (
+	parent_id TEXT NOT NULL,
+	child_id TEXT NOT NULL,
+	PRIMARY KEY (parent_id, child_id),
+	FOREIGN KEY (parent_id) REFERENCES bilibili_comments(unique_id),
+	FOREIGN KEY (child_id) REFERENCES bilibili_comments(unique_id)
+);
+CREATE INDEX idx_parent_child ON comment_relations(parent_id, child_id);
+CREATE TABLE comment_stats (
+	bvid TEXT PRIMARY KEY,
+	comment_count INTEGER NOT NULL DEFAULT 0,
+	last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (bvid) REFERENCES video_info(bvid)
+);
+
+INSERT INTO video_info (bvid, title, cover) VALUES ('BV1xx411c7mD', '旧版本的视频', 'cover.jpg');
+INSERT INTO bilibili_comments (unique_id, bvid, rpid, content, oid, mid, parent, ctime, like_count, upname, location)
+VALUES
+	('BV1xx411c7mD_1001', 'BV1xx411c7mD', 1001, '看完学到了很多', 170001, 11, '0', 1700000000, 5, '用户甲', 'IP属地：上海'),
+	('BV1xx411c7mD_1002', 'BV1xx411c7mD', 1002, '回复楼主', 170001, 12, 'BV1xx411c7mD_1001', 1700000100, 1, '用户乙', 'IP属地：北京');
+INSERT INTO comment_relations (parent_id, child_id) VALUES ('BV1xx411c7mD_1001', 'BV1xx411c7mD_1002');
+INSERT INTO comment_stats (bvid, comment_count) VALUES ('BV1xx411c7mD', 2);
+`
+
+// openBaselineDB 创建迁移机制引入前的程序写出的数据库，不升级表结构
+func openBaselineDB(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "bilibili.db")
+	if err := OpenDB(path); err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	t.Cleanup(CloseDB)
+	if _, err := db.Exec(baselineSchema); err != nil {
+		t.Fatalf("创建旧版本表结构失败: %v", err)
+	}
+	return path
+}
+
+func queryInt(t *testing.T, query string, args ...any) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
+
+func tableExists(t *testing.T, name string) bool {
+	t.Helper()
+	return queryInt(t, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name) > 0
+}
+
+func columnExists(t *testing.T, table, column string) bool {
+	t.Helper()
+	return queryInt(t, `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column) > 0
+}
+
+func assertSchemaVersion(t *testing.T, want int) {
+	t.Helper()
+	got, err := SchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Fatalf("表结构版本 = %d, 期望 %d", got, want)
+	}
+}
+
+// assertBaselineData 检查 baselineSchema 写入的数据仍然完整
+func assertBaselineData(t *testing.T) {
+	t.Helper()
+	for _, tc := range []struct {
+		query string
+		want  int
+	}{
+		{`SELECT COUNT(*) FROM video_info WHERE bvid = 'BV1xx411c7mD' AND title = '旧版本的视频'`, 1},
+		{`SELECT COUNT(*) FROM bilibili_comments WHERE bvid = 'BV1xx411c7mD'`, 2},
+		{`SELECT COUNT(*) FROM bilibili_comments WHERE unique_id = 'BV1xx411c7mD_1001' AND content = '看完学到了很多' AND like_count = 5`, 1},
+		{`SELECT COUNT(*) FROM comment_relations WHERE parent_id = 'BV1xx411c7mD_1001' AND child_id = 'BV1xx411c7mD_1002'`, 1},
+		{`SELECT comment_count FROM comment_stats WHERE bvid = 'BV1xx411c7mD'`, 2},
+	} {
+		if got := queryInt(t, tc.query); got != tc.want {
+			t.Errorf("%s = %d, 期望 %d", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestMigrateBaselineDatabase(t *testing.T) {
+	path := openBaselineDB(t)
+
+	if err := MigrateUp(0); err != nil {
+		t.Fatalf("升级失败: %v", err)
+	}
+	assertSchemaVersion(t, LatestVersion())
+	assertBaselineData(t)
+
+	for _, table := range []string{"crawl_jobs", "crawl_schedules", "request_usage", "crawl_checkpoints",
+		"raw_pages", "comments_fts", "comment_snapshots", "video_crawls"} {
+		if !tableExists(t, table) {
+			t.Errorf("升级后缺少表 %s", table)
+		}
+	}
+	for _, col := range []string{"rcount", "root", "pinned", "first_seen", "deleted_at"} {
+		if !columnExists(t, "bilibili_comments", col) {
+			t.Errorf("升级后 bilibili_comments 缺少列 %s", col)
+		}
+	}
+	// 已有评论补建全文索引，并按升级时间记录首次观察时间
+	if got := queryInt(t, `SELECT COUNT(*) FROM comments_fts WHERE comments_fts MATCH '学到了'`); got != 1 {
+		t.Errorf("全文索引命中 %d 条, 期望 1", got)
+	}
+	if got := queryInt(t, `SELECT COUNT(*) FROM bilibili_comments WHERE first_seen = 0 OR last_seen = 0`); got != 0 {
+		t.Errorf("%d 条评论未补齐观察时间", got)
+	}
+	// 重建后的关系表随评论级联删除
+	if _, err := db.Exec(`DELETE FROM bilibili_comments WHERE unique_id = 'BV1xx411c7mD_1002'`); err != nil {
+		t.Fatal(err)
+	}
+	if got := queryInt(t, `SELECT COUNT(*) FROM comment_relations`); got != 0 {
+		t.Errorf("删除评论后剩余 %d 条关系", got)
+	}
+
+	// 破坏性升级前以升级前的版本备份
+	pre := cascadeVersion(t) - 1
+	backups, err := filepath.Glob(fmt.Sprintf("%s.v*.bak", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("备份文件 %v, 期望恰好 1 个", backups)
+	}
+	if matched, _ := filepath.Match(fmt.Sprintf("%s.v%d-*.bak", path, pre), backups[0]); !matched {
+		t.Errorf("备份文件 %s 不是版本 %d 的备份", backups[0], pre)
+	}
+	backup, err := sql.Open("sqlite", backups[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+	var version, relations int
+	if err := backup.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if err := backup.QueryRow(`SELECT COUNT(*) FROM comment_relations`).Scan(&relations); err != nil {
+		t.Fatal(err)
+	}
+	if version != pre || relations != 1 {
+		t.Errorf("备份的版本 = %d、关系 = %d 条, 期望 %d、1 条", version, relations, pre)
+	}
+}
+
+// cascadeVersion 重建关系表与统计表的破坏性版本
+func cascadeVersion(t *testing.T) int {
+	t.Helper()
+	for _, m := range migrations {
+		if m.Name == "video_delete_cascade" {
+			if !m.Destructive {
+				t.Fatalf("版本 %d 应标记为破坏性升级", m.Version)
+			}
+			return m.Version
+		}
+	}
+	t.Fatal("缺少 video_delete_cascade 版本")
+	return 0
+}
+
+func TestMigrateDownAndUp(t *testing.T) {
+	path := openBaselineDB(t)
+	if err := MigrateUp(0); err != nil {
+		t.Fatalf("升级失败: %v", err)
+	}
+
+	if err := MigrateDown(1); err != nil {
+		t.Fatalf("回退失败: %v", err)
+	}
+	assertSchemaVersion(t, 1)
+	assertBaselineData(t)
+	for _, table := range []string{"crawl_jobs", "crawl_schedules", "crawl_checkpoints", "raw_pages", "comments_fts", "video_crawls"} {
+		if tableExists(t, table) {
+			t.Errorf("回退到版本 1 后仍存在表 %s", table)
+		}
+	}
+	for _, col := range []string{"rcount", "root", "first_seen"} {
+		if columnExists(t, "bilibili_comments", col) {
+			t.Errorf("回退到版本 1 后仍存在列 %s", col)
+		}
+	}
+	if backups, _ := filepath.Glob(fmt.Sprintf("%s.v%d-*.bak", path, LatestVersion())); len(backups) != 1 {
+		t.Errorf("回退前的备份 %v, 期望恰好 1 个", backups)
+	}
+
+	if err := MigrateUp(0); err != nil {
+		t.Fatalf("再次升级失败: %v", err)
+	}
+	assertSchemaVersion(t, LatestVersion())
+	assertBaselineData(t)
+	if got := queryInt(t, `SELECT COUNT(*) FROM comments_fts WHERE comments_fts MATCH '学到了'`); got != 1 {
+		t.Errorf("全文索引命中 %d 条, 期望 1", got)
+	}
+}
+
+func TestMigrateUpRollsBackFailedStep(t *testing.T) {
+	openTestDB(t)
+	saveTestComments(t, "BV1xx411c7mD", "升级失败前的评论")
+
+	failing := Migration{
+		Version: LatestVersion() + 1,
+		Name:    "failing_step",
+		Up: func(tx *sql.Tx) error {
+			if err := addColumns("bilibili_comments", [][2]string{{"probe", "INTEGER NOT NULL DEFAULT 0"}})(tx); err != nil {
+				return err
+			}
+			return execSQL(
+				"CREATE TABLE migration_probe (id INTEGER PRIMARY KEY)",
+				"UPDATE bilibili_comments SET content = ''",
+				"INSERT INTO missing_table VALUES (1)",
+			)(tx)
+		},
+		Down: execSQL("DROP TABLE IF EXISTS migration_probe"),
+	}
+	saved := migrations
+	migrations = append(append([]Migration(nil), saved...), failing)
+	defer func() { migrations = saved }()
+
+	if err := MigrateUp(0); err == nil {
+		t.Fatal("迁移步骤失败时 MigrateUp 应返回错误")
+	}
+	assertSchemaVersion(t, failing.Version-1)
+	if tableExists(t, "migration_probe") {
+		t.Error("失败的步骤创建的表未回滚")
+	}
+	if columnExists(t, "bilibili_comments", "probe") {
+		t.Error("失败的步骤添加的列未回滚")
+	}
+	if got := queryInt(t, `SELECT COUNT(*) FROM bilibili_comments WHERE content = '升级失败前的评论'`); got != 1 {
+		t.Errorf("失败的步骤修改的数据未回滚, 剩余 %d 条原评论", got)
+	}
+}
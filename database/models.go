@@ -2,6 +2,7 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -16,6 +17,9 @@ import (
 // 全局数据库连接
 var db *sql.DB
 
+// dbFile 数据库文件路径，迁移前备份时使用
+var dbFile string
+
 // InitDB 初始化数据库连接
 func InitDB(dbPath string) error {
 	// 确保数据库目录存在
@@ -23,12 +27,40 @@ func InitDB(dbPath string) error {
 		return fmt.Errorf("创建数据库目录失败: %w", err)
 	}
 
+	if err := OpenDB(dbPath); err != nil {
+		return err
+	}
+
+	// 升级表结构
+	if err := MigrateUp(0); err != nil {
+		return fmt.Errorf("升级数据库结构失败: %w", err)
+	}
+
+	// 测试连接
+	if err := db.Ping(); err != nil {
+		return fmt.Errorf("数据库连接测试失败: %w", err)
+	}
+
+	logger.GetLogger().Infof("数据库初始化成功: %s", dbPath)
+	return nil
+}
+
+// OpenDB 打开数据库连接但不升级表结构，供 migrate 命令查看或回退版本
+func OpenDB(path string) error {
 	// 打开数据库连接
 	var err error
-	db, err = sql.Open("sqlite", dbPath)
+	// 外键约束按连接生效，通过 DSN 为连接池中的每个连接开启，删除视频时级联删除关系与统计
+	dsn := path
+	if strings.Contains(dsn, "?") {
+		dsn += "&_pragma=foreign_keys(1)"
+	} else {
+		dsn += "?_pragma=foreign_keys(1)"
+	}
+	db, err = sql.Open("sqlite", dsn)
 	if err != nil {
 		return fmt.Errorf("打开数据库失败: %w", err)
 	}
+	dbFile = path
 
 	// PRAGMA优化设置
 	pragmaStmts := []string{
@@ -41,18 +73,6 @@ func InitDB(dbPath string) error {
 			return fmt.Errorf("设置PRAGMA失败: %w", err)
 		}
 	}
-
-	// 创建表
-	if err := createTables(); err != nil {
-		return fmt.Errorf("创建表失败: %w", err)
-	}
-
-	// 测试连接
-	if err := db.Ping(); err != nil {
-		return fmt.Errorf("数据库连接测试失败: %w", err)
-	}
-
-	logger.GetLogger().Infof("数据库初始化成功: %s", dbPath)
 	return nil
 }
 
@@ -69,8 +89,9 @@ func GetDB() *sql.DB {
 	return db
 }
 
-// createTables 创建所需的数据库表
-func createTables() error {
+// createTables 创建最初版本的视频、评论、评论关系与评论统计表，即结构版本 1
+// 旧版本创建的数据库中表已存在时跳过；之后新增的表与列由后续版本创建
+func createTables(tx *sql.Tx) error {
 	// 创建视频信息表
 	videoTableSQL := `
 	CREATE TABLE IF NOT EXISTS video_info (
@@ -80,7 +101,7 @@ func createTables() error {
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);`
 
-	if _, err := db.Exec(videoTableSQL); err != nil {
+	if _, err := tx.Exec(videoTableSQL); err != nil {
 		return fmt.Errorf("创建视频表失败: %w", err)
 	}
 
@@ -110,7 +131,7 @@ func createTables() error {
 	CREATE INDEX IF NOT EXISTS idx_mid ON bilibili_comments(mid);
 	CREATE INDEX IF NOT EXISTS idx_bvid_ctime ON bilibili_comments(bvid, ctime);`
 
-	if _, err := db.Exec(commentTableSQL); err != nil {
+	if _, err := tx.Exec(commentTableSQL); err != nil {
 		return fmt.Errorf("创建评论表失败: %w", err)
 	}
 
@@ -126,7 +147,7 @@ func createTables() error {
 	
 	CREATE INDEX IF NOT EXISTS idx_parent_child ON comment_relations(parent_id, child_id);`
 
-	if _, err := db.Exec(relationTableSQL); err != nil {
+	if _, err := tx.Exec(relationTableSQL); err != nil {
 		return fmt.Errorf("创建评论关系表失败: %w", err)
 	}
 
@@ -139,7 +160,7 @@ func createTables() error {
 		FOREIGN KEY (bvid) REFERENCES video_info(bvid)
 	);`
 
-	if _, err := db.Exec(statsTableSQL); err != nil {
+	if _, err := tx.Exec(statsTableSQL); err != nil {
 		return fmt.Errorf("创建评论统计表失败: %w", err)
 	}
 
@@ -147,12 +168,45 @@ func createTables() error {
 	return nil
 }
 
+// addColumnIfMissing 为已存在的表补充新增列
+func addColumnIfMissing(tx *sql.Tx, table, column, definition string) error {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("查询表结构失败 (%s): %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return fmt.Errorf("扫描表结构失败 (%s): %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("遍历表结构失败 (%s): %w", table, err)
+	}
+	rows.Close()
+
+	if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("添加列失败 (%s.%s): %w", table, column, err)
+	}
+	logger.GetLogger().Infof("已为表 %s 添加列 %s", table, column)
+	return nil
+}
+
 // SaveVideo 保存视频信息到数据库
 func SaveVideo(video *Video) error {
 	// 直接存储文件名（不需要修改路径）
 	_, err := db.Exec(`
-        INSERT OR REPLACE INTO video_info (bvid, title, cover)
-        VALUES (?, ?, ?)`,
+        INSERT INTO video_info (bvid, title, cover)
+        VALUES (?, ?, ?)
+        ON CONFLICT(bvid) DO UPDATE SET
+            title = excluded.title, cover = excluded.cover, created_at = excluded.created_at`,
 		video.BVid, video.Title, video.Cover,
 	)
 
@@ -162,10 +216,55 @@ func SaveVideo(video *Video) error {
 	return nil
 }
 
-// SaveComment 保存评论到数据库
-func SaveComment(comment *Comment) error {
-	// 生成唯一ID (bvid + "_" + rpid)
-	uniqueID := fmt.Sprintf("%s_%d", comment.BVid, comment.Rpid)
+// commentColumns bilibili_comments 的全部列，与 commentValues、scanComment 的顺序一致
+var commentColumns = []string{
+	"unique_id", "bvid", "rpid", "content", "pictures", "oid", "mid", "parent", "fans_grade",
+	"ctime", "like_count", "upname", "sex", "following", "level", "location", "rcount",
+	"root", "dialog", "invisible", "time_desc", "avatar", "vip_type", "vip_status", "vip_label",
+	"fans_medal", "fans_medal_level", "emotes", "members", "pinned", "up_replied",
+	"first_seen", "last_seen", "deleted_at",
+}
+
+// commentPlaceholders 一条评论的 VALUES 占位符
+var commentPlaceholders = "(" + strings.TrimSuffix(strings.Repeat("?, ", len(commentColumns)), ", ") + ")"
+
+// commentUpsert 写入评论的 SQL 前缀，后接 VALUES 列表与 commentConflictUpdate
+var commentUpsert = "INSERT INTO bilibili_comments (" + strings.Join(commentColumns, ", ") + ") VALUES "
+
+// commentConflictUpdate 评论已存在时原地更新除主键外的全部列
+// 不使用 INSERT OR REPLACE：替换会先删除旧行且不触发删除触发器，导致全文索引与评论表不一致
+// 观察时间只扩展不回退；再次观察到已标记删除的评论时清除删除标记（从存档重新解析的旧观察除外）
+var commentConflictUpdate = func() string {
+	seenUpdates := map[string]string{
+		"first_seen": "first_seen = MIN(first_seen, excluded.first_seen)",
+		"last_seen":  "last_seen = MAX(last_seen, excluded.last_seen)",
+		"deleted_at": "deleted_at = CASE WHEN excluded.last_seen < deleted_at THEN deleted_at END",
+	}
+	sets := make([]string, 0, len(commentColumns)-1)
+	for _, col := range commentColumns[1:] {
+		if set, ok := seenUpdates[col]; ok {
+			sets = append(sets, set)
+			continue
+		}
+		sets = append(sets, col+" = excluded."+col)
+	}
+	return " ON CONFLICT(unique_id) DO UPDATE SET " + strings.Join(sets, ", ")
+}()
+
+// commentSelect 返回查询评论全部列的 SELECT 列表，alias 为表别名（可为空）
+func commentSelect(alias string) string {
+	if alias == "" {
+		return strings.Join(commentColumns, ", ")
+	}
+	return alias + "." + strings.Join(commentColumns, ", "+alias+".")
+}
+
+// commentValues 返回写入评论时各列的值，同时补全 UniqueID 与观察时间（未指定时为当前时间）
+func commentValues(comment *Comment) []interface{} {
+	comment.UniqueID = fmt.Sprintf("%s_%d", comment.BVid, comment.Rpid)
+	if comment.LastSeen.IsZero() {
+		comment.LastSeen = time.Unix(time.Now().Unix(), 0)
+	}
 
 	// 将图片数组转换为分号分隔的字符串
 	pictures := ""
@@ -177,15 +276,8 @@ func SaveComment(comment *Comment) error {
 		pictures = strings.Join(picURLs, ";")
 	}
 
-	// 将时间转换为Unix时间戳
-	ctime := comment.Ctime.Unix()
-
-	_, err := db.Exec(`
-		INSERT OR REPLACE INTO bilibili_comments 
-		(unique_id, bvid, rpid, content, pictures, oid, mid, parent, fans_grade, 
-		 ctime, like_count, upname, sex, following, level, location)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		uniqueID,
+	return []interface{}{
+		comment.UniqueID,
 		comment.BVid,
 		comment.Rpid,
 		comment.Content,
@@ -194,14 +286,105 @@ func SaveComment(comment *Comment) error {
 		comment.Mid,
 		comment.Parent,
 		comment.FansGrade,
-		ctime, // 使用整型时间戳
+		comment.Ctime.Unix(), // 使用整型时间戳
 		comment.LikeCount,
 		comment.Upname,
 		comment.Sex,
 		comment.Following,
 		comment.Level,
 		comment.Location,
+		comment.Rcount,
+		comment.Root,
+		comment.Dialog,
+		comment.Invisible,
+		comment.TimeDesc,
+		comment.Avatar,
+		comment.VipType,
+		comment.VipStatus,
+		comment.VipLabel,
+		comment.FansMedal,
+		comment.FansMedalLevel,
+		encodeJSONField(len(comment.Emotes), comment.Emotes),
+		encodeJSONField(len(comment.Members), comment.Members),
+		comment.Pinned,
+		comment.UpReplied,
+		comment.LastSeen.Unix(), // 新评论的首次观察时间即本次观察时间
+		comment.LastSeen.Unix(),
+		nil,
+	}
+}
+
+// encodeJSONField 将表情、@提及等结构化字段编码为 JSON 文本，为空时存储空字符串
+func encodeJSONField(n int, v interface{}) string {
+	if n == 0 {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// rowScanner *sql.Row 与 *sql.Rows 共有的 Scan 方法
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanComment 按 commentSelect 的列顺序读取一条评论
+func scanComment(row rowScanner) (Comment, error) {
+	var c Comment
+	var ctime int64 // 整型时间戳
+	var picturesStr, emotes, members string
+	var firstSeen, lastSeen int64
+	var deletedAt sql.NullInt64
+	err := row.Scan(
+		&c.UniqueID, &c.BVid, &c.Rpid, &c.Content, &picturesStr,
+		&c.Oid, &c.Mid, &c.Parent, &c.FansGrade, &ctime,
+		&c.LikeCount, &c.Upname, &c.Sex, &c.Following, &c.Level,
+		&c.Location, &c.Rcount, &c.Root, &c.Dialog, &c.Invisible,
+		&c.TimeDesc, &c.Avatar, &c.VipType, &c.VipStatus, &c.VipLabel,
+		&c.FansMedal, &c.FansMedalLevel, &emotes, &members, &c.Pinned,
+		&c.UpReplied, &firstSeen, &lastSeen, &deletedAt,
 	)
+	if err != nil {
+		return c, err
+	}
+	c.FirstSeen = time.Unix(firstSeen, 0)
+	c.LastSeen = time.Unix(lastSeen, 0)
+	if deletedAt.Valid {
+		t := time.Unix(deletedAt.Int64, 0)
+		c.DeletedAt = &t
+	}
+
+	// 将时间戳转换为时间对象
+	c.Ctime = time.Unix(ctime, 0)
+	c.FormattedTime = c.Ctime.Format("2006-01-02 15:04:05")
+
+	// 解析图片字符串
+	if picturesStr != "" {
+		for _, url := range strings.Split(picturesStr, ";") {
+			if url != "" {
+				c.Pictures = append(c.Pictures, Picture{ImgSrc: url})
+			}
+		}
+	}
+	if emotes != "" {
+		if err := json.Unmarshal([]byte(emotes), &c.Emotes); err != nil {
+			return c, fmt.Errorf("解析表情失败 (%s): %w", c.UniqueID, err)
+		}
+	}
+	if members != "" {
+		if err := json.Unmarshal([]byte(members), &c.Members); err != nil {
+			return c, fmt.Errorf("解析@提及失败 (%s): %w", c.UniqueID, err)
+		}
+	}
+	return c, nil
+}
+
+// SaveComment 保存评论到数据库
+func SaveComment(comment *Comment) error {
+	_, err := db.Exec(commentUpsert+commentPlaceholders+commentConflictUpdate, commentValues(comment)...)
 
 	if err != nil {
 		return fmt.Errorf("保存评论失败 (Rpid: %d): %w", comment.Rpid, err)
@@ -247,44 +430,16 @@ func BatchSaveComments(comments []*Comment) error {
 
 			// 构造多值插入SQL
 			valueStrings := make([]string, 0, len(batch))
-			valueArgs := make([]interface{}, 0, len(batch)*16)
+			valueArgs := make([]interface{}, 0, len(batch)*len(commentColumns))
 			for _, comment := range batch {
-				uniqueID := fmt.Sprintf("%s_%d", comment.BVid, comment.Rpid)
-				comment.UniqueID = uniqueID
-				pictures := ""
-				if len(comment.Pictures) > 0 {
-					var picURLs []string
-					for _, pic := range comment.Pictures {
-						picURLs = append(picURLs, pic.ImgSrc)
-					}
-					pictures = strings.Join(picURLs, ";")
-				}
-				ctime := comment.Ctime.Unix()
-				valueStrings = append(valueStrings, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
-				valueArgs = append(valueArgs,
-					comment.UniqueID,
-					comment.BVid,
-					comment.Rpid,
-					comment.Content,
-					pictures,
-					comment.Oid,
-					comment.Mid,
-					comment.Parent,
-					comment.FansGrade,
-					ctime,
-					comment.LikeCount,
-					comment.Upname,
-					comment.Sex,
-					comment.Following,
-					comment.Level,
-					comment.Location,
-				)
+				valueStrings = append(valueStrings, commentPlaceholders)
+				valueArgs = append(valueArgs, commentValues(comment)...)
+			}
+			insertSQL := commentUpsert + strings.Join(valueStrings, ",") + commentConflictUpdate
+			err := recordSnapshots(tx, batch)
+			if err == nil {
+				_, err = tx.Exec(insertSQL, valueArgs...)
 			}
-			insertSQL := "INSERT OR REPLACE INTO bilibili_comments " +
-				"(unique_id, bvid, rpid, content, pictures, oid, mid, parent, fans_grade, " +
-				"ctime, like_count, upname, sex, following, level, location) VALUES " +
-				strings.Join(valueStrings, ",")
-			_, err := tx.Exec(insertSQL, valueArgs...)
 			if err != nil {
 				errorCount += len(batch)
 				logger.GetLogger().Errorf("批量插入评论失败 (index: %d-%d): %v", chunkStart+batchStart, chunkStart+batchEnd-1, err)
@@ -308,6 +463,62 @@ func BatchSaveComments(comments []*Comment) error {
 	return nil
 }
 
+// recordSnapshots 对比将要写入的评论与已入库的状态，内容或点赞数有变化时把变化前的状态写入 comment_snapshots
+// 早于已入库状态的观察（如从存档重新解析旧的响应）不记录；需在 commentValues 补全 UniqueID 与观察时间之后调用
+func recordSnapshots(tx *sql.Tx, batch []*Comment) error {
+	args := make([]interface{}, len(batch))
+	for i, c := range batch {
+		args[i] = c.UniqueID
+	}
+	rows, err := tx.Query(`
+		SELECT unique_id, COALESCE(content, ''), COALESCE(like_count, 0), last_seen
+		FROM bilibili_comments
+		WHERE unique_id IN (`+placeholders(len(batch))+`)`, args...)
+	if err != nil {
+		return fmt.Errorf("查询已入库评论失败: %w", err)
+	}
+	type commentState struct {
+		content  string
+		likes    int
+		lastSeen int64
+	}
+	stored := make(map[string]commentState)
+	for rows.Next() {
+		var id string
+		var st commentState
+		if err := rows.Scan(&id, &st.content, &st.likes, &st.lastSeen); err != nil {
+			rows.Close()
+			return fmt.Errorf("扫描已入库评论失败: %w", err)
+		}
+		stored[id] = st
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("遍历已入库评论失败: %w", err)
+	}
+
+	for _, c := range batch {
+		old, ok := stored[c.UniqueID]
+		seen := c.LastSeen.Unix()
+		if !ok || seen < old.lastSeen || (old.content == c.Content && old.likes == c.LikeCount) {
+			continue
+		}
+		var content interface{}
+		if old.content != c.Content {
+			content = old.content
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO comment_snapshots (unique_id, bvid, observed_at, seen_at, content, like_count)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			c.UniqueID, c.BVid, seen, old.lastSeen, content, old.likes); err != nil {
+			return fmt.Errorf("保存评论快照失败: %w", err)
+		}
+		// 同一批中重复出现的评论与本次写入的状态比较
+		stored[c.UniqueID] = commentState{content: c.Content, likes: c.LikeCount, lastSeen: seen}
+	}
+	return nil
+}
+
 // SaveCommentRelations 保存评论关系
 func SaveCommentRelations(parentID string, childIDs []string) error {
 	if len(childIDs) == 0 {
@@ -324,9 +535,12 @@ func SaveCommentRelations(parentID string, childIDs []string) error {
 		}
 	}()
 
+	// 父评论可能尚未入库（子评论先于主评论到达），跳过外键不满足的关系，结束后由 RebuildAllCommentRelations 补齐
 	stmt, err := tx.Prepare(`
 		INSERT OR IGNORE INTO comment_relations (parent_id, child_id)
-		VALUES (?, ?)`)
+		SELECT p.unique_id, c.unique_id
+		FROM bilibili_comments p, bilibili_comments c
+		WHERE p.unique_id = ? AND c.unique_id = ?`)
 
 	if err != nil {
 		return fmt.Errorf("准备关系插入语句失败: %w", err)
@@ -347,35 +561,64 @@ func SaveCommentRelations(parentID string, childIDs []string) error {
 	return nil
 }
 
-// UpdateCommentStats 更新评论统计信息
+// UpdateCommentStats 更新评论统计信息，视频信息未入库时不写入（统计表以外键关联视频）
 func UpdateCommentStats(bvid string) error {
 	_, err := db.Exec(`
         INSERT OR REPLACE INTO comment_stats (bvid, comment_count)
-        SELECT ?, COUNT(*) 
-        FROM bilibili_comments 
-        WHERE bvid = ? AND parent = '0'`,
-		bvid, bvid,
+        SELECT v.bvid, (SELECT COUNT(*) FROM bilibili_comments WHERE bvid = v.bvid AND parent = '0')
+        FROM video_info v
+        WHERE v.bvid = ?`,
+		bvid,
 	)
 	return err
 }
 
+// GetRootCommentIndex 获取视频已入库主评论的 rpid -> 回复数映射，以及主评论的最新发布时间（Unix 秒）
+// 用于增量爬取判断翻页终点和需要刷新的楼层
+func GetRootCommentIndex(bvid string) (map[int64]int, int64, error) {
+	rows, err := db.Query(`SELECT rpid, rcount, COALESCE(ctime, 0) FROM bilibili_comments WHERE bvid = ? AND parent = '0'`, bvid)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询已入库主评论失败: %w", err)
+	}
+	defer rows.Close()
+
+	roots := make(map[int64]int)
+	var latest int64
+	for rows.Next() {
+		var rpid, ctime int64
+		var rcount int
+		if err := rows.Scan(&rpid, &rcount, &ctime); err != nil {
+			return nil, 0, fmt.Errorf("扫描主评论失败: %w", err)
+		}
+		roots[rpid] = rcount
+		if ctime > latest {
+			latest = ctime
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("遍历主评论失败: %w", err)
+	}
+	return roots, latest, nil
+}
+
 // 获取分页视频列表
 func GetVideosPaginated(page, perPage int, searchTerm string) ([]Video, int, error) {
 	offset := (page - 1) * perPage
 	var videos []Video
 	var total int
 
-	// 获取总数
-	countQuery := "SELECT COUNT(*) FROM video_info"
-	if searchTerm != "" {
-		countQuery += " WHERE title LIKE ?"
-	}
-
+	// 标题搜索使用全文索引，语法与 /api/search 相同
+	where := ""
 	var args []interface{}
 	if searchTerm != "" {
-		args = append(args, "%"+searchTerm+"%")
+		var cond string
+		cond, args = keywordCondition(searchTerm, "v.title", "v.rowid", "videos_fts")
+		where = " WHERE " + cond
 	}
 
+	// 获取总数
+	countQuery := "SELECT COUNT(*) FROM video_info v" + where
+
 	err := db.QueryRow(countQuery, args...).Scan(&total)
 	if err != nil {
 		return nil, 0, fmt.Errorf("获取视频总数失败: %w", err)
@@ -387,16 +630,8 @@ func GetVideosPaginated(page, perPage int, searchTerm string) ([]Video, int, err
         FROM video_info v
         LEFT JOIN comment_stats s ON v.bvid = s.bvid
     `
-	if searchTerm != "" {
-		query += " WHERE v.title LIKE ?"
-	}
-	query += " ORDER BY v.created_at DESC LIMIT ? OFFSET ?"
-
-	if searchTerm != "" {
-		args = append(args, "%"+searchTerm+"%", perPage, offset)
-	} else {
-		args = []interface{}{perPage, offset}
-	}
+	query += where + " ORDER BY v.created_at DESC LIMIT ? OFFSET ?"
+	args = append(args, perPage, offset)
 
 	rows, err := db.Query(query, args...)
 	if err != nil {
@@ -443,158 +678,24 @@ func GetVideoByBVid(bvid string) (*Video, error) {
 	return &v, nil
 }
 
-// GetCommentsByBVid 获取指定视频的评论
-func GetCommentsByBVid(bvid string, page, pageSize int, keyword string) ([]Comment, int, error) {
-	offset := (page - 1) * pageSize
-	var comments []Comment
-	var total int
-
-	// 从统计表获取总数
-	err := db.QueryRow("SELECT comment_count FROM comment_stats WHERE bvid = ?", bvid).Scan(&total)
+// GetCommentReplies 获取评论的回复，按点赞数降序
+// comment_relations 由 parent 列重建，这里直接按 parent 列查询以使用 (parent, like_count, unique_id) 索引
+func GetCommentReplies(parentID string, pr PageRequest) (*CommentPage, error) {
+	page := &CommentPage{}
+	err := db.QueryRow(`SELECT COUNT(*) FROM bilibili_comments WHERE parent = ?`, parentID).Scan(&page.Total)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			// 如果没有统计记录，回退到实时计数
-			err = db.QueryRow("SELECT COUNT(*) FROM bilibili_comments WHERE bvid = ?", bvid).Scan(&total)
-			if err != nil {
-				return nil, 0, fmt.Errorf("获取评论总数失败: %w", err)
-			}
-		} else {
-			return nil, 0, fmt.Errorf("获取评论统计失败: %w", err)
-		}
+		return nil, fmt.Errorf("获取回复总数失败: %w", err)
 	}
-
-	// 查询评论
-	query := `
-		SELECT unique_id, bvid, rpid, content, pictures, oid, mid, parent, 
-			fans_grade, ctime, like_count, upname, sex, following, level, location
-		FROM bilibili_comments
-        WHERE bvid = ? AND parent = '0'`
-
-	var args []interface{}
-	args = append(args, bvid)
-
-	if keyword != "" {
-		query += " AND content LIKE ?"
-		args = append(args, "%"+keyword+"%")
+	if page.Total == 0 {
+		page.Comments = []Comment{}
+		return page, nil
 	}
 
-	query += " ORDER BY like_count DESC, ctime DESC LIMIT ? OFFSET ?"
-	args = append(args, pageSize, offset)
-
-	rows, err := db.Query(query, args...)
-	if err != nil {
-		return nil, 0, fmt.Errorf("查询评论失败: %w", err)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var c Comment
-		var ctime int64 // 整型时间戳
-		var picturesStr string
-
-		err := rows.Scan(
-			&c.UniqueID, &c.BVid, &c.Rpid, &c.Content, &picturesStr,
-			&c.Oid, &c.Mid, &c.Parent, &c.FansGrade, &ctime,
-			&c.LikeCount, &c.Upname, &c.Sex, &c.Following, &c.Level,
-			&c.Location,
-		)
-
-		if err != nil {
-			return nil, 0, fmt.Errorf("扫描评论行失败: %w", err)
-		}
-
-		// 将时间戳转换为时间对象
-		c.Ctime = time.Unix(ctime, 0)
-		c.FormattedTime = c.Ctime.Format("2006-01-02 15:04:05")
-
-		// 解析图片字符串
-		if picturesStr != "" {
-			picURLs := strings.Split(picturesStr, ";")
-			for _, url := range picURLs {
-				if url != "" {
-					c.Pictures = append(c.Pictures, Picture{ImgSrc: url})
-				}
-			}
-		}
-
-		comments = append(comments, c)
-	}
-
-	return comments, total, nil
-}
-
-// GetCommentReplies 获取评论的回复
-func GetCommentReplies(parentID string, page, pageSize int) ([]Comment, int, error) {
-	offset := (page - 1) * pageSize
-
-	// 1. 先获取回复总数
-	var total int
-	countQuery := `SELECT COUNT(*) 
-                   FROM comment_relations 
-                   WHERE parent_id = ?`
-	err := db.QueryRow(countQuery, parentID).Scan(&total)
-	if err != nil {
-		return nil, 0, fmt.Errorf("获取回复总数失败: %w", err)
-	}
-
-	// 2. 如果总数为0，直接返回空列表
-	if total == 0 {
-		return []Comment{}, 0, nil
-	}
-
-	// 3. 查询回复列表
-	query := `
-        SELECT c.unique_id, c.bvid, c.rpid, c.content, c.pictures, c.oid, c.mid, 
-               c.parent, c.fans_grade, c.ctime, c.like_count, c.upname, 
-               c.sex, c.following, c.level, c.location
-        FROM bilibili_comments c
-        JOIN comment_relations r ON c.unique_id = r.child_id
-        WHERE r.parent_id = ?
-        ORDER BY c.like_count DESC, c.ctime DESC
-        LIMIT ? OFFSET ?`
-
-	rows, err := db.Query(query, parentID, pageSize, offset)
+	page.Comments, page.NextCursor, err = queryCommentPage("parent = ?", []interface{}{parentID}, CommentSortLikes, false, pr)
 	if err != nil {
-		return nil, 0, fmt.Errorf("查询回复失败: %w", err)
-	}
-	defer rows.Close()
-
-	// 4. 声明并初始化replies切片
-	var replies []Comment
-	for rows.Next() {
-		var c Comment
-		var ctime int64
-		var picturesStr string
-
-		err := rows.Scan(
-			&c.UniqueID, &c.BVid, &c.Rpid, &c.Content, &picturesStr,
-			&c.Oid, &c.Mid, &c.Parent, &c.FansGrade, &ctime,
-			&c.LikeCount, &c.Upname, &c.Sex, &c.Following, &c.Level,
-			&c.Location,
-		)
-
-		if err != nil {
-			return nil, 0, fmt.Errorf("扫描回复行失败: %w", err)
-		}
-
-		// 将时间戳转换为时间对象
-		c.Ctime = time.Unix(ctime, 0)
-		c.FormattedTime = c.Ctime.Format("2006-01-02 15:04:05")
-
-		// 解析图片字符串
-		if picturesStr != "" {
-			picURLs := strings.Split(picturesStr, ";")
-			for _, url := range picURLs {
-				if url != "" {
-					c.Pictures = append(c.Pictures, Picture{ImgSrc: url})
-				}
-			}
-		}
-
-		replies = append(replies, c)
+		return nil, err
 	}
-
-	return replies, total, nil
+	return page, nil
 }
 
 // ImportVideoData 导入视频数据
@@ -636,6 +737,12 @@ type Picture struct {
 	ImgSrc string `json:"img_src"`
 }
 
+// Mention 评论中@提及的用户
+type Mention struct {
+	Mid   int64  `json:"mid"`
+	Uname string `json:"uname"`
+}
+
 // 视频结构体
 type Video struct {
 	BVid         string `json:"bvid"`
@@ -649,24 +756,44 @@ type Video struct {
 
 // 评论结构体
 type Comment struct {
-	UniqueID      string    `json:"unique_id"`
-	BVid          string    `json:"bvid"`
-	Rpid          int64     `json:"rpid"`
-	Content       string    `json:"content"`
-	Pictures      []Picture `json:"pictures"`
-	Oid           int       `json:"oid"`
-	Mid           int       `json:"mid"`
-	Parent        string    `json:"parent"`
-	FansGrade     int       `json:"fans_grade"`
-	Ctime         time.Time `json:"ctime"`
-	LikeCount     int       `json:"like_count"`
-	Upname        string    `json:"upname"`
-	Sex           string    `json:"sex"`
-	Following     bool      `json:"following"`
-	Level         int       `json:"level"`
-	Location      string    `json:"location"`
-	Replies       []string  `json:"replies,omitempty"` // 现在只存储回复ID
-	FormattedTime string    `json:"formatted_time,omitempty"`
+	UniqueID       string            `json:"unique_id"`
+	BVid           string            `json:"bvid"`
+	Rpid           int64             `json:"rpid"`
+	Content        string            `json:"content"`
+	Pictures       []Picture         `json:"pictures"`
+	Oid            int64             `json:"oid"`
+	Mid            int64             `json:"mid"`
+	Parent         string            `json:"parent"`
+	FansGrade      int               `json:"fans_grade"`
+	Ctime          time.Time         `json:"ctime"`
+	LikeCount      int               `json:"like_count"`
+	Upname         string            `json:"upname"`
+	Sex            string            `json:"sex"`
+	Following      bool              `json:"following"`
+	Level          int               `json:"level"`
+	Location       string            `json:"location"`
+	Rcount         int               `json:"rcount,omitempty"`
+	Root           int64             `json:"root"`   // 所属主评论 rpid，主评论为 0
+	Dialog         int64             `json:"dialog"` // 所属对话首条评论的 rpid
+	Invisible      bool              `json:"invisible"`
+	TimeDesc       string            `json:"time_desc,omitempty"`
+	Avatar         string            `json:"avatar,omitempty"`
+	VipType        int               `json:"vip_type"`
+	VipStatus      int               `json:"vip_status"`
+	VipLabel       string            `json:"vip_label,omitempty"`
+	FansMedal      string            `json:"fans_medal,omitempty"` // 粉丝勋章名称
+	FansMedalLevel int               `json:"fans_medal_level,omitempty"`
+	Emotes         map[string]string `json:"emotes,omitempty"`  // 表情文本 -> 图片地址
+	Members        []Mention         `json:"members,omitempty"` // @提及的用户
+	Pinned         bool              `json:"pinned"`            // 是否被UP主置顶
+	UpReplied      bool              `json:"up_replied"`        // UP主是否回复过该评论
+	Replies        []string          `json:"replies,omitempty"` // 现在只存储回复ID
+	FormattedTime  string            `json:"formatted_time,omitempty"`
+	ReplyCount     int               `json:"reply_count"`               // 已入库的直接回复数，由列表查询填充
+	LatestReplyAt  *time.Time        `json:"latest_reply_at,omitempty"` // 最新一条直接回复的发布时间
+	FirstSeen      time.Time         `json:"first_seen"`                // 首次被爬取观察到的时间
+	LastSeen       time.Time         `json:"last_seen"`                 // 最近一次被观察到的时间，写入时为本次观察时间
+	DeletedAt      *time.Time        `json:"deleted_at,omitempty"`      // 完整爬取中未再出现（已在B站删除）的时间
 }
 
 // RebuildAllCommentRelations 重建指定bvid下所有评论的父子关系
@@ -693,7 +820,10 @@ func RebuildAllCommentRelations(bvid string) error {
 	if err != nil {
 		return fmt.Errorf("开启事务失败: %w", err)
 	}
-	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO comment_relations (parent_id, child_id) VALUES (?, ?)`)
+	// 父评论未入库的回复没有关系记录，回复树按 dialog 与 root 列补全
+	stmt, err := tx.Prepare(`
+		INSERT OR IGNORE INTO comment_relations (parent_id, child_id)
+		SELECT p.unique_id, ? FROM bilibili_comments p WHERE p.unique_id = ?`)
 	if err != nil {
 		tx.Rollback()
 		return fmt.Errorf("准备插入语句失败: %w", err)
@@ -706,7 +836,7 @@ func RebuildAllCommentRelations(bvid string) error {
 			tx.Rollback()
 			return fmt.Errorf("扫描评论失败: %w", err)
 		}
-		_, err := stmt.Exec(parentID, childID)
+		_, err := stmt.Exec(childID, parentID)
 		if err != nil {
 			tx.Rollback()
 			return fmt.Errorf("插入评论关系失败: %w", err)
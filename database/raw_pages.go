@@ -0,0 +1,103 @@
+package database
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"fmt"
+	"io"
+	"time"
+)
+
+// RawPage 评论接口一页的原始响应，用于新增字段后从存档回填而无需重新爬取
+type RawPage struct {
+	ID        int64     `json:"id"`
+	Bvid      string    `json:"bvid"`
+	Oid       int64     `json:"oid"`
+	Kind      string    `json:"kind"` // main: 主评论列表，reply: 子评论列表
+	Root      int64     `json:"root"` // 子评论页所属的主评论 rpid
+	Page      int       `json:"page"`
+	Offset    string    `json:"offset"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Body      []byte    `json:"-"` // 解压后的原始 JSON
+}
+
+// createRawPagesTable 创建原始响应表，响应体以 gzip 压缩存储
+func createRawPagesTable(tx *sql.Tx) error {
+	rawPagesTableSQL := `
+	CREATE TABLE IF NOT EXISTS raw_pages (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		bvid TEXT NOT NULL,
+		oid INTEGER NOT NULL DEFAULT 0,
+		kind TEXT NOT NULL,
+		root INTEGER NOT NULL DEFAULT 0,
+		page INTEGER NOT NULL DEFAULT 0,
+		cursor_offset TEXT NOT NULL DEFAULT '',
+		fetched_at INTEGER NOT NULL,
+		body BLOB NOT NULL -- gzip 压缩的原始 JSON
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_raw_pages_bvid ON raw_pages(bvid, id);`
+
+	if _, err := tx.Exec(rawPagesTableSQL); err != nil {
+		return fmt.Errorf("创建原始响应表失败: %w", err)
+	}
+	return nil
+}
+
+// SaveRawPage 压缩并保存一页原始响应
+func SaveRawPage(page *RawPage) error {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(page.Body); err != nil {
+		return fmt.Errorf("压缩原始响应失败: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("压缩原始响应失败: %w", err)
+	}
+
+	fetchedAt := page.FetchedAt
+	if fetchedAt.IsZero() {
+		fetchedAt = time.Now()
+	}
+	_, err := db.Exec(`
+		INSERT INTO raw_pages (bvid, oid, kind, root, page, cursor_offset, fetched_at, body)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		page.Bvid, page.Oid, page.Kind, page.Root, page.Page, page.Offset, fetchedAt.Unix(), buf.Bytes(),
+	)
+	if err != nil {
+		return fmt.Errorf("保存原始响应失败: %w", err)
+	}
+	return nil
+}
+
+// GetRawPages 按获取顺序返回视频的全部原始响应（已解压）
+func GetRawPages(bvid string) ([]RawPage, error) {
+	rows, err := db.Query(`
+		SELECT id, bvid, oid, kind, root, page, cursor_offset, fetched_at, body
+		FROM raw_pages WHERE bvid = ? ORDER BY id`, bvid)
+	if err != nil {
+		return nil, fmt.Errorf("查询原始响应失败: %w", err)
+	}
+	defer rows.Close()
+
+	var pages []RawPage
+	for rows.Next() {
+		var p RawPage
+		var fetchedAt int64
+		var compressed []byte
+		if err := rows.Scan(&p.ID, &p.Bvid, &p.Oid, &p.Kind, &p.Root, &p.Page, &p.Offset, &fetchedAt, &compressed); err != nil {
+			return nil, fmt.Errorf("读取原始响应失败: %w", err)
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, fmt.Errorf("解压原始响应 %d 失败: %w", p.ID, err)
+		}
+		if p.Body, err = io.ReadAll(zr); err != nil {
+			return nil, fmt.Errorf("解压原始响应 %d 失败: %w", p.ID, err)
+		}
+		p.FetchedAt = time.Unix(fetchedAt, 0)
+		pages = append(pages, p)
+	}
+	return pages, rows.Err()
+}
@@ -0,0 +1,220 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Schedule 定时爬取计划
+type Schedule struct {
+	ID          int64      `json:"id"`
+	Type        string     `json:"type"`   // 与爬取任务类型相同：video 或 up
+	Target      string     `json:"target"` // 视频为bvid，UP主为mid
+	Cron        string     `json:"cron"`
+	FetchAll    bool       `json:"fetch_all"`
+	Incremental bool       `json:"incremental"`
+	Enabled     bool       `json:"enabled"`
+	LastRunAt   *time.Time `json:"last_run_at,omitempty"`
+	NextRunAt   *time.Time `json:"next_run_at,omitempty"`
+	LastJobID   int64      `json:"last_job_id,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// createSchedulesTable 创建定时爬取计划表和接口请求用量表
+func createSchedulesTable(tx *sql.Tx) error {
+	scheduleTableSQL := `
+	CREATE TABLE IF NOT EXISTS crawl_schedules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		type TEXT NOT NULL,
+		target TEXT NOT NULL,
+		cron TEXT NOT NULL,
+		fetch_all BOOLEAN NOT NULL DEFAULT 0,
+		incremental BOOLEAN NOT NULL DEFAULT 0,
+		enabled BOOLEAN NOT NULL DEFAULT 1,
+		last_run_at INTEGER,
+		next_run_at INTEGER,
+		last_job_id INTEGER NOT NULL DEFAULT 0,
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_crawl_schedules_next ON crawl_schedules(enabled, next_run_at);
+
+	CREATE TABLE IF NOT EXISTS request_usage (
+		day TEXT PRIMARY KEY, -- 本地日期 YYYY-MM-DD
+		requests INTEGER NOT NULL DEFAULT 0
+	);`
+
+	if _, err := tx.Exec(scheduleTableSQL); err != nil {
+		return fmt.Errorf("创建定时爬取计划表失败: %w", err)
+	}
+	return nil
+}
+
+const scheduleColumns = `id, type, target, cron, fetch_all, incremental, enabled, last_run_at, next_run_at, last_job_id, created_at, updated_at`
+
+// scanSchedule 扫描一行计划记录
+func scanSchedule(scanner interface{ Scan(...any) error }) (*Schedule, error) {
+	var s Schedule
+	var lastRun, nextRun sql.NullInt64
+	var createdAt, updatedAt int64
+	if err := scanner.Scan(&s.ID, &s.Type, &s.Target, &s.Cron, &s.FetchAll, &s.Incremental, &s.Enabled,
+		&lastRun, &nextRun, &s.LastJobID, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+	s.LastRunAt = unixPtr(lastRun)
+	s.NextRunAt = unixPtr(nextRun)
+	s.CreatedAt = time.Unix(createdAt, 0)
+	s.UpdatedAt = time.Unix(updatedAt, 0)
+	return &s, nil
+}
+
+// unixPtr 将可空的 Unix 时间戳转换为时间指针
+func unixPtr(v sql.NullInt64) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := time.Unix(v.Int64, 0)
+	return &t
+}
+
+// nullUnix 将时间指针转换为可空的 Unix 时间戳
+func nullUnix(t *time.Time) sql.NullInt64 {
+	if t == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.Unix(), Valid: true}
+}
+
+// CreateSchedule 新建定时爬取计划，成功后回填 ID 与创建时间
+func CreateSchedule(s *Schedule) error {
+	now := time.Unix(time.Now().Unix(), 0)
+	res, err := db.Exec(`
+		INSERT INTO crawl_schedules (type, target, cron, fetch_all, incremental, enabled, next_run_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.Type, s.Target, s.Cron, s.FetchAll, s.Incremental, s.Enabled, nullUnix(s.NextRunAt), now.Unix(), now.Unix(),
+	)
+	if err != nil {
+		return fmt.Errorf("创建定时爬取计划失败: %w", err)
+	}
+	if s.ID, err = res.LastInsertId(); err != nil {
+		return fmt.Errorf("获取计划ID失败: %w", err)
+	}
+	s.CreatedAt = now
+	s.UpdatedAt = now
+	return nil
+}
+
+// UpdateSchedule 更新定时爬取计划的配置字段
+func UpdateSchedule(s *Schedule) error {
+	now := time.Unix(time.Now().Unix(), 0)
+	_, err := db.Exec(`
+		UPDATE crawl_schedules SET type = ?, target = ?, cron = ?, fetch_all = ?, incremental = ?, enabled = ?,
+			next_run_at = ?, updated_at = ?
+		WHERE id = ?`,
+		s.Type, s.Target, s.Cron, s.FetchAll, s.Incremental, s.Enabled, nullUnix(s.NextRunAt), now.Unix(), s.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("更新定时爬取计划失败: %w", err)
+	}
+	s.UpdatedAt = now
+	return nil
+}
+
+// MarkScheduleRun 记录计划的一次执行与下次执行时间，jobID 为 0 表示本次未提交任务
+func MarkScheduleRun(id int64, runAt time.Time, nextRun *time.Time, jobID int64) error {
+	_, err := db.Exec(`
+		UPDATE crawl_schedules SET last_run_at = ?, next_run_at = ?,
+			last_job_id = CASE WHEN ? > 0 THEN ? ELSE last_job_id END
+		WHERE id = ?`,
+		runAt.Unix(), nullUnix(nextRun), jobID, jobID, id,
+	)
+	if err != nil {
+		return fmt.Errorf("更新计划执行时间失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteSchedule 删除定时爬取计划，计划不存在时返回 false
+func DeleteSchedule(id int64) (bool, error) {
+	res, err := db.Exec(`DELETE FROM crawl_schedules WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("删除定时爬取计划失败: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("删除定时爬取计划失败: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetSchedule 按ID获取计划，不存在时返回 nil
+func GetSchedule(id int64) (*Schedule, error) {
+	row := db.QueryRow(`SELECT `+scheduleColumns+` FROM crawl_schedules WHERE id = ?`, id)
+	s, err := scanSchedule(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询定时爬取计划失败: %w", err)
+	}
+	return s, nil
+}
+
+// GetSchedules 获取全部计划（按ID排序）
+func GetSchedules() ([]Schedule, error) {
+	return querySchedules(`SELECT ` + scheduleColumns + ` FROM crawl_schedules ORDER BY id`)
+}
+
+// GetDueSchedules 获取已启用且到期（下次执行时间不晚于 now）的计划
+func GetDueSchedules(now time.Time) ([]Schedule, error) {
+	return querySchedules(`SELECT `+scheduleColumns+` FROM crawl_schedules
+		WHERE enabled = 1 AND next_run_at IS NOT NULL AND next_run_at <= ?
+		ORDER BY next_run_at, id`, now.Unix())
+}
+
+func querySchedules(query string, args ...interface{}) ([]Schedule, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询定时爬取计划失败: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []Schedule{}
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描计划行失败: %w", err)
+		}
+		schedules = append(schedules, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历计划行失败: %w", err)
+	}
+	return schedules, nil
+}
+
+// AddRequestUsage 累加指定日期的接口请求数
+func AddRequestUsage(day string, requests int64) error {
+	_, err := db.Exec(`
+		INSERT INTO request_usage (day, requests) VALUES (?, ?)
+		ON CONFLICT(day) DO UPDATE SET requests = requests + excluded.requests`,
+		day, requests,
+	)
+	if err != nil {
+		return fmt.Errorf("记录接口请求用量失败: %w", err)
+	}
+	return nil
+}
+
+// GetRequestUsage 获取指定日期的接口请求数
+func GetRequestUsage(day string) (int64, error) {
+	var requests int64
+	err := db.QueryRow(`SELECT requests FROM request_usage WHERE day = ?`, day).Scan(&requests)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("查询接口请求用量失败: %w", err)
+	}
+	return requests, nil
+}
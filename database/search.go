@@ -0,0 +1,540 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 全文搜索：评论内容与视频标题各有一张 FTS5 外部内容表（trigram 分词，中文无需分词词典），由触发器与源表保持同步。
+// 搜索表达式支持空格或 AND 连接多个词、OR、NOT 或 - 前缀排除、双引号短语与括号分组，优先级 NOT > AND > OR。
+// trigram 无法用索引匹配少于 3 个字的词，这类词退化为 LIKE 子串匹配，结果一致但需要扫描。
+
+// ErrInvalidSearch 搜索表达式无法解析
+var ErrInvalidSearch = errors.New("无效的搜索表达式")
+
+// maxSearchTerms 一个搜索表达式最多包含的词数
+const maxSearchTerms = 16
+
+// minIndexedRunes trigram 索引可匹配的最短词长
+const minIndexedRunes = 3
+
+// createSearchTables 创建全文索引表与同步触发器，并为已有数据建立索引
+func createSearchTables(tx *sql.Tx) error {
+	return execSQL(
+		`CREATE VIRTUAL TABLE IF NOT EXISTS comments_fts USING fts5(
+			content, content='bilibili_comments', content_rowid='rowid', tokenize='trigram')`,
+		`CREATE TRIGGER IF NOT EXISTS comments_fts_ai AFTER INSERT ON bilibili_comments BEGIN
+			INSERT INTO comments_fts(rowid, content) VALUES (new.rowid, new.content);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS comments_fts_ad AFTER DELETE ON bilibili_comments BEGIN
+			INSERT INTO comments_fts(comments_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS comments_fts_au AFTER UPDATE OF content ON bilibili_comments BEGIN
+			INSERT INTO comments_fts(comments_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+			INSERT INTO comments_fts(rowid, content) VALUES (new.rowid, new.content);
+		END`,
+		`INSERT INTO comments_fts(comments_fts) VALUES ('rebuild')`,
+
+		`CREATE VIRTUAL TABLE IF NOT EXISTS videos_fts USING fts5(
+			title, content='video_info', content_rowid='rowid', tokenize='trigram')`,
+		`CREATE TRIGGER IF NOT EXISTS videos_fts_ai AFTER INSERT ON video_info BEGIN
+			INSERT INTO videos_fts(rowid, title) VALUES (new.rowid, new.title);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS videos_fts_ad AFTER DELETE ON video_info BEGIN
+			INSERT INTO videos_fts(videos_fts, rowid, title) VALUES ('delete', old.rowid, old.title);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS videos_fts_au AFTER UPDATE OF title ON video_info BEGIN
+			INSERT INTO videos_fts(videos_fts, rowid, title) VALUES ('delete', old.rowid, old.title);
+			INSERT INTO videos_fts(rowid, title) VALUES (new.rowid, new.title);
+		END`,
+		`INSERT INTO videos_fts(videos_fts) VALUES ('rebuild')`,
+	)(tx)
+}
+
+// SearchQuery 解析后的搜索表达式
+type SearchQuery struct {
+	root  *searchNode
+	terms []string // 未被排除的检索词，用于排序与高亮
+}
+
+const (
+	nodeTerm = iota
+	nodeAnd
+	nodeOr
+	nodeNot
+)
+
+type searchNode struct {
+	op       int
+	term     string
+	children []*searchNode
+}
+
+const (
+	tokTerm = iota
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+type searchToken struct {
+	kind int
+	text string
+}
+
+// ParseSearchQuery 解析搜索表达式，至少需要一个未被排除的检索词
+func ParseSearchQuery(s string) (*SearchQuery, error) {
+	toks, err := tokenizeSearch(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(toks) == 0 {
+		return nil, fmt.Errorf("%w: 搜索词为空", ErrInvalidSearch)
+	}
+
+	p := &searchParser{toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("%w: 多余的右括号", ErrInvalidSearch)
+	}
+
+	q := &SearchQuery{root: root}
+	count := 0
+	root.walk(false, func(n *searchNode, negated bool) {
+		count++
+		if !negated {
+			q.terms = append(q.terms, n.term)
+		}
+	})
+	if count > maxSearchTerms {
+		return nil, fmt.Errorf("%w: 检索词不能超过 %d 个", ErrInvalidSearch, maxSearchTerms)
+	}
+	if len(q.terms) == 0 {
+		return nil, fmt.Errorf("%w: 至少需要一个不被排除的检索词", ErrInvalidSearch)
+	}
+	return q, nil
+}
+
+// literalSearch 把整个字符串作为一个子串检索词
+func literalSearch(s string) *SearchQuery {
+	s = strings.TrimSpace(s)
+	return &SearchQuery{root: &searchNode{op: nodeTerm, term: s}, terms: []string{s}}
+}
+
+// keywordCondition 把列表页的关键词转换为 SQL 条件，无法解析为表达式时按整体子串匹配
+func keywordCondition(keyword, col, rowid, fts string) (string, []interface{}) {
+	q, err := ParseSearchQuery(keyword)
+	if err != nil {
+		q = literalSearch(keyword)
+	}
+	return q.where(col, rowid, fts)
+}
+
+func tokenizeSearch(s string) ([]searchToken, error) {
+	var toks []searchToken
+	rs := []rune(s)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			toks = append(toks, searchToken{kind: tokLParen})
+			i++
+		case r == ')':
+			toks = append(toks, searchToken{kind: tokRParen})
+			i++
+		case r == '"':
+			j := i + 1
+			for j < len(rs) && rs[j] != '"' {
+				j++
+			}
+			if j == len(rs) {
+				return nil, fmt.Errorf("%w: 引号未闭合", ErrInvalidSearch)
+			}
+			if phrase := strings.TrimSpace(string(rs[i+1 : j])); phrase != "" {
+				toks = append(toks, searchToken{kind: tokTerm, text: phrase})
+			}
+			i = j + 1
+		case r == '-' && i+1 < len(rs) && !unicode.IsSpace(rs[i+1]):
+			toks = append(toks, searchToken{kind: tokNot})
+			i++
+		default:
+			j := i
+			for j < len(rs) && !unicode.IsSpace(rs[j]) && rs[j] != '"' && rs[j] != '(' && rs[j] != ')' {
+				j++
+			}
+			word := string(rs[i:j])
+			switch word {
+			case "AND":
+				toks = append(toks, searchToken{kind: tokAnd})
+			case "OR":
+				toks = append(toks, searchToken{kind: tokOr})
+			case "NOT":
+				toks = append(toks, searchToken{kind: tokNot})
+			default:
+				toks = append(toks, searchToken{kind: tokTerm, text: word})
+			}
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+type searchParser struct {
+	toks []searchToken
+	pos  int
+}
+
+func (p *searchParser) peek() *searchToken {
+	if p.pos >= len(p.toks) {
+		return nil
+	}
+	return &p.toks[p.pos]
+}
+
+func (p *searchParser) parseOr() (*searchNode, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	children := []*searchNode{first}
+	for t := p.peek(); t != nil && t.kind == tokOr; t = p.peek() {
+		p.pos++
+		next, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, next)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return &searchNode{op: nodeOr, children: children}, nil
+}
+
+// parseAnd 相邻的词之间省略 AND
+func (p *searchParser) parseAnd() (*searchNode, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	children := []*searchNode{first}
+	for t := p.peek(); t != nil && t.kind != tokOr && t.kind != tokRParen; t = p.peek() {
+		if t.kind == tokAnd {
+			p.pos++
+		}
+		next, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, next)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return &searchNode{op: nodeAnd, children: children}, nil
+}
+
+func (p *searchParser) parseUnary() (*searchNode, error) {
+	if t := p.peek(); t != nil && t.kind == tokNot {
+		p.pos++
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &searchNode{op: nodeNot, children: []*searchNode{child}}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *searchParser) parsePrimary() (*searchNode, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, fmt.Errorf("%w: 缺少检索词", ErrInvalidSearch)
+	}
+	p.pos++
+	switch t.kind {
+	case tokTerm:
+		return &searchNode{op: nodeTerm, term: t.text}, nil
+	case tokLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.peek(); t == nil || t.kind != tokRParen {
+			return nil, fmt.Errorf("%w: 括号未闭合", ErrInvalidSearch)
+		}
+		p.pos++
+		return node, nil
+	default:
+		return nil, fmt.Errorf("%w: 运算符位置错误", ErrInvalidSearch)
+	}
+}
+
+// walk 遍历全部检索词，negated 表示该词是否被奇数个 NOT 排除
+func (n *searchNode) walk(negated bool, fn func(n *searchNode, negated bool)) {
+	switch n.op {
+	case nodeTerm:
+		fn(n, negated)
+	case nodeNot:
+		n.children[0].walk(!negated, fn)
+	default:
+		for _, c := range n.children {
+			c.walk(negated, fn)
+		}
+	}
+}
+
+// where 生成匹配 col 列的 SQL 条件；不少于 3 个字的词通过 fts 全文索引表按 rowid 匹配
+func (q *SearchQuery) where(col, rowid, fts string) (string, []interface{}) {
+	var args []interface{}
+	cond := q.root.sql(col, rowid, fts, &args)
+	return cond, args
+}
+
+func (n *searchNode) sql(col, rowid, fts string, args *[]interface{}) string {
+	switch n.op {
+	case nodeTerm:
+		if utf8.RuneCountInString(n.term) >= minIndexedRunes {
+			*args = append(*args, ftsPhrase(n.term))
+			return fmt.Sprintf("%s IN (SELECT rowid FROM %s WHERE %s MATCH ?)", rowid, fts, fts)
+		}
+		*args = append(*args, "%"+escapeLike(n.term)+"%")
+		return col + ` LIKE ? ESCAPE '\'`
+	case nodeNot:
+		return "NOT (" + n.children[0].sql(col, rowid, fts, args) + ")"
+	default:
+		sep := " AND "
+		if n.op == nodeOr {
+			sep = " OR "
+		}
+		parts := make([]string, 0, len(n.children))
+		for _, c := range n.children {
+			parts = append(parts, c.sql(col, rowid, fts, args))
+		}
+		return "(" + strings.Join(parts, sep) + ")"
+	}
+}
+
+// rankJoin 返回按 bm25 相关度排序的 JOIN 子句、ORDER BY 前缀与参数
+// 参与排序的是可使用索引的检索词；没有这样的词时三者均为空，由调用方的排序列决定顺序
+func (q *SearchQuery) rankJoin(fts, rowid string) (join, order string, args []interface{}) {
+	var phrases []string
+	for _, t := range q.terms {
+		if utf8.RuneCountInString(t) >= minIndexedRunes {
+			phrases = append(phrases, ftsPhrase(t))
+		}
+	}
+	if len(phrases) == 0 {
+		return "", "", nil
+	}
+	join = fmt.Sprintf("LEFT JOIN (SELECT rowid AS id, bm25(%s) AS score FROM %s WHERE %s MATCH ?) r ON r.id = %s ",
+		fts, fts, fts, rowid)
+	return join, "r.score IS NULL, r.score, ", []interface{}{strings.Join(phrases, " OR ")}
+}
+
+// ftsPhrase 把检索词转义为 FTS5 短语
+func ftsPhrase(term string) string {
+	return `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+}
+
+// escapeLike 转义 LIKE 通配符，配合 ESCAPE '\' 使用
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Highlight 对文本做 HTML 转义，并用 <mark> 标出全部检索词（不区分大小写）
+func (q *SearchQuery) Highlight(text string) string {
+	rs := []rune(text)
+	return q.markRunes(rs, q.matchMask(rs))
+}
+
+// Snippet 截取第一个命中位置附近不超过 width 个字的片段并高亮，截断处以省略号表示
+func (q *SearchQuery) Snippet(text string, width int) string {
+	rs := []rune(text)
+	mask := q.matchMask(rs)
+	if len(rs) <= width {
+		return q.markRunes(rs, mask)
+	}
+
+	first := 0
+	for i, m := range mask {
+		if m {
+			first = i
+			break
+		}
+	}
+	start := max(first-width/4, 0)
+	end := min(start+width, len(rs))
+	start = max(end-width, 0)
+
+	out := q.markRunes(rs[start:end], mask[start:end])
+	if start > 0 {
+		out = "…" + out
+	}
+	if end < len(rs) {
+		out += "…"
+	}
+	return out
+}
+
+// matchMask 标记文本中属于检索词的字符
+func (q *SearchQuery) matchMask(rs []rune) []bool {
+	lower := make([]rune, len(rs))
+	for i, r := range rs {
+		lower[i] = unicode.ToLower(r)
+	}
+	mask := make([]bool, len(rs))
+	for _, term := range q.terms {
+		t := []rune(strings.ToLower(term))
+		if len(t) == 0 {
+			continue
+		}
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) == string(t) {
+				for j := i; j < i+len(t); j++ {
+					mask[j] = true
+				}
+			}
+		}
+	}
+	return mask
+}
+
+func (q *SearchQuery) markRunes(rs []rune, mask []bool) string {
+	var b strings.Builder
+	open := false
+	for i, r := range rs {
+		if mask[i] != open {
+			if mask[i] {
+				b.WriteString("<mark>")
+			} else {
+				b.WriteString("</mark>")
+			}
+			open = mask[i]
+		}
+		b.WriteString(html.EscapeString(string(r)))
+	}
+	if open {
+		b.WriteString("</mark>")
+	}
+	return b.String()
+}
+
+// CommentHit 评论搜索结果
+type CommentHit struct {
+	Comment
+	VideoTitle string `json:"video_title"`
+	Snippet    string `json:"snippet"` // 命中位置附近的内容，已做 HTML 转义，检索词以 <mark> 标出
+}
+
+// VideoHit 视频标题搜索结果
+type VideoHit struct {
+	Video
+	TitleHighlight string `json:"title_highlight"` // 已做 HTML 转义，检索词以 <mark> 标出
+}
+
+// snippetWidth 评论搜索结果片段的最大字数
+const snippetWidth = 80
+
+// SearchComments 在全部视频的评论中搜索
+// 有可使用全文索引的检索词时按 bm25 相关度排序，否则按点赞数与时间排序
+func SearchComments(q *SearchQuery, page, pageSize int) ([]CommentHit, int, error) {
+	cond, args := q.where("c.content", "c.rowid", "comments_fts")
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM bilibili_comments c WHERE "+cond, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("统计搜索结果失败: %w", err)
+	}
+	if total == 0 {
+		return []CommentHit{}, 0, nil
+	}
+
+	join, order, queryArgs := q.rankJoin("comments_fts", "c.rowid")
+	query := "SELECT " + commentSelect("c") + ", IFNULL(v.title, '') FROM bilibili_comments c " +
+		"LEFT JOIN video_info v ON v.bvid = c.bvid " + join +
+		"WHERE " + cond + " ORDER BY " + order + "c.like_count DESC, c.ctime DESC LIMIT ? OFFSET ?"
+	queryArgs = append(queryArgs, args...)
+	queryArgs = append(queryArgs, pageSize, (page-1)*pageSize)
+
+	rows, err := db.Query(query, queryArgs...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("搜索评论失败: %w", err)
+	}
+	defer rows.Close()
+
+	hits := []CommentHit{}
+	for rows.Next() {
+		var hit CommentHit
+		var err error
+		hit.Comment, err = scanComment(withTrailing(rows, &hit.VideoTitle))
+		if err != nil {
+			return nil, 0, fmt.Errorf("扫描搜索结果失败: %w", err)
+		}
+		hit.Snippet = q.Snippet(hit.Content, snippetWidth)
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("遍历搜索结果失败: %w", err)
+	}
+	return hits, total, nil
+}
+
+// SearchVideos 按标题搜索视频，最多返回 limit 个，按相关度排序
+func SearchVideos(q *SearchQuery, limit int) ([]VideoHit, error) {
+	cond, args := q.where("v.title", "v.rowid", "videos_fts")
+	join, order, queryArgs := q.rankJoin("videos_fts", "v.rowid")
+	queryArgs = append(queryArgs, args...)
+	rows, err := db.Query(`
+		SELECT v.bvid, v.title, v.cover, IFNULL(s.comment_count, 0)
+		FROM video_info v
+		LEFT JOIN comment_stats s ON v.bvid = s.bvid
+		`+join+`
+		WHERE `+cond+`
+		ORDER BY `+order+`v.created_at DESC
+		LIMIT ?`, append(queryArgs, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("搜索视频失败: %w", err)
+	}
+	defer rows.Close()
+
+	hits := []VideoHit{}
+	for rows.Next() {
+		var hit VideoHit
+		if err := rows.Scan(&hit.BVid, &hit.Title, &hit.Cover, &hit.CommentCount); err != nil {
+			return nil, fmt.Errorf("扫描视频行失败: %w", err)
+		}
+		hit.Cover = strings.ReplaceAll(hit.Cover, `\`, `/`)
+		hit.TitleHighlight = q.Highlight(hit.Title)
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历视频行失败: %w", err)
+	}
+	return hits, nil
+}
+
+// trailingScanner 在 scanComment 读取的评论列之后追加读取额外的列
+type trailingScanner struct {
+	rows  rowScanner
+	extra []interface{}
+}
+
+func withTrailing(rows rowScanner, extra ...interface{}) rowScanner {
+	return trailingScanner{rows: rows, extra: extra}
+}
+
+func (s trailingScanner) Scan(dest ...interface{}) error {
+	return s.rows.Scan(append(dest, s.extra...)...)
+}
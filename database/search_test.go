@@ -0,0 +1,71 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// saveTestComments 保存一个视频的主评论，内容依次为 contents
+func saveTestComments(t *testing.T, bvid string, contents ...string) {
+	t.Helper()
+	if err := SaveVideo(&Video{BVid: bvid, Title: "测试视频"}); err != nil {
+		t.Fatal(err)
+	}
+	comments := make([]*Comment, len(contents))
+	for i, content := range contents {
+		rpid := int64(1000 + i)
+		comments[i] = &Comment{
+			UniqueID: fmt.Sprintf("%s_%d", bvid, rpid),
+			BVid:     bvid,
+			Rpid:     rpid,
+			Content:  content,
+			Mid:      int64(i + 1),
+			Parent:   "0",
+			Ctime:    time.Unix(1700000000+int64(i), 0),
+			Upname:   fmt.Sprintf("用户%d", i),
+			LastSeen: time.Now(),
+		}
+	}
+	if err := BatchSaveComments(comments); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSearchComments(t *testing.T) {
+	openTestDB(t)
+	saveTestComments(t, "BV1xx411c7mD",
+		"看完学到了很多", "学到了，感谢UP", "前排吃瓜", "吃瓜群众路过", "和搜索无关的评论")
+
+	for _, tc := range []struct {
+		query string
+		want  int
+	}{
+		{"学到了", 2},     // trigram 索引
+		{"吃瓜", 2},      // 少于三个字的词回退为子串匹配
+		{"学到了 -感谢", 1}, // 排除词
+		{"学到了 OR 吃瓜", 4},
+		{`"和搜索无关"`, 1},
+	} {
+		q, err := ParseSearchQuery(tc.query)
+		if err != nil {
+			t.Errorf("解析搜索词 %q 失败: %v", tc.query, err)
+			continue
+		}
+		hits, total, err := SearchComments(q, 1, 5)
+		if err != nil {
+			t.Errorf("搜索 %q: %v", tc.query, err)
+			continue
+		}
+		if total != tc.want || len(hits) != tc.want {
+			t.Errorf("搜索 %q 命中 %d 条（本页 %d 条），期望 %d 条", tc.query, total, len(hits), tc.want)
+			continue
+		}
+		for _, h := range hits {
+			if !strings.Contains(h.Snippet, "<mark>") {
+				t.Errorf("搜索 %q 的摘要未标记关键词: %q", tc.query, h.Snippet)
+			}
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GetCommentByID 按 unique_id 获取评论并填充回复数，不存在时返回 nil
+func GetCommentByID(uniqueID string) (*Comment, error) {
+	row := db.QueryRow(`SELECT `+commentSelect("")+` FROM bilibili_comments WHERE unique_id = ?`, uniqueID)
+	c, err := scanComment(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询评论失败: %w", err)
+	}
+	comments := []Comment{c}
+	if err := fillReplyStats(comments); err != nil {
+		return nil, err
+	}
+	return &comments[0], nil
+}
+
+// GetCommentByRpid 按 rpid 获取评论，不存在时返回 nil
+func GetCommentByRpid(rpid int64) (*Comment, error) {
+	var uniqueID string
+	err := db.QueryRow(`SELECT unique_id FROM bilibili_comments WHERE rpid = ? LIMIT 1`, rpid).Scan(&uniqueID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询评论失败: %w", err)
+	}
+	return GetCommentByID(uniqueID)
+}
+
+// GetThreadComments 获取评论下的全部回复，按层从 comment_relations 展开；
+// 主评论再按 root 列补上关系缺失（如中间的回复未入库）的楼层回复。
+// 最多返回 limit 条，truncated 表示还有未返回的回复
+func GetThreadComments(target *Comment, limit int) (replies []Comment, truncated bool, err error) {
+	seen := map[string]bool{target.UniqueID: true}
+	add := func(rows *sql.Rows) error {
+		defer rows.Close()
+		for rows.Next() {
+			c, err := scanComment(rows)
+			if err != nil {
+				return fmt.Errorf("扫描回复行失败: %w", err)
+			}
+			if !seen[c.UniqueID] {
+				seen[c.UniqueID] = true
+				replies = append(replies, c)
+			}
+		}
+		return rows.Err()
+	}
+
+	frontier := []string{target.UniqueID}
+	for len(frontier) > 0 && len(replies) <= limit {
+		args := make([]interface{}, 0, len(frontier)+1)
+		for _, id := range frontier {
+			args = append(args, id)
+		}
+		args = append(args, limit+1-len(replies))
+		rows, err := db.Query(`
+			SELECT `+commentSelect("c")+`
+			FROM comment_relations r
+			JOIN bilibili_comments c ON c.unique_id = r.child_id
+			WHERE r.parent_id IN (`+placeholders(len(frontier))+`)
+			ORDER BY c.ctime, c.unique_id
+			LIMIT ?`, args...)
+		if err != nil {
+			return nil, false, fmt.Errorf("查询回复失败: %w", err)
+		}
+		start := len(replies)
+		if err := add(rows); err != nil {
+			return nil, false, err
+		}
+		frontier = frontier[:0]
+		for _, c := range replies[start:] {
+			frontier = append(frontier, c.UniqueID)
+		}
+	}
+
+	if target.Parent == "0" && len(replies) <= limit {
+		rows, err := db.Query(`
+			SELECT `+commentSelect("")+`
+			FROM bilibili_comments
+			WHERE bvid = ? AND root = ? AND unique_id != ?
+			ORDER BY ctime, unique_id
+			LIMIT ?`, target.BVid, target.Rpid, target.UniqueID, limit+1+len(replies))
+		if err != nil {
+			return nil, false, fmt.Errorf("查询楼层回复失败: %w", err)
+		}
+		if err := add(rows); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if len(replies) > limit {
+		replies, truncated = replies[:limit], true
+	}
+	if err := fillReplyStats(replies); err != nil {
+		return nil, false, err
+	}
+	return replies, truncated, nil
+}
+
+// GetDialogComments 获取楼层中同一对话的全部回复，按发布时间升序
+func GetDialogComments(bvid string, root, dialog int64) ([]Comment, error) {
+	rows, err := db.Query(`
+		SELECT `+commentSelect("")+`
+		FROM bilibili_comments
+		WHERE bvid = ? AND root = ? AND dialog = ?
+		ORDER BY ctime, unique_id`, bvid, root, dialog)
+	if err != nil {
+		return nil, fmt.Errorf("查询对话失败: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []Comment
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描回复行失败: %w", err)
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+// fillReplyStats 为评论填充已入库的直接回复数与最新回复时间
+func fillReplyStats(comments []Comment) error {
+	if len(comments) == 0 {
+		return nil
+	}
+	args := make([]interface{}, len(comments))
+	for i := range comments {
+		args[i] = comments[i].UniqueID
+	}
+	rows, err := db.Query(`
+		SELECT parent, COUNT(*), MAX(ctime)
+		FROM bilibili_comments
+		WHERE parent IN (`+placeholders(len(comments))+`)
+		GROUP BY parent`, args...)
+	if err != nil {
+		return fmt.Errorf("统计回复数失败: %w", err)
+	}
+	defer rows.Close()
+
+	type replyStats struct {
+		count  int
+		latest int64
+	}
+	stats := make(map[string]replyStats)
+	for rows.Next() {
+		var parent string
+		var s replyStats
+		if err := rows.Scan(&parent, &s.count, &s.latest); err != nil {
+			return fmt.Errorf("扫描回复统计失败: %w", err)
+		}
+		stats[parent] = s
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("遍历回复统计失败: %w", err)
+	}
+
+	for i := range comments {
+		s, ok := stats[comments[i].UniqueID]
+		if !ok {
+			continue
+		}
+		latest := time.Unix(s.latest, 0)
+		comments[i].ReplyCount = s.count
+		comments[i].LatestReplyAt = &latest
+	}
+	return nil
+}
+
+// placeholders 生成 n 个以逗号分隔的 ? 占位符
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
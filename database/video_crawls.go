@@ -0,0 +1,188 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// 视频评论爬取方式
+const (
+	VideoCrawlFull        = "full"
+	VideoCrawlIncremental = "incremental"
+	VideoCrawlResume      = "resume" // 从断点继续上次未完成的完整爬取
+)
+
+// 视频评论爬取状态
+const (
+	VideoCrawlRunning     = "running"
+	VideoCrawlCompleted   = "completed"
+	VideoCrawlInterrupted = "interrupted" // 被取消、超时或服务重启，留有断点
+	VideoCrawlFailed      = "failed"
+)
+
+// VideoCrawl 一次写入数据库的视频评论爬取
+// 本次观察到的评论 last_seen 为 StartedAt；完整爬取确认爬完全部评论时，last_seen 早于 Since 的评论标记为已删除
+type VideoCrawl struct {
+	ID         int64      `json:"id"`
+	Bvid       string     `json:"bvid"`
+	Mode       string     `json:"mode"`
+	Status     string     `json:"status"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Since      *time.Time `json:"since,omitempty"` // 完整爬取（含断点续爬之前的部分）的开始时间，增量爬取为空
+	Comments   int        `json:"comments"`        // 本次观察到的评论数
+	Deleted    int        `json:"deleted"`         // 本次标记为已删除的评论数
+	Incomplete bool       `json:"incomplete"`      // 结束时无法确认已爬取全部评论（跳过页面或提前停止），不标记删除
+}
+
+const videoCrawlColumns = `id, bvid, mode, status, started_at, finished_at, since, comments, deleted, incomplete`
+
+// scanVideoCrawl 扫描一行爬取记录
+func scanVideoCrawl(scanner rowScanner) (*VideoCrawl, error) {
+	var c VideoCrawl
+	var startedAt int64
+	var finishedAt, since sql.NullInt64
+	if err := scanner.Scan(&c.ID, &c.Bvid, &c.Mode, &c.Status, &startedAt, &finishedAt, &since, &c.Comments, &c.Deleted, &c.Incomplete); err != nil {
+		return nil, err
+	}
+	c.StartedAt = time.Unix(startedAt, 0)
+	if finishedAt.Valid {
+		t := time.Unix(finishedAt.Int64, 0)
+		c.FinishedAt = &t
+	}
+	if since.Valid {
+		t := time.Unix(since.Int64, 0)
+		c.Since = &t
+	}
+	return &c, nil
+}
+
+// StartVideoCrawl 记录一次开始的爬取
+// 断点续爬沿用上次未完成的完整爬取的 Since，找不到时（如断点早于爬取记录）不标记删除
+func StartVideoCrawl(bvid, mode string) (*VideoCrawl, error) {
+	now := time.Unix(time.Now().Unix(), 0)
+	crawl := &VideoCrawl{Bvid: bvid, Mode: mode, Status: VideoCrawlRunning, StartedAt: now}
+	switch mode {
+	case VideoCrawlFull:
+		crawl.Since = &now
+	case VideoCrawlResume:
+		var since sql.NullInt64
+		err := db.QueryRow(`
+			SELECT since FROM video_crawls
+			WHERE bvid = ? AND mode IN (?, ?)
+			ORDER BY id DESC LIMIT 1`,
+			bvid, VideoCrawlFull, VideoCrawlResume,
+		).Scan(&since)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("查询上次爬取记录失败: %w", err)
+		}
+		if since.Valid {
+			t := time.Unix(since.Int64, 0)
+			crawl.Since = &t
+		}
+	}
+
+	var since interface{}
+	if crawl.Since != nil {
+		since = crawl.Since.Unix()
+	}
+	res, err := db.Exec(`
+		INSERT INTO video_crawls (bvid, mode, status, started_at, since)
+		VALUES (?, ?, ?, ?, ?)`,
+		bvid, mode, VideoCrawlRunning, now.Unix(), since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("创建爬取记录失败: %w", err)
+	}
+	if crawl.ID, err = res.LastInsertId(); err != nil {
+		return nil, fmt.Errorf("获取爬取记录ID失败: %w", err)
+	}
+	return crawl, nil
+}
+
+// FinishVideoCrawl 记录爬取的最终状态；完整爬取成功结束且确认爬完全部评论时（incomplete 为 false），
+// 在同一事务中把本轮完整爬取未观察到的评论标记为已删除
+func FinishVideoCrawl(crawl *VideoCrawl, status string, comments int, incomplete bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	deleted := 0
+	if status == VideoCrawlCompleted && crawl.Since != nil && !incomplete {
+		res, err := tx.Exec(`
+			UPDATE bilibili_comments SET deleted_at = ?
+			WHERE bvid = ? AND deleted_at IS NULL AND last_seen < ?`,
+			crawl.Since.Unix(), crawl.Bvid, crawl.Since.Unix(),
+		)
+		if err != nil {
+			return fmt.Errorf("标记已删除评论失败: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("标记已删除评论失败: %w", err)
+		}
+		deleted = int(n)
+	}
+
+	now := time.Unix(time.Now().Unix(), 0)
+	if _, err := tx.Exec(`
+		UPDATE video_crawls SET status = ?, finished_at = ?, comments = ?, deleted = ?, incomplete = ?
+		WHERE id = ?`,
+		status, now.Unix(), comments, deleted, incomplete, crawl.ID,
+	); err != nil {
+		return fmt.Errorf("更新爬取记录失败: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	crawl.Status, crawl.FinishedAt, crawl.Comments, crawl.Deleted, crawl.Incomplete = status, &now, comments, deleted, incomplete
+	return nil
+}
+
+// InterruptRunningVideoCrawls 将上次进程退出时仍在运行的爬取标记为中断
+func InterruptRunningVideoCrawls() (int64, error) {
+	res, err := db.Exec(`UPDATE video_crawls SET status = ?, finished_at = ? WHERE status = ?`,
+		VideoCrawlInterrupted, time.Now().Unix(), VideoCrawlRunning)
+	if err != nil {
+		return 0, fmt.Errorf("重置中断的爬取记录失败: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// GetVideoCrawls 获取视频的爬取记录，按时间倒序
+func GetVideoCrawls(bvid string) ([]VideoCrawl, error) {
+	rows, err := db.Query(`SELECT `+videoCrawlColumns+` FROM video_crawls WHERE bvid = ? ORDER BY id DESC`, bvid)
+	if err != nil {
+		return nil, fmt.Errorf("查询爬取记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	crawls := []VideoCrawl{}
+	for rows.Next() {
+		c, err := scanVideoCrawl(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描爬取记录失败: %w", err)
+		}
+		crawls = append(crawls, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历爬取记录失败: %w", err)
+	}
+	return crawls, nil
+}
+
+// GetVideoCrawl 按ID获取视频的爬取记录，不存在时返回 nil
+func GetVideoCrawl(bvid string, id int64) (*VideoCrawl, error) {
+	row := db.QueryRow(`SELECT `+videoCrawlColumns+` FROM video_crawls WHERE bvid = ? AND id = ?`, bvid, id)
+	c, err := scanVideoCrawl(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询爬取记录失败: %w", err)
+	}
+	return c, nil
+}
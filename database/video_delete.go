@@ -0,0 +1,77 @@
+package database
+
+import (
+	"fmt"
+)
+
+// VideoDeletion 删除视频时各表删除（或试运行时将要删除）的行数
+type VideoDeletion struct {
+	Video       bool `json:"video"`       // video_info 中是否有该视频
+	Comments    int  `json:"comments"`    // bilibili_comments
+	Relations   int  `json:"relations"`   // comment_relations，随评论级联删除
+	Stats       int  `json:"stats"`       // comment_stats，随视频级联删除
+	Snapshots   int  `json:"snapshots"`   // comment_snapshots，随评论级联删除
+	Crawls      int  `json:"crawls"`      // video_crawls，随视频级联删除
+	RawPages    int  `json:"raw_pages"`   // raw_pages
+	Checkpoints int  `json:"checkpoints"` // crawl_checkpoints
+}
+
+// Empty 数据库中没有该视频的任何数据
+func (d *VideoDeletion) Empty() bool {
+	return !d.Video && d.Comments == 0 && d.Relations == 0 && d.Stats == 0 && d.Snapshots == 0 && d.Crawls == 0 &&
+		d.RawPages == 0 && d.Checkpoints == 0
+}
+
+// DeleteVideo 在一个事务中删除视频及其全部评论数据：
+// 删除评论时 comment_relations、comment_snapshots 级联删除，删除视频时 comment_stats、video_crawls 级联删除，全文索引由触发器同步；
+// 原始响应与爬取断点没有外键，单独删除。dryRun 时只统计行数并回滚
+func DeleteVideo(bvid string, dryRun bool) (*VideoDeletion, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	d := &VideoDeletion{}
+	var videos int
+	counts := []struct {
+		dest  *int
+		query string
+	}{
+		{&videos, `SELECT COUNT(*) FROM video_info WHERE bvid = ?`},
+		{&d.Comments, `SELECT COUNT(*) FROM bilibili_comments WHERE bvid = ?`},
+		{&d.Relations, `
+			SELECT COUNT(*) FROM comment_relations
+			WHERE parent_id IN (SELECT unique_id FROM bilibili_comments WHERE bvid = ?1)
+			   OR child_id IN (SELECT unique_id FROM bilibili_comments WHERE bvid = ?1)`},
+		{&d.Stats, `SELECT COUNT(*) FROM comment_stats WHERE bvid = ?`},
+		{&d.Snapshots, `SELECT COUNT(*) FROM comment_snapshots WHERE unique_id IN (SELECT unique_id FROM bilibili_comments WHERE bvid = ?)`},
+		{&d.Crawls, `SELECT COUNT(*) FROM video_crawls WHERE bvid = ?`},
+		{&d.RawPages, `SELECT COUNT(*) FROM raw_pages WHERE bvid = ?`},
+		{&d.Checkpoints, `SELECT COUNT(*) FROM crawl_checkpoints WHERE bvid = ?`},
+	}
+	for _, c := range counts {
+		if err := tx.QueryRow(c.query, bvid).Scan(c.dest); err != nil {
+			return nil, fmt.Errorf("统计待删除数据失败: %w", err)
+		}
+	}
+	d.Video = videos > 0
+	if dryRun {
+		return d, nil
+	}
+
+	for _, stmt := range []string{
+		`DELETE FROM bilibili_comments WHERE bvid = ?`,
+		`DELETE FROM video_info WHERE bvid = ?`,
+		`DELETE FROM raw_pages WHERE bvid = ?`,
+		`DELETE FROM crawl_checkpoints WHERE bvid = ?`,
+	} {
+		if _, err := tx.Exec(stmt, bvid); err != nil {
+			return nil, fmt.Errorf("删除视频数据失败: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return d, nil
+}
@@ -63,6 +63,13 @@ export async function fetchComments(API_BASE_URL, commentsPerPage, COMMENT_LOADI
             COMMENT_COUNT.textContent = response.total;
             const commentsWithReplies = await Promise.all(
                 response.comments.map(async comment => {
+                    // 列表已返回回复数，没有回复的评论不再请求回复接口
+                    if (!comment.reply_count) {
+                        comment.loadedReplies = [];
+                        comment.totalReplies = 0;
+                        comment.currentReplyPage = 1;
+                        return comment;
+                    }
                     try {
                         const replyData = await fetchCommentReplies(API_BASE_URL, comment.unique_id, 1, fetchData, 5);
                         if (replyData) {
@@ -107,12 +107,50 @@ document.addEventListener('DOMContentLoaded', () => {
     // 入口初始化
     fetchVideos(API_BASE_URL, perPage, VIDEO_LOADING, VIDEO_LIST, VIDEO_ERROR, VIDEO_PAGINATION, fetchData, (videos) => renderVideoList(videos, VIDEO_LIST, handleVideoClick), renderPagination, showLoading, showError, hideLoading, currentVideoPage, currentSearchTerm, setTotalVideoPages, setCurrentVideoPage);
 
+    // 深链接：/?bvid=...&comment=... 直接打开视频并定位到评论
+    const urlParams = new URLSearchParams(window.location.search);
+    if (urlParams.get('bvid') || urlParams.get('comment')) {
+        openDeepLink(urlParams.get('bvid'), urlParams.get('comment'));
+    }
+
     // 处理视频点击
     function handleVideoClick(event) {
         const card = event.currentTarget;
-        selectedBvid = card.dataset.bvid;
-        const title = card.dataset.title;
-        const cover = card.dataset.cover || '';
+        openVideo(card.dataset.bvid, card.dataset.title, card.dataset.cover || '', 1);
+    }
+
+    // 打开深链接：评论所在页由 /api/comment/:id 给出，加载后高亮该评论
+    async function openDeepLink(bvid, commentId) {
+        try {
+            let link = null;
+            if (commentId) {
+                link = await fetchData(`${API_BASE_URL}/api/comment/${encodeURIComponent(commentId)}?pageSize=${commentsPerPage}&context=0`);
+                bvid = link.comment.bvid;
+            }
+            const video = await fetchData(`${API_BASE_URL}/api/video/${encodeURIComponent(bvid)}`);
+            await openVideo(video.bvid, video.title, video.cover || '', link ? link.video_page || 1 : 1);
+            if (link) {
+                highlightComment(link);
+            }
+        } catch (error) {
+            console.error('打开链接失败:', error);
+        }
+    }
+
+    // 高亮评论；回复只有在已加载的回复中时才高亮回复本身，否则高亮所属主评论
+    function highlightComment(link) {
+        const rootId = link.root ? link.root.unique_id : link.comment.unique_id;
+        const commentEl = document.querySelector(`.comment[data-comment-id="${CSS.escape(rootId)}"]`);
+        if (!commentEl) return;
+        const replyEl = link.root ? commentEl.querySelector(`.reply[data-reply-id="${CSS.escape(link.comment.unique_id)}"]`) : null;
+        const target = replyEl || commentEl;
+        target.classList.add('highlighted');
+        target.scrollIntoView({ behavior: 'smooth', block: 'center' });
+    }
+
+    // 打开视频评论页
+    function openVideo(bvid, title, cover, page) {
+        selectedBvid = bvid;
         VIDEO_LIST_VIEW.style.display = 'none';
         COMMENT_VIEW.style.display = 'block';
         window.scrollTo(0, 0);
@@ -124,11 +162,11 @@ document.addEventListener('DOMContentLoaded', () => {
             this.src = `/proxy_image?url=${encodeURIComponent(cover)}`;
         };
         COMMENT_COUNT.textContent = '...';
-        currentCommentPage = 1;
+        currentCommentPage = page;
         currentCommentSearchTerm = '';
         COMMENT_SEARCH_INPUT.value = '';
         loadedReplies.clear();
-        fetchComments(API_BASE_URL, commentsPerPage, COMMENT_LOADING, COMMENT_LIST, COMMENT_ERROR, COMMENT_PAGINATION, fetchData, fetchCommentReplies, (comments) => renderCommentList(comments, COMMENT_LIST, (comment) => createCommentElement(
+        return fetchComments(API_BASE_URL, commentsPerPage, COMMENT_LOADING, COMMENT_LIST, COMMENT_ERROR, COMMENT_PAGINATION, fetchData, fetchCommentReplies, (comments) => renderCommentList(comments, COMMENT_LIST, (comment) => createCommentElement(
             comment,
             (pictures) => renderPictures(pictures, processImageSrc, selectedBvid),
             (replies) => renderReplies(replies, renderPictures, escapeHtml, selectedBvid),
@@ -175,7 +213,7 @@ document.addEventListener('DOMContentLoaded', () => {
     function createReplyElement(reply, renderPictures, escapeHtml) {
         const picturesHTML = renderPictures(reply.pictures, processImageSrc, selectedBvid);
         return `
-            <div class="reply">
+            <div class="reply" data-reply-id="${escapeHtml(reply.unique_id)}">
                 <div class="comment-header">
                     <span class="comment-user">${escapeHtml(reply.upname)}</span>
                     <span class="comment-level">Lv.${reply.level}</span>
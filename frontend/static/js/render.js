@@ -93,7 +93,7 @@ export function renderReplies(replies, renderPictures, escapeHtml, bvid) {
     return replies.map(reply => {
         const picturesHTML = renderPictures(reply.pictures, processImageSrc, bvid);
         return `
-            <div class="reply">
+            <div class="reply" data-reply-id="${escapeHtml(reply.unique_id)}">
                 <div class="comment-header">
                     <span class="comment-user">${escapeHtml(reply.upname)}</span>
                     <span class="comment-level">Lv.${reply.level}</span>
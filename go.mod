@@ -6,11 +6,10 @@ replace gopkg.in/natefinch/lumberjack.v2 => github.com/natefinch/lumberjack v2.0
 
 require (
 	github.com/gin-gonic/gin v1.10.1
-	github.com/go-resty/resty/v2 v2.16.5
+	github.com/klauspost/compress v1.18.0
 	github.com/sirupsen/logrus v1.9.3
 	github.com/spf13/cobra v1.9.1
 	github.com/spf13/viper v1.20.1
-	github.com/tidwall/gjson v1.18.0
 	gopkg.in/natefinch/lumberjack.v2 v2.0.0-00010101000000-000000000000
 	modernc.org/sqlite v1.38.0
 )
@@ -47,8 +46,6 @@ require (
 	github.com/spf13/cast v1.7.1 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
 	github.com/subosito/gotenv v1.6.0 // indirect
-	github.com/tidwall/match v1.1.1 // indirect
-	github.com/tidwall/pretty v1.2.0 // indirect
 	github.com/twitchyliquid64/golang-asm v0.15.1 // indirect
 	github.com/ugorji/go/codec v1.2.12 // indirect
 	go.uber.org/atomic v1.9.0 // indirect
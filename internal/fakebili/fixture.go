@@ -0,0 +1,143 @@
+package fakebili
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+//go:embed fixtures/default.json
+var fixtureFS embed.FS
+
+// Fixture 模拟服务器的数据：WBI 密钥、UP主、视频与评论树
+type Fixture struct {
+	Wbi struct {
+		ImgKey string `json:"img_key"`
+		SubKey string `json:"sub_key"`
+	} `json:"wbi"`
+	Users  []User  `json:"users"`
+	Videos []Video `json:"videos"`
+}
+
+// User UP主
+type User struct {
+	Mid  int64  `json:"mid"`
+	Name string `json:"name"`
+}
+
+// Video 视频及其评论
+type Video struct {
+	Bvid     string    `json:"bvid"`
+	Aid      int64     `json:"aid"`
+	Title    string    `json:"title"`
+	Owner    int64     `json:"owner"`
+	Pubdate  int64     `json:"pubdate"`
+	Comments []Comment `json:"comments"`
+}
+
+// Comment 一条评论；主评论的 Replies 为楼中楼回复
+// 回复的 Parent 为被回复的评论 rpid，为 0 时表示直接回复主评论
+type Comment struct {
+	Rpid     int64     `json:"rpid"`
+	Mid      int64     `json:"mid"`
+	Uname    string    `json:"uname"`
+	Message  string    `json:"message"`
+	Ctime    int64     `json:"ctime"`
+	Like     int       `json:"like"`
+	Level    int       `json:"level"`
+	Location string    `json:"location,omitempty"`
+	Top      bool      `json:"top,omitempty"`      // 置顶评论，只在第一页的 top_replies 中返回
+	Pictures []string  `json:"pictures,omitempty"` // 图片路径，相对于模拟服务器地址
+	Parent   int64     `json:"parent,omitempty"`
+	Replies  []Comment `json:"replies,omitempty"`
+}
+
+// DefaultFixture 返回内置的数据：
+// 一个 UP 主的三个视频，分别有 3 页主评论（含置顶、图片和多页子评论）、少量评论和没有评论
+func DefaultFixture() *Fixture {
+	data, err := fixtureFS.ReadFile("fixtures/default.json")
+	if err != nil {
+		panic(err)
+	}
+	fx, err := parseFixture(data)
+	if err != nil {
+		panic(err)
+	}
+	return fx
+}
+
+// LoadFixture 从 JSON 文件加载数据
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取模拟数据失败: %w", err)
+	}
+	return parseFixture(data)
+}
+
+func parseFixture(data []byte) (*Fixture, error) {
+	var fx Fixture
+	if err := json.Unmarshal(data, &fx); err != nil {
+		return nil, fmt.Errorf("解析模拟数据失败: %w", err)
+	}
+	for i := range fx.Videos {
+		for j := range fx.Videos[i].Comments {
+			replies := fx.Videos[i].Comments[j].Replies
+			sort.SliceStable(replies, func(a, b int) bool { return replies[a].Ctime < replies[b].Ctime })
+		}
+	}
+	return &fx, nil
+}
+
+// Video 按 BV 号查找视频
+func (fx *Fixture) Video(bvid string) *Video {
+	for i := range fx.Videos {
+		if fx.Videos[i].Bvid == bvid {
+			return &fx.Videos[i]
+		}
+	}
+	return nil
+}
+
+// videoByAid 按 avid 查找视频
+func (fx *Fixture) videoByAid(aid int64) *Video {
+	for i := range fx.Videos {
+		if fx.Videos[i].Aid == aid {
+			return &fx.Videos[i]
+		}
+	}
+	return nil
+}
+
+// user 按 mid 查找 UP 主
+func (fx *Fixture) user(mid int64) *User {
+	for i := range fx.Users {
+		if fx.Users[i].Mid == mid {
+			return &fx.Users[i]
+		}
+	}
+	return nil
+}
+
+// CommentCount 视频的评论总数（主评论与回复）
+func (v *Video) CommentCount() int {
+	n := len(v.Comments)
+	for _, c := range v.Comments {
+		n += len(c.Replies)
+	}
+	return n
+}
+
+// VideosOf 返回 UP 主的视频，按发布时间倒序
+func (fx *Fixture) VideosOf(mid int64) []Video {
+	var out []Video
+	for _, v := range fx.Videos {
+		if v.Owner == mid {
+			out = append(out, v)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Pubdate > out[j].Pubdate })
+	return out
+}
@@ -0,0 +1,604 @@
+// Package fakebili 提供离线的 B 站接口模拟服务器，按 Fixture 返回确定的评论树、分页游标、
+// 子评论分页、视频信息、UP主投稿列表与图片，并可注入风控错误，用于在没有网络时端到端地验证爬虫
+package fakebili
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bilibili-comments-viewer-go/crawler/bili_info/util"
+	"bilibili-comments-viewer-go/crawler/biliapi"
+)
+
+// 接口路径
+const (
+	PathReplyMain  = "/x/v2/reply/wbi/main"
+	PathReplyReply = "/x/v2/reply/reply"
+	PathReplyCount = "/x/v2/reply/count"
+	PathArcSearch  = "/x/space/wbi/arc/search"
+	PathView       = "/x/web-interface/view"
+	PathNav        = "/x/web-interface/nav"
+)
+
+// DefaultPageSize 主评论每页条数，与网页端一致
+const DefaultPageSize = 20
+
+// previewReplies 主评论列表中每条主评论内嵌的回复数
+const previewReplies = 3
+
+// Failure 注入的错误类型
+type Failure int
+
+const (
+	RiskCode352  Failure = iota // 返回错误码 -352
+	RiskCode412                 // 返回错误码 -412
+	RiskHTTP412                 // 返回 HTTP 412
+	RiskHTMLPage                // 返回 HTML 验证页面
+	ServerError                 // 返回 HTTP 502
+	EmptyPage                   // 返回没有评论的一页，但未标记到达末尾
+	CodeError                   // 返回错误码 -500，客户端不重试
+)
+
+// Server 模拟服务器
+type Server struct {
+	*httptest.Server
+
+	Fixture  *Fixture
+	PageSize int // 主评论每页条数，默认 DefaultPageSize
+
+	mu       sync.Mutex
+	hits     map[string]int
+	failures map[string][]Failure
+}
+
+// New 启动使用指定数据的模拟服务器，fx 为 nil 时使用 DefaultFixture
+func New(fx *Fixture) *Server {
+	if fx == nil {
+		fx = DefaultFixture()
+	}
+	s := &Server{
+		Fixture:  fx,
+		PageSize: DefaultPageSize,
+		hits:     map[string]int{},
+		failures: map[string][]Failure{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathNav, s.handleNav)
+	mux.HandleFunc(PathReplyCount, s.wrap(s.handleCount))
+	mux.HandleFunc(PathReplyMain, s.wrap(s.handleMain))
+	mux.HandleFunc(PathReplyReply, s.wrap(s.handleReply))
+	mux.HandleFunc(PathArcSearch, s.wrap(s.handleArcSearch))
+	mux.HandleFunc(PathView, s.wrap(s.handleView))
+	mux.HandleFunc("/cover/", s.handleImage)
+	mux.HandleFunc("/face/", s.handleImage)
+	mux.HandleFunc("/img/", s.handleImage)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Install 将 biliapi 各接口族的服务地址指向模拟服务器，返回恢复默认地址的函数
+func (s *Server) Install() (restore func()) {
+	endpoints := []util.Endpoint{util.EndpointReplyMain, util.EndpointReplyReply, util.EndpointArcSearch, util.EndpointView, util.EndpointNav}
+	for _, ep := range endpoints {
+		biliapi.SetBaseURL(ep, s.URL)
+	}
+	return func() {
+		for _, ep := range endpoints {
+			biliapi.SetBaseURL(ep, "")
+		}
+	}
+}
+
+// FailNext 让接下来 n 次对 path 的请求返回 f 类型的错误
+func (s *Server) FailNext(path string, f Failure, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < n; i++ {
+		s.failures[path] = append(s.failures[path], f)
+	}
+}
+
+// Hits 返回 path 收到的请求数（包括注入错误的请求）
+func (s *Server) Hits(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[path]
+}
+
+// nextFailure 记录一次请求，返回需要注入的错误
+func (s *Server) nextFailure(path string) (Failure, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hits[path]++
+	queue := s.failures[path]
+	if len(queue) == 0 {
+		return 0, false
+	}
+	s.failures[path] = queue[1:]
+	return queue[0], true
+}
+
+// wrap 统一处理错误注入与 WBI 签名校验
+func (s *Server) wrap(h func(w http.ResponseWriter, r *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if f, ok := s.nextFailure(r.URL.Path); ok {
+			writeFailure(w, f)
+			return
+		}
+		// 带 wbi 的接口必须签名，其余接口带签名时也校验
+		query := r.URL.Query()
+		if strings.Contains(r.URL.Path, "/wbi/") || query.Has("w_rid") {
+			if !s.verifyWbi(query) {
+				writeJSON(w, -403, "访问权限不足", nil)
+				return
+			}
+		}
+		h(w, r)
+	}
+}
+
+func writeFailure(w http.ResponseWriter, f Failure) {
+	switch f {
+	case RiskCode352:
+		writeJSON(w, -352, "风控校验失败", nil)
+	case RiskCode412:
+		writeJSON(w, -412, "请求被拦截", nil)
+	case RiskHTTP412:
+		w.WriteHeader(http.StatusPreconditionFailed)
+	case RiskHTMLPage:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<!DOCTYPE html><html><body>请完成验证</body></html>"))
+	case CodeError:
+		writeJSON(w, -500, "服务器错误", nil)
+	case EmptyPage:
+		writeJSON(w, 0, "0", map[string]any{
+			"cursor": map[string]any{
+				"is_end":           false,
+				"pagination_reply": map[string]string{"next_offset": "fake:empty"},
+			},
+			"replies":     []replyJSON{},
+			"top_replies": []replyJSON{},
+		})
+	default:
+		w.WriteHeader(http.StatusBadGateway)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, message string, data any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]any{"code": code, "message": message, "ttl": 1, "data": data})
+}
+
+var mixinKeyEncTab = []int{
+	46, 47, 18, 2, 53, 8, 23, 32, 15, 50, 10, 31, 58, 3, 45, 35, 27, 43, 5, 49,
+	33, 9, 42, 19, 29, 28, 14, 39, 12, 38, 41, 13, 37, 48, 7, 16, 24, 55, 40,
+	61, 26, 17, 0, 1, 60, 51, 30, 4, 22, 25, 54, 21, 56, 59, 6, 63, 57, 62, 11,
+	36, 20, 34, 44, 52,
+}
+
+// verifyWbi 按网页端的算法重新计算 w_rid 并比较
+func (s *Server) verifyWbi(query url.Values) bool {
+	wrid := query.Get("w_rid")
+	if wrid == "" || query.Get("wts") == "" {
+		return false
+	}
+	orig := s.Fixture.Wbi.ImgKey + s.Fixture.Wbi.SubKey
+	var mixin strings.Builder
+	for _, i := range mixinKeyEncTab {
+		if i < len(orig) && mixin.Len() < 32 {
+			mixin.WriteByte(orig[i])
+		}
+	}
+
+	params := url.Values{}
+	for k := range query {
+		if k != "w_rid" {
+			params.Set(k, query.Get(k))
+		}
+	}
+	encoded := strings.ReplaceAll(params.Encode(), "+", "%20")
+	sum := md5.Sum([]byte(encoded + mixin.String()))
+	return hex.EncodeToString(sum[:]) == wrid
+}
+
+// handleNav 未登录时的导航接口：错误码 -101，但返回 WBI 密钥
+func (s *Server) handleNav(w http.ResponseWriter, r *http.Request) {
+	if f, ok := s.nextFailure(r.URL.Path); ok {
+		writeFailure(w, f)
+		return
+	}
+	writeJSON(w, -101, "账号未登录", map[string]any{
+		"isLogin": false,
+		"wbi_img": map[string]string{
+			"img_url": "https://i0.hdslb.com/bfs/wbi/" + s.Fixture.Wbi.ImgKey + ".png",
+			"sub_url": "https://i0.hdslb.com/bfs/wbi/" + s.Fixture.Wbi.SubKey + ".png",
+		},
+	})
+}
+
+func (s *Server) handleCount(w http.ResponseWriter, r *http.Request) {
+	v := s.videoFromOid(r.URL.Query().Get("oid"))
+	if v == nil {
+		writeJSON(w, -404, "啥都木有", nil)
+		return
+	}
+	writeJSON(w, 0, "0", map[string]int{"count": v.CommentCount()})
+}
+
+// replyJSON 接口返回的评论结构（只包含爬虫使用到的字段）
+type replyJSON struct {
+	Rpid    int64  `json:"rpid"`
+	RpidStr string `json:"rpid_str"`
+	Oid     int64  `json:"oid"`
+	Type    int    `json:"type"`
+	Mid     int64  `json:"mid"`
+	Root    int64  `json:"root"`
+	Parent  int64  `json:"parent"`
+	Dialog  int64  `json:"dialog"`
+	Count   int    `json:"count"`
+	Rcount  int    `json:"rcount"`
+	Ctime   int64  `json:"ctime"`
+	Like    int    `json:"like"`
+	Member  struct {
+		Mid       string `json:"mid"`
+		Uname     string `json:"uname"`
+		Sex       string `json:"sex"`
+		Avatar    string `json:"avatar"`
+		LevelInfo struct {
+			CurrentLevel int `json:"current_level"`
+		} `json:"level_info"`
+		Vip struct {
+			VipType   int `json:"vipType"`
+			VipStatus int `json:"vipStatus"`
+			Label     struct {
+				Text string `json:"text"`
+			} `json:"label"`
+		} `json:"vip"`
+		FansDetail *fansDetailJSON `json:"fans_detail"`
+	} `json:"member"`
+	Content struct {
+		Message  string `json:"message"`
+		Pictures []struct {
+			ImgSrc string `json:"img_src"`
+		} `json:"pictures,omitempty"`
+		Members []memberJSON         `json:"members"`
+		Emote   map[string]emoteJSON `json:"emote,omitempty"`
+	} `json:"content"`
+	Replies   []replyJSON `json:"replies"`
+	Invisible bool        `json:"invisible"`
+	UpAction  struct {
+		Like  bool `json:"like"`
+		Reply bool `json:"reply"`
+	} `json:"up_action"`
+	ReplyControl struct {
+		IsUpTop  bool   `json:"is_up_top,omitempty"`
+		TimeDesc string `json:"time_desc"`
+		Location string `json:"location,omitempty"`
+	} `json:"reply_control"`
+}
+
+type fansDetailJSON struct {
+	UID       int64  `json:"uid"`
+	MedalID   int64  `json:"medal_id"`
+	MedalName string `json:"medal_name"`
+	Level     int    `json:"level"`
+}
+
+type memberJSON struct {
+	Mid   string `json:"mid"`
+	Uname string `json:"uname"`
+}
+
+type emoteJSON struct {
+	ID   int64  `json:"id"`
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// emotePattern 评论内容中的表情文本，如 [吃瓜]
+var emotePattern = regexp.MustCompile(`\[[^\[\]]+\]`)
+
+// mentionPattern 评论内容中的 @提及
+var mentionPattern = regexp.MustCompile(`@(\S+)`)
+
+func (s *Server) reply(v *Video, c Comment, root, parent, dialog int64) replyJSON {
+	out := replyJSON{
+		Rpid: c.Rpid, RpidStr: strconv.FormatInt(c.Rpid, 10), Oid: v.Aid, Type: 1, Mid: c.Mid,
+		Root: root, Parent: parent, Dialog: dialog, Count: len(c.Replies), Rcount: len(c.Replies),
+		Ctime: c.Ctime, Like: c.Like, Replies: []replyJSON{},
+	}
+	out.Member.Mid = strconv.FormatInt(c.Mid, 10)
+	out.Member.Uname = c.Uname
+	out.Member.Sex = "保密"
+	out.Member.Avatar = fmt.Sprintf("%s/face/%d.jpg", s.URL, c.Mid)
+	out.Member.LevelInfo.CurrentLevel = c.Level
+	// 大会员与粉丝勋章按 mid 确定地生成
+	switch c.Mid % 3 {
+	case 0:
+		out.Member.Vip.VipType, out.Member.Vip.VipStatus, out.Member.Vip.Label.Text = 2, 1, "年度大会员"
+	case 1:
+		out.Member.Vip.VipType, out.Member.Vip.VipStatus, out.Member.Vip.Label.Text = 1, 1, "大会员"
+	}
+	if owner := s.Fixture.user(v.Owner); owner != nil && c.Mid%4 == 0 {
+		out.Member.FansDetail = &fansDetailJSON{UID: c.Mid, MedalID: owner.Mid, MedalName: owner.Name, Level: int(c.Mid%20) + 1}
+	}
+	out.Content.Message = c.Message
+	out.Content.Members = []memberJSON{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(c.Message, -1) {
+		for _, u := range s.Fixture.Users {
+			if u.Name == m[1] {
+				out.Content.Members = append(out.Content.Members, memberJSON{Mid: strconv.FormatInt(u.Mid, 10), Uname: u.Name})
+			}
+		}
+	}
+	for i, text := range emotePattern.FindAllString(c.Message, -1) {
+		if out.Content.Emote == nil {
+			out.Content.Emote = map[string]emoteJSON{}
+		}
+		out.Content.Emote[text] = emoteJSON{ID: int64(i + 1), Text: text, URL: s.URL + "/img/emote/" + url.PathEscape(strings.Trim(text, "[]")) + ".png"}
+	}
+	out.ReplyControl.TimeDesc = time.Unix(c.Ctime, 0).Format("2006-01-02") + "发布"
+	out.ReplyControl.IsUpTop = c.Top
+	for _, r := range c.Replies {
+		if r.Mid == v.Owner {
+			out.UpAction.Reply = true
+		}
+	}
+	for _, p := range c.Pictures {
+		out.Content.Pictures = append(out.Content.Pictures, struct {
+			ImgSrc string `json:"img_src"`
+		}{ImgSrc: s.URL + "/" + strings.TrimPrefix(p, "/")})
+	}
+	out.ReplyControl.Location = c.Location
+	return out
+}
+
+// threadReplies 按时间顺序返回楼层回复，计算 parent 与 dialog
+// 直接回复主评论时 dialog 为自身 rpid，回复楼中楼时沿用被回复评论的 dialog
+func (s *Server) threadReplies(v *Video, root Comment) []replyJSON {
+	dialogs := map[int64]int64{}
+	out := make([]replyJSON, 0, len(root.Replies))
+	for _, c := range root.Replies {
+		parent, dialog := root.Rpid, c.Rpid
+		if c.Parent != 0 {
+			parent = c.Parent
+			if d, ok := dialogs[c.Parent]; ok {
+				dialog = d
+			}
+		}
+		dialogs[c.Rpid] = dialog
+		out = append(out, s.reply(v, c, root.Rpid, parent, dialog))
+	}
+	return out
+}
+
+func (s *Server) rootReply(v *Video, c Comment) replyJSON {
+	out := s.reply(v, c, 0, 0, 0)
+	replies := s.threadReplies(v, c)
+	if len(replies) > previewReplies {
+		replies = replies[:previewReplies]
+	}
+	out.Replies = replies
+	return out
+}
+
+// handleMain 主评论列表：mode=2 按时间倒序，其余按点赞数倒序；游标为 pagination_str 中的 offset
+func (s *Server) handleMain(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	v := s.videoFromOid(query.Get("oid"))
+	if v == nil {
+		writeJSON(w, -404, "啥都木有", nil)
+		return
+	}
+	mode := query.Get("mode")
+	if mode != "2" {
+		mode = "3"
+	}
+
+	var pagination struct {
+		Offset string `json:"offset"`
+	}
+	if ps := query.Get("pagination_str"); ps != "" {
+		if err := json.Unmarshal([]byte(ps), &pagination); err != nil {
+			writeJSON(w, -400, "请求错误", nil)
+			return
+		}
+	}
+	start := 0
+	if pagination.Offset != "" {
+		var cursorMode string
+		if _, err := fmt.Sscanf(pagination.Offset, "fake:%1s:%d", &cursorMode, &start); err != nil || cursorMode != mode || start < 0 {
+			writeJSON(w, -400, "请求错误", nil)
+			return
+		}
+	}
+
+	var top []replyJSON
+	var roots []Comment
+	for _, c := range v.Comments {
+		if c.Top {
+			top = append(top, s.rootReply(v, c))
+		} else {
+			roots = append(roots, c)
+		}
+	}
+	sort.SliceStable(roots, func(i, j int) bool {
+		if mode == "3" && roots[i].Like != roots[j].Like {
+			return roots[i].Like > roots[j].Like
+		}
+		return roots[i].Ctime > roots[j].Ctime
+	})
+
+	pageSize := s.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	end := min(start+pageSize, len(roots))
+	start = min(start, end)
+	replies := make([]replyJSON, 0, end-start)
+	for _, c := range roots[start:end] {
+		replies = append(replies, s.rootReply(v, c))
+	}
+	if start > 0 || top == nil {
+		top = []replyJSON{}
+	}
+
+	isEnd := end >= len(roots)
+	nextOffset := ""
+	if !isEnd {
+		nextOffset = fmt.Sprintf("fake:%s:%d", mode, end)
+	}
+	writeJSON(w, 0, "0", map[string]any{
+		"cursor": map[string]any{
+			"is_begin":         start == 0,
+			"is_end":           isEnd,
+			"mode":             atoiDefault(mode, 3),
+			"all_count":        v.CommentCount(),
+			"pagination_reply": map[string]string{"next_offset": nextOffset},
+		},
+		"replies":     replies,
+		"top_replies": top,
+	})
+}
+
+// handleReply 子评论分页：按时间顺序，pn 从 1 开始，超出范围时返回空列表
+func (s *Server) handleReply(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	v := s.videoFromOid(query.Get("oid"))
+	if v == nil {
+		writeJSON(w, -404, "啥都木有", nil)
+		return
+	}
+	rootID, _ := strconv.ParseInt(query.Get("root"), 10, 64)
+	var root *Comment
+	for i := range v.Comments {
+		if v.Comments[i].Rpid == rootID {
+			root = &v.Comments[i]
+		}
+	}
+	if root == nil {
+		writeJSON(w, 12022, "已经被删除了", nil)
+		return
+	}
+
+	pn := atoiDefault(query.Get("pn"), 1)
+	ps := atoiDefault(query.Get("ps"), 10)
+	all := s.threadReplies(v, *root)
+	start := min(max(pn-1, 0)*ps, len(all))
+	end := min(start+ps, len(all))
+
+	writeJSON(w, 0, "0", map[string]any{
+		"page":        map[string]int{"num": pn, "size": ps, "count": len(all)},
+		"root":        s.reply(v, *root, 0, 0, 0),
+		"replies":     all[start:end],
+		"top_replies": []replyJSON{},
+	})
+}
+
+// handleArcSearch UP主投稿列表，按发布时间倒序分页
+func (s *Server) handleArcSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	mid, _ := strconv.ParseInt(query.Get("mid"), 10, 64)
+	owner := s.Fixture.user(mid)
+	if owner == nil {
+		writeJSON(w, -404, "啥都木有", nil)
+		return
+	}
+	videos := s.Fixture.VideosOf(mid)
+	pn := atoiDefault(query.Get("pn"), 1)
+	ps := atoiDefault(query.Get("ps"), 30)
+	start := min(max(pn-1, 0)*ps, len(videos))
+	end := min(start+ps, len(videos))
+
+	vlist := make([]map[string]any, 0, end-start)
+	for _, v := range videos[start:end] {
+		vlist = append(vlist, map[string]any{
+			"aid": v.Aid, "bvid": v.Bvid, "title": v.Title, "pic": s.coverURL(v.Bvid),
+			"created": v.Pubdate, "comment": v.CommentCount(), "author": owner.Name, "mid": owner.Mid,
+			"length": "03:14",
+		})
+	}
+	writeJSON(w, 0, "0", map[string]any{
+		"list": map[string]any{"vlist": vlist},
+		"page": map[string]int{"pn": pn, "ps": ps, "count": len(videos)},
+	})
+}
+
+func (s *Server) handleView(w http.ResponseWriter, r *http.Request) {
+	v := s.Fixture.Video(r.URL.Query().Get("bvid"))
+	if v == nil {
+		writeJSON(w, -404, "啥都木有", nil)
+		return
+	}
+	owner := map[string]any{"mid": v.Owner}
+	if u := s.Fixture.user(v.Owner); u != nil {
+		owner["name"] = u.Name
+		owner["face"] = fmt.Sprintf("%s/face/%d.jpg", s.URL, u.Mid)
+	}
+	writeJSON(w, 0, "0", map[string]any{
+		"bvid": v.Bvid, "aid": v.Aid, "title": v.Title, "pic": s.coverURL(v.Bvid),
+		"pubdate": v.Pubdate, "owner": owner,
+		"stat": map[string]int{"reply": v.CommentCount()},
+	})
+}
+
+func (s *Server) coverURL(bvid string) string {
+	return s.URL + "/cover/" + bvid + ".jpg"
+}
+
+// handleImage 返回按路径着色的 JPEG 图片，相同路径的内容总是相同
+func (s *Server) handleImage(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.hits[r.URL.Path]++
+	s.mu.Unlock()
+
+	h := fnv.New32a()
+	h.Write([]byte(r.URL.Path))
+	sum := h.Sum32()
+	img := image.NewRGBA(image.Rect(0, 0, 16, 9))
+	fill := color.RGBA{R: uint8(sum), G: uint8(sum >> 8), B: uint8(sum >> 16), A: 255}
+	for x := 0; x < 16; x++ {
+		for y := 0; y < 9; y++ {
+			img.Set(x, y, fill)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Write(buf.Bytes())
+}
+
+func (s *Server) videoFromOid(oid string) *Video {
+	aid, err := strconv.ParseInt(oid, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return s.Fixture.videoByAid(aid)
+}
+
+func atoiDefault(s string, def int) int {
+	if n, err := strconv.Atoi(s); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -16,6 +17,9 @@ import (
 
 	"bilibili-comments-viewer-go/backend"
 	"bilibili-comments-viewer-go/config"
+	biliutil "bilibili-comments-viewer-go/crawler/bili_info/util"
+	"bilibili-comments-viewer-go/crawler/biliapi"
+	"bilibili-comments-viewer-go/crawler/blblcd/progress"
 	"bilibili-comments-viewer-go/database"
 	"bilibili-comments-viewer-go/logger"
 	"bilibili-comments-viewer-go/utils"
@@ -65,6 +69,14 @@ func main() {
 		log.Fatalf("Failed to create image storage directory: %v", err)
 	}
 
+	// migrate 子命令：查看或调整数据库结构版本后退出
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg.DatabasePath, os.Args[2:]); err != nil {
+			log.Fatalf("migrate 失败: %v", err)
+		}
+		return
+	}
+
 	// 初始化数据库
 	err = database.InitDB(cfg.DatabasePath)
 	if err != nil {
@@ -72,6 +84,32 @@ func main() {
 	}
 	defer database.CloseDB()
 
+	// 配置B站接口限流，所有爬取任务共享同一组令牌桶
+	limits := cfg.Crawler.RateLimits
+	biliutil.SetRateLimit(biliutil.EndpointReplyMain, limits.ReplyMain, cfg.Crawler.RateBurst)
+	biliutil.SetRateLimit(biliutil.EndpointReplyReply, limits.ReplyReply, cfg.Crawler.RateBurst)
+	biliutil.SetRateLimit(biliutil.EndpointArcSearch, limits.ArcSearch, cfg.Crawler.RateBurst)
+	biliutil.SetRateLimit(biliutil.EndpointView, limits.View, cfg.Crawler.RateBurst)
+	biliutil.SetRateLimit(biliutil.EndpointNav, limits.Nav, cfg.Crawler.RateBurst)
+
+	// 配置B站接口服务地址
+	urls := cfg.Crawler.BaseURLs
+	biliapi.SetBaseURL(biliutil.EndpointReplyMain, urls.ReplyMain)
+	biliapi.SetBaseURL(biliutil.EndpointReplyReply, urls.ReplyReply)
+	biliapi.SetBaseURL(biliutil.EndpointArcSearch, urls.ArcSearch)
+	biliapi.SetBaseURL(biliutil.EndpointView, urls.View)
+	biliapi.SetBaseURL(biliutil.EndpointNav, urls.Nav)
+
+	// 启动爬取任务管理器
+	jobManager := backend.StartJobManager(cfg.Crawler.JobWorkers)
+	defer jobManager.Stop()
+
+	// 启动定时爬取调度器
+	if cfg.Scheduler.Enabled {
+		scheduler := backend.StartScheduler(jobManager)
+		defer scheduler.Stop()
+	}
+
 	// 创建Gin路由器
 	router := gin.Default()
 
@@ -88,12 +126,39 @@ func main() {
 	{
 		api.GET("/videos", getVideos)
 		api.GET("/video/:bvid", getVideoDetails)
+		api.POST("/video/:bvid/reparse", reparseVideo)
+		api.DELETE("/video/:bvid", deleteVideo)
+		api.GET("/video/:bvid/crawls", getVideoCrawls)
+		api.GET("/video/:bvid/changes", getVideoChanges)
 		api.GET("/comments/:bvid", getComments)
+		api.GET("/search", search)
 		api.POST("/crawl/:bvid", crawlVideo)
 		api.POST("/crawl/up/:mid", crawlUpVideos)
+		api.POST("/crawl/:bvid/cancel", cancelCrawl)
+
+		// 爬取任务接口
+		api.GET("/jobs", getJobs)
+		api.GET("/jobs/:id", getJob)
+		api.GET("/jobs/:id/events", streamJobEvents)
+		api.GET("/jobs/:id/recording", getJobRecording)
+		api.DELETE("/jobs/:id", cancelJob)
+
+		// 定时爬取计划接口
+		api.GET("/schedules", getSchedules)
+		api.POST("/schedules", createSchedule)
+		api.GET("/schedules/:id", getSchedule)
+		api.PUT("/schedules/:id", updateSchedule)
+		api.DELETE("/schedules/:id", deleteSchedule)
+
+		// 诊断
+		api.GET("/diagnostics/rate_limits", getRateLimits)
 
 		// 新增评论回复接口
 		api.GET("/comment/replies/:comment_id", getCommentReplies)
+		api.GET("/comment/:id/thread", getCommentThread)
+		api.GET("/comment/:id/conversation", getCommentConversation)
+		api.GET("/comment/:id/history", getCommentHistory)
+		api.GET("/comment/:id", getComment)
 
 		// 修复模块路由
 		api.GET("/repair/validate", validateDatabase)
@@ -158,26 +223,31 @@ func crawlVideo(c *gin.Context) {
 		return
 	}
 
+	// 是否增量爬取，未指定时使用配置默认值
+	incremental := config.Get().Crawler.Incremental
+	if v := c.Query("incremental"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid incremental parameter"})
+			return
+		}
+		incremental = b
+	}
+
 	log := logger.GetLogger()
-	log.Infof("收到爬取请求: bvid=%s", bvid)
+	log.Infof("收到爬取请求: bvid=%s, incremental=%v", bvid, incremental)
 
-	// 暂时不传 ctx，待 backend 层完成 context 改造后再恢复
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Printf("[panic] crawlVideo goroutine: %v", r)
-			}
-		}()
-		if err := backend.CrawlAndImport(context.Background(), bvid); err != nil {
-			log.Errorf("视频 %s 爬取失败: %v", bvid, err)
-		} else {
-			log.Infof("视频 %s 爬取完成", bvid)
-		}
-	}()
+	job, err := backend.GetJobManager().SubmitVideo(bvid, incremental)
+	if err != nil {
+		log.Errorf("提交爬取任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue crawl job"})
+		return
+	}
 
 	c.JSON(http.StatusAccepted, gin.H{
-		"status":  "started",
-		"message": "Crawling started for video " + bvid,
+		"status":  job.Status,
+		"job_id":  job.ID,
+		"message": "Crawling queued for video " + bvid,
 	})
 }
 
@@ -192,28 +262,290 @@ func crawlUpVideos(c *gin.Context) {
 	// 获取是否爬取所有视频参数
 	fetchAll := c.DefaultQuery("all", "false") == "true"
 
-	midInt, err := utils.StringToInt(mid)
-	if err != nil {
+	midInt, err := strconv.ParseInt(mid, 10, 64)
+	if err != nil || midInt <= 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mid parameter"})
 		return
 	}
 
 	log.Printf("收到UP主爬取请求: MID=%d, 爬取所有=%v", midInt, fetchAll)
 
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Printf("[panic] crawlUpVideos goroutine: %v", r)
-			}
-		}()
-		if err := backend.CrawlUpVideos(midInt, fetchAll); err != nil {
-			log.Printf("UP主爬取失败: %v", err)
-		}
-	}()
+	job, err := backend.GetJobManager().SubmitUp(midInt, fetchAll)
+	if err != nil {
+		log.Printf("提交UP主爬取任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue crawl job"})
+		return
+	}
 
 	c.JSON(http.StatusAccepted, gin.H{
-		"status":  "started",
-		"message": fmt.Sprintf("Crawling started for UP %s (all: %v)", mid, fetchAll),
+		"status":  job.Status,
+		"job_id":  job.ID,
+		"message": fmt.Sprintf("Crawling queued for UP %s (all: %v)", mid, fetchAll),
+	})
+}
+
+// 获取爬取任务列表
+func getJobs(c *gin.Context) {
+	page := c.DefaultQuery("page", "1")
+	pageSize := c.DefaultQuery("pageSize", "20")
+	status := c.DefaultQuery("status", "")
+
+	pageInt, err := utils.StringToInt(page)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page parameter"})
+		return
+	}
+
+	pageSizeInt, err := utils.StringToInt(pageSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pageSize parameter"})
+		return
+	}
+
+	jobs, total, err := database.GetCrawlJobsPaginated(pageInt, pageSizeInt, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobs":     jobs,
+		"total":    total,
+		"page":     pageInt,
+		"pageSize": pageSizeInt,
+	})
+}
+
+// 获取单个爬取任务
+func getJob(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job id"})
+		return
+	}
+
+	job, err := database.GetCrawlJob(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get job"})
+		return
+	}
+
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// 下载任务的流量录制存档（需开启 crawler.record_traffic）
+func getJobRecording(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job id"})
+		return
+	}
+
+	job, err := database.GetCrawlJob(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get job"})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	// 运行中的任务存档尚未写完
+	if job.Status == database.CrawlJobQueued || job.Status == database.CrawlJobRunning {
+		c.JSON(http.StatusConflict, gin.H{"error": "Job is still running"})
+		return
+	}
+
+	path := backend.RecordingPath(id)
+	if _, err := os.Stat(path); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recording not found"})
+		return
+	}
+
+	c.FileAttachment(path, filepath.Base(path))
+}
+
+// 取消爬取任务
+func cancelJob(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job id"})
+		return
+	}
+
+	job, err := backend.GetJobManager().Cancel(id)
+	if errors.Is(err, backend.ErrJobFinished) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Job already finished", "job": job})
+		return
+	}
+	if err != nil {
+		logger.GetLogger().Errorf("取消爬取任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel job"})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	// 运行中的任务会在保存已获取的评论后结束，最终状态通过任务查询或事件流获取
+	if job.Status == database.CrawlJobRunning {
+		c.JSON(http.StatusAccepted, gin.H{"message": "Cancellation requested", "job": job})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Job cancelled", "job": job})
+}
+
+// 取消指定视频的爬取任务（排队中与运行中）
+func cancelCrawl(c *gin.Context) {
+	bvid := c.Param("bvid")
+	if bvid == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing bvid parameter"})
+		return
+	}
+
+	jobs, err := backend.GetJobManager().CancelTarget(database.CrawlJobTypeVideo, bvid)
+	if err != nil {
+		logger.GetLogger().Errorf("取消视频爬取失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel crawl"})
+		return
+	}
+	if len(jobs) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No active crawl for video " + bvid})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Cancellation requested for video " + bvid, "jobs": jobs})
+}
+
+// 获取定时爬取计划列表及调度器状态
+func getSchedules(c *gin.Context) {
+	schedules, err := database.GetSchedules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get schedules"})
+		return
+	}
+
+	status, err := backend.GetSchedulerStatus()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get scheduler status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"schedules": schedules,
+		"scheduler": status,
+	})
+}
+
+// 获取单个定时爬取计划
+func getSchedule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule id"})
+		return
+	}
+
+	schedule, err := database.GetSchedule(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get schedule"})
+		return
+	}
+	if schedule == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// 创建定时爬取计划
+func createSchedule(c *gin.Context) {
+	var input backend.ScheduleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	schedule, err := backend.CreateSchedule(input)
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// 更新定时爬取计划（未提供的字段保持不变）
+func updateSchedule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule id"})
+		return
+	}
+
+	var input backend.ScheduleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	schedule, err := backend.UpdateSchedule(id, input)
+	if err != nil {
+		respondScheduleError(c, err)
+		return
+	}
+	if schedule == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// 删除定时爬取计划
+func deleteSchedule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule id"})
+		return
+	}
+
+	deleted, err := database.DeleteSchedule(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete schedule"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Schedule deleted"})
+}
+
+// respondScheduleError 参数校验错误返回 400，其余返回 500
+func respondScheduleError(c *gin.Context, err error) {
+	var crawlerErr *backend.CrawlerError
+	if errors.As(err, &crawlerErr) && crawlerErr.Type == backend.ErrorTypeUserInput {
+		c.JSON(http.StatusBadRequest, gin.H{"error": crawlerErr.Message})
+		return
+	}
+	logger.GetLogger().Errorf("保存定时爬取计划失败: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save schedule"})
+}
+
+// 获取B站接口限流状态、当前请求速率与风控熔断器状态
+func getRateLimits(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"total_requests":  biliutil.RequestCount(),
+		"endpoints":       biliutil.RateStats(),
+		"circuit_breaker": biliutil.GetBreakerStatus(),
 	})
 }
 
@@ -283,37 +615,103 @@ func getComments(c *gin.Context) {
 		return
 	}
 
-	page := c.DefaultQuery("page", "1")
-	pageSize := c.DefaultQuery("pageSize", "20")
-	keyword := c.DefaultQuery("keyword", "") // 获取关键词参数
-
-	pageInt, err := utils.StringToInt(page)
+	query, err := backend.ParseCommentQuery(c.Request.URL.Query())
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page parameter"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	pageSizeInt, err := utils.StringToInt(pageSize)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pageSize parameter"})
+	// 只获取顶级评论
+	result, err := query.Run(bvid)
+	if errors.Is(err, database.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	// 默认只获取顶级评论
-	comments, total, err := database.GetCommentsByBVid(bvid, pageInt, pageSizeInt, keyword)
 	if err != nil {
+		log.Printf("查询评论失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get comments"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"comments": comments,
-		"total":    total,
-		"page":     pageInt,
-		"pageSize": pageSizeInt,
+		"comments":    result.Comments,
+		"total":       result.Total,
+		"page":        query.Page,
+		"pageSize":    query.PageSize,
+		"next_cursor": result.NextCursor,
 	})
 }
 
+// 获取评论的完整回复树
+func getCommentThread(c *gin.Context) {
+	maxDepth, err := utils.StringToInt(c.DefaultQuery("max_depth", strconv.Itoa(backend.DefaultThreadDepth)))
+	if err != nil || maxDepth < 1 || maxDepth > backend.MaxThreadDepth {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid max_depth parameter"})
+		return
+	}
+	limit, err := utils.StringToInt(c.DefaultQuery("limit", strconv.Itoa(backend.DefaultThreadSize)))
+	if err != nil || limit < 1 || limit > backend.MaxThreadSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+		return
+	}
+
+	thread, err := backend.BuildThread(c.Param("id"), maxDepth, limit)
+	if errors.Is(err, backend.ErrCommentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("获取回复树失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get comment thread"})
+		return
+	}
+
+	c.JSON(http.StatusOK, thread)
+}
+
+// 获取单条评论及其上下文，id 可以是 unique_id 或 rpid
+func getComment(c *gin.Context) {
+	query, err := backend.ParseCommentQuery(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	contextSize, err := utils.StringToInt(c.DefaultQuery("context", strconv.Itoa(backend.DefaultPermalinkContext)))
+	if err != nil || contextSize < 0 || contextSize > backend.MaxPermalinkContext {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid context parameter"})
+		return
+	}
+
+	permalink, err := backend.BuildPermalink(c.Param("id"), query, contextSize)
+	if errors.Is(err, backend.ErrCommentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("获取评论失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get comment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, permalink)
+}
+
+// 获取回复所在的对话
+func getCommentConversation(c *gin.Context) {
+	conv, err := backend.BuildConversation(c.Param("id"))
+	switch {
+	case errors.Is(err, backend.ErrCommentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+	case errors.Is(err, backend.ErrNotReply):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		log.Printf("获取对话失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get conversation"})
+	default:
+		c.JSON(http.StatusOK, conv)
+	}
+}
+
 // 获取评论的回复
 func getCommentReplies(c *gin.Context) {
 	commentID := c.Param("comment_id")
@@ -323,18 +721,102 @@ func getCommentReplies(c *gin.Context) {
 
 	pageInt, _ := utils.StringToInt(page)
 	pageSizeInt, _ := utils.StringToInt(pageSize)
+	if pageInt < 1 {
+		pageInt = 1
+	}
+	if pageSizeInt < 1 || pageSizeInt > backend.MaxCommentPageSize {
+		pageSizeInt = 5
+	}
 
-	replies, total, err := database.GetCommentReplies(commentID, pageInt, pageSizeInt)
+	result, err := database.GetCommentReplies(commentID, database.PageRequest{
+		Page: pageInt, PageSize: pageSizeInt, Cursor: c.Query("cursor"),
+	})
+	if errors.Is(err, database.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get comment replies"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"replies":  replies,
-		"total":    total,
-		"page":     pageInt,
-		"pageSize": pageSizeInt,
+		"replies":     result.Comments,
+		"total":       result.Total,
+		"page":        pageInt,
+		"pageSize":    pageSizeInt,
+		"next_cursor": result.NextCursor,
+	})
+}
+
+// 通过SSE推送爬取任务的实时进度
+func streamJobEvents(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job id"})
+		return
+	}
+
+	// 先订阅再查询任务状态，避免任务恰好在两者之间结束而丢失事件
+	events, last, unsubscribe := progress.Default.Subscribe(id)
+	defer unsubscribe()
+
+	job, err := database.GetCrawlJob(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get job"})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	// SSE为长连接，取消服务器的写超时
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("取消SSE写超时失败: %v", err)
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	// 任务已结束，直接推送最终状态
+	if job.Status != database.CrawlJobQueued && job.Status != database.CrawlJobRunning {
+		c.SSEvent(string(progress.EventJobStatus), progress.Event{
+			Type:       progress.EventJobStatus,
+			Time:       time.Now(),
+			Status:     job.Status,
+			Downloaded: job.CommentCount,
+			Message:    job.Error,
+		})
+		return
+	}
+
+	// 先推送当前状态，便于中途连接的客户端立即显示进度
+	if last != nil {
+		c.SSEvent(string(last.Type), last)
+	} else {
+		c.SSEvent(string(progress.EventJobStatus), progress.Event{Type: progress.EventJobStatus, Time: time.Now(), Status: job.Status})
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(string(e.Type), e)
+			return true
+		case <-heartbeat.C:
+			// 注释行作为心跳，防止代理断开空闲连接
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
 	})
 }
 
@@ -613,3 +1095,247 @@ func repairVideoData(c *gin.Context) {
 		"message": fmt.Sprintf("视频 %s 数据修复完成，修复了 %d 个问题", bvid, result.Summary.Summary.IssuesFixed),
 	})
 }
+
+// 从存档的原始响应重新解析视频评论，用于新增字段后回填
+func reparseVideo(c *gin.Context) {
+	bvid := c.Param("bvid")
+	if bvid == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing bvid parameter"})
+		return
+	}
+
+	log := logger.GetLogger()
+	log.Infof("收到重新解析请求: bvid=%s", bvid)
+
+	result, err := backend.ReparseVideo(bvid)
+	if errors.Is(err, backend.ErrNoRawPages) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No archived pages for video " + bvid})
+		return
+	}
+	if err != nil {
+		log.Errorf("重新解析失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Reparse failed", "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"result":  result,
+		"message": fmt.Sprintf("视频 %s 重新解析完成，从 %d 页原始响应写入 %d 条评论", bvid, result.Pages, result.Comments),
+	})
+}
+
+// 删除视频的数据库记录、评论图片、封面与评论输出文件
+// dry_run=true 时只返回将要删除的内容
+func deleteVideo(c *gin.Context) {
+	bvid := c.Param("bvid")
+	dryRun := false
+	if v := c.Query("dry_run"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dry_run parameter"})
+			return
+		}
+		dryRun = b
+	}
+
+	log := logger.GetLogger()
+	log.Infof("收到删除视频请求: bvid=%s, dry_run=%v", bvid, dryRun)
+
+	result, err := backend.DeleteVideo(bvid, dryRun)
+	switch {
+	case errors.Is(err, backend.ErrInvalidBVid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, backend.ErrVideoNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	case errors.Is(err, backend.ErrVideoBusy):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Errorf("删除视频失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete video", "message": err.Error()})
+		return
+	}
+
+	message := fmt.Sprintf("视频 %s 已删除：%d 条评论，%d 个文件或目录", bvid, result.Database.Comments, len(result.Files))
+	if dryRun {
+		message = fmt.Sprintf("试运行：将删除视频 %s 的 %d 条评论与 %d 个文件或目录", bvid, result.Database.Comments, len(result.Files))
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "result": result, "message": message})
+}
+
+// 获取视频写入数据库的爬取记录，按时间倒序
+func getVideoCrawls(c *gin.Context) {
+	crawls, err := database.GetVideoCrawls(c.Param("bvid"))
+	if err != nil {
+		log.Printf("获取爬取记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get crawls"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"crawls": crawls})
+}
+
+// 获取两次爬取之间被删除或修改的评论
+// from/to 为爬取记录 id，省略 to 时取最近一次已完成的爬取，省略 from 时取 to 之前最近一次已完成的爬取
+func getVideoChanges(c *gin.Context) {
+	var ids [2]int64
+	for i, name := range []string{"from", "to"} {
+		if v := c.Query(name); v != "" {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || id <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name + " parameter"})
+				return
+			}
+			ids[i] = id
+		}
+	}
+
+	changes, err := backend.GetVideoChanges(c.Param("bvid"), ids[0], ids[1])
+	switch {
+	case errors.Is(err, backend.ErrCrawlNotFound), errors.Is(err, backend.ErrNoCompletedCrawl):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, backend.ErrInvalidCrawlRange):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Printf("获取评论变化失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get comment changes"})
+		return
+	}
+	c.JSON(http.StatusOK, changes)
+}
+
+// 获取评论的历史状态（每次内容或点赞数变化前的快照）
+func getCommentHistory(c *gin.Context) {
+	history, err := backend.GetCommentHistory(c.Param("id"))
+	if errors.Is(err, backend.ErrCommentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("获取评论历史失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get comment history"})
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// 全文搜索全部视频的评论与标题
+// q 支持空格分隔的多个词、OR、NOT 或 - 前缀、双引号短语与括号，例如 "学到了 (吃瓜 OR 前排) -广告"
+func search(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing q parameter"})
+		return
+	}
+	query, err := database.ParseSearchQuery(q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid search query", "message": err.Error()})
+		return
+	}
+
+	pageInt, err := utils.StringToInt(c.DefaultQuery("page", "1"))
+	if err != nil || pageInt < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page parameter"})
+		return
+	}
+	pageSizeInt, err := utils.StringToInt(c.DefaultQuery("pageSize", "20"))
+	if err != nil || pageSizeInt < 1 || pageSizeInt > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pageSize parameter"})
+		return
+	}
+
+	comments, total, err := database.SearchComments(query, pageInt, pageSizeInt)
+	if err != nil {
+		log.Printf("搜索评论失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		return
+	}
+	// 视频标题只在第一页返回
+	videos := []database.VideoHit{}
+	if pageInt == 1 {
+		if videos, err = database.SearchVideos(query, 10); err != nil {
+			log.Printf("搜索视频失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":    q,
+		"videos":   videos,
+		"comments": comments,
+		"total":    total,
+		"page":     pageInt,
+		"pageSize": pageSizeInt,
+	})
+}
+
+// runMigrate 执行 migrate 子命令：
+//
+//	bcvg migrate status        查看各版本的应用状态
+//	bcvg migrate up [版本]     升级到指定版本，默认升级到最新
+//	bcvg migrate down [版本]   回退到指定版本，默认回退一个版本；回退前自动备份数据库
+func runMigrate(dbPath string, args []string) error {
+	if err := database.OpenDB(dbPath); err != nil {
+		return err
+	}
+	defer database.CloseDB()
+
+	cmd := "status"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+	target := -1
+	if len(args) > 1 {
+		v, err := strconv.Atoi(args[1])
+		if err != nil || v < 0 {
+			return fmt.Errorf("无效的版本号: %s", args[1])
+		}
+		target = v
+	}
+
+	current, err := database.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	switch cmd {
+	case "status":
+	case "up":
+		if target == 0 {
+			return fmt.Errorf("无效的版本号: %d", target)
+		}
+		err = database.MigrateUp(max(target, 0))
+	case "down":
+		if target < 0 {
+			target = max(current-1, 0)
+		}
+		err = database.MigrateDown(target)
+	default:
+		return fmt.Errorf("未知的 migrate 命令: %s（可用 status、up、down）", cmd)
+	}
+	if err != nil {
+		return err
+	}
+
+	statuses, err := database.MigrationStatuses()
+	if err != nil {
+		return err
+	}
+	current, err = database.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("数据库: %s\n当前版本: %d（程序支持的最新版本: %d）\n", dbPath, current, database.LatestVersion())
+	for _, st := range statuses {
+		if st.AppliedAt != nil {
+			fmt.Printf("  [x] %3d %-24s %s\n", st.Version, st.Name, st.AppliedAt.Format("2006-01-02 15:04:05"))
+		} else {
+			fmt.Printf("  [ ] %3d %s\n", st.Version, st.Name)
+		}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -9,11 +10,23 @@ import (
 
 	"bilibili-comments-viewer-go/backend"
 	"bilibili-comments-viewer-go/config"
+	"bilibili-comments-viewer-go/crawler/biliapi"
 	"bilibili-comments-viewer-go/database"
 	"bilibili-comments-viewer-go/logger"
 )
 
+// 用法:
+//
+//	go run ./test <bvid>                       爬取真实视频，使用 config.yaml 中的配置
+//	go run ./test -record out.tar.zst <bvid>   爬取并把接口请求与响应录制到存档
+//	go run ./test -replay out.tar.zst <bvid>   从存档回放接口响应，离线重新爬取
+//
+// 离线端到端测试见 backend 包中的 go test，使用 internal/fakebili 模拟服务器
 func main() {
+	record := flag.String("record", "", "将接口请求与响应录制到 .tar.zst 存档")
+	replay := flag.String("replay", "", "从 .tar.zst 存档回放接口响应，不访问网络")
+	flag.Parse()
+
 	// 初始化配置
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -31,15 +44,13 @@ func main() {
 	log := logger.GetLogger()
 
 	// 检查命令行参数
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: go run test/test_crawler.go <bvid>")
-		fmt.Println("Example: go run test/test_crawler.go BV1xx411c7mD")
+	if flag.NArg() < 1 {
+		fmt.Println("Usage: go run ./test <bvid>")
+		fmt.Println("       go run ./test [-record|-replay archive.tar.zst] <bvid>")
+		fmt.Println("Example: go run ./test BV1xx411c7mD")
 		os.Exit(1)
 	}
 
-	bvid := os.Args[1]
-	log.Infof("开始测试爬虫修复效果，目标视频: %s", bvid)
-
 	// 初始化数据库
 	log.Infof("初始化数据库: %s", cfg.DatabasePath)
 	err = database.InitDB(cfg.DatabasePath)
@@ -53,6 +64,31 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
 	defer cancel()
 
+	bvid := flag.Arg(0)
+	log.Infof("开始测试爬虫修复效果，目标视频: %s", bvid)
+
+	switch {
+	case *replay != "":
+		rp, err := biliapi.OpenReplay(*replay)
+		if err != nil {
+			log.Fatalf("打开回放存档失败: %v", err)
+		}
+		ctx = biliapi.WithReplay(ctx, rp)
+	case *record != "":
+		rec, err := biliapi.NewRecorder(*record)
+		if err != nil {
+			log.Fatalf("创建录制存档失败: %v", err)
+		}
+		defer func() {
+			if err := rec.Close(); err != nil {
+				log.Errorf("录制存档写入失败: %v", err)
+				return
+			}
+			log.Infof("已录制 %d 次接口请求: %s", rec.Count(), rec.Path())
+		}()
+		ctx = biliapi.WithRecorder(ctx, rec)
+	}
+
 	// 开始爬取
 	startTime := time.Now()
 	err = backend.CrawlAndImport(ctx, bvid)
@@ -60,7 +96,7 @@ func main() {
 
 	if err != nil {
 		log.Errorf("爬取失败: %v", err)
-		os.Exit(1)
+		return
 	}
 
 	duration := endTime.Sub(startTime)